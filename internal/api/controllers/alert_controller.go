@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"ares_api/internal/api/dto"
+	"ares_api/internal/common"
+	service "ares_api/internal/interfaces/service"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// streamHeartbeat keeps idle alert streams from being closed by proxies
+const streamHeartbeat = 15 * time.Second
+
+type AlertController struct {
+	Service       service.AlertService
+	LedgerService service.LedgerService
+}
+
+func NewAlertController(s service.AlertService, l service.LedgerService) *AlertController {
+	return &AlertController{Service: s, LedgerService: l}
+}
+
+// @Summary Create a price alert
+// @Description Watch a coin for a price crossing, a percent move over a window or a 24h change threshold.
+// @Description The webhook secret is only returned here.
+// @Tags Alerts
+// @Accept json
+// @Produce json
+// @Param request body dto.CreateAlertRequest true "Alert"
+// @Success 201 {object} dto.AlertDTO
+// @Failure 400 {object} map[string]string
+// @Security BearerAuth
+// @Router /alerts [post]
+func (c *AlertController) Create(ctx *gin.Context) {
+	var req dto.CreateAlertRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		common.JSON(ctx, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := ctx.GetUint("userID")
+
+	res, err := c.Service.Create(userID, req)
+	if err != nil {
+		common.JSON(ctx, alertErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	_ = c.LedgerService.Append(userID, "CreateAlert", "Created "+res.Condition+" alert "+strconv.FormatUint(uint64(res.ID), 10)+" for coin ID: "+res.CoinID)
+	common.JSON(ctx, http.StatusCreated, res)
+}
+
+// @Summary List price alerts
+// @Tags Alerts
+// @Produce json
+// @Success 200 {array} dto.AlertDTO
+// @Security BearerAuth
+// @Router /alerts [get]
+func (c *AlertController) List(ctx *gin.Context) {
+	res, err := c.Service.List(ctx.GetUint("userID"))
+	if err != nil {
+		common.JSON(ctx, http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	common.JSON(ctx, http.StatusOK, res)
+}
+
+// @Summary Delete a price alert
+// @Tags Alerts
+// @Produce json
+// @Param id path int true "Alert ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /alerts/{id} [delete]
+func (c *AlertController) Delete(ctx *gin.Context) {
+	alertID, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		common.JSON(ctx, http.StatusBadRequest, gin.H{"error": "invalid alert id"})
+		return
+	}
+
+	userID := ctx.GetUint("userID")
+
+	if err := c.Service.Delete(userID, uint(alertID)); err != nil {
+		common.JSON(ctx, alertErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	_ = c.LedgerService.Append(userID, "DeleteAlert", "Deleted alert "+ctx.Param("id"))
+	common.JSON(ctx, http.StatusOK, gin.H{"message": "alert deleted"})
+}
+
+// @Summary Stream fired alerts
+// @Description Server-sent events: an "alert" event carrying a dto.AlertEventDTO each time one of the user's alerts fires.
+// @Description Browsers can pass the JWT as the access_token query parameter.
+// @Tags Alerts
+// @Produce text/event-stream
+// @Param access_token query string false "JWT, for clients that can't set the Authorization header"
+// @Success 200 {object} dto.AlertEventDTO
+// @Security BearerAuth
+// @Router /alerts/stream [get]
+func (c *AlertController) Stream(ctx *gin.Context) {
+	events, cancel := c.Service.Subscribe(ctx.GetUint("userID"))
+	defer cancel()
+
+	heartbeat := time.NewTicker(streamHeartbeat)
+	defer heartbeat.Stop()
+
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no")
+	ctx.Status(http.StatusOK)
+	ctx.Writer.Flush()
+
+	ctx.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-events:
+			if !ok {
+				return false
+			}
+			ctx.SSEvent("alert", event)
+			return true
+		case <-heartbeat.C:
+			_, err := io.WriteString(w, ": ping\n\n")
+			return err == nil
+		case <-ctx.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// alertErrorStatus maps alert errors to HTTP status codes
+func alertErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrAlertNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidAlert):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
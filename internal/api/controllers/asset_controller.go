@@ -1,24 +1,29 @@
 package controllers
 
 import (
+	"ares_api/internal/api/dto"
 	service "ares_api/internal/interfaces/service"
+	"errors"
 	"github.com/gin-gonic/gin"
+	"math"
 	"net/http"
 	"strconv"
+	"time"
 )
 
 type AssetController struct {
 	Service service.AssetService
+	CandleService service.CandleService
 	LedgerService service.LedgerService
 }
 
-func NewAssetController(s service.AssetService , l service.LedgerService) *AssetController {
-	return &AssetController{Service: s , LedgerService: l}
+func NewAssetController(s service.AssetService , c service.CandleService, l service.LedgerService) *AssetController {
+	return &AssetController{Service: s , CandleService: c, LedgerService: l}
 }
 
 //GetAllCoins godoc
 // @Summary      Get all coins
-// @Description  Fetch the list of coins from CoinGecko with optional limit
+// @Description  List the stored coin catalog, largest coins by market cap first, with optional limit
 // @Tags         coins
 // @Produce      json
 // @Param        limit  query     int  false  "Limit number of coins to return"  default(100)
@@ -34,13 +39,42 @@ func (c *AssetController) GetAllCoins(ctx *gin.Context) {
 
 	coins, err := c.Service.GetAllCoins(limit)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(assetErrorStatus(ctx, err), gin.H{"error": err.Error()})
 		return
 	}
 	_ = c.LedgerService.Append(0,  "GetAllCoins", "Fetched list of coins with limit: " + limitStr)
 	ctx.JSON(http.StatusOK, coins)
 }
 
+// SearchCoins godoc
+// @Summary      Search the coin catalog
+// @Description  Ranks catalog coins against q: exact symbol or name first, then symbol, name and word prefixes, substrings, and finally near misses allowing one typo per four characters
+// @Tags         coins
+// @Produce      json
+// @Param        q      query     string  true   "Symbol or name to look for"
+// @Param        limit  query     int     false  "Maximum results (max 100)"  default(20)
+// @Success      200  {array}   dto.CoinSearchResultDTO
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /assets/coins/search [get]
+func (c *AssetController) SearchCoins(ctx *gin.Context) {
+	query := ctx.Query("q")
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+
+	coins, err := c.Service.SearchCoins(query, limit)
+	if err != nil {
+		ctx.JSON(assetErrorStatus(ctx, err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, coins)
+}
+
 // GetCoinMarket godoc
 // @Summary      Get coin market data
 // @Description  Fetch real-time market data for a specific coin
@@ -56,7 +90,7 @@ func (c *AssetController) GetCoinMarket(ctx *gin.Context) {
     currency := ctx.DefaultQuery("vs_currency", "usd") // default to USD
     coin, err := c.Service.GetCoinMarket(id, currency)
     if err != nil {
-        ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        ctx.JSON(assetErrorStatus(ctx, err), gin.H{"error": err.Error()})
         return
     }
 	_ = c.LedgerService.Append(0,  "GetCoinMarket", "Fetched market data for coin ID: " + id)
@@ -66,29 +100,70 @@ func (c *AssetController) GetCoinMarket(ctx *gin.Context) {
 
 // GetTopMovers godoc
 // @Summary      Get top movers
-// @Description  Fetch top N coins by 24h price change
+// @Description  Rank the market universe (the largest coins by market cap) into the biggest gainers and losers over 1h, 24h or 7d, optionally dropping coins below a 24h USD volume
 // @Tags         coins
 // @Produce      json
-// @Param        limit  query     int  false  "Number of top movers"  default(10)
-// @Success      200    {array}   dto.TopMoverDTO
+// @Param        window      query     string  false  "Window: 1h, 24h or 7d"  default(24h)
+// @Param        limit       query     int     false  "Coins per side"  default(10)
+// @Param        min_volume  query     number  false  "Minimum 24h volume in USD"
+// @Success      200    {object}  dto.TopMoversDTO
+// @Failure      400    {object}  map[string]string
 // @Failure      500    {object}  map[string]string
 // @Router      /assets/coins/top-movers [get]
 func (c *AssetController) GetTopMovers(ctx *gin.Context) {
-	limitStr := ctx.DefaultQuery("limit", "10")
-	limit, err := strconv.Atoi(limitStr)
-	if err != nil || limit <= 0 {
-		limit = 10
+	var query dto.TopMoversQuery
+	if err := ctx.ShouldBindQuery(&query); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if query.Limit > 100 {
+		query.Limit = 100
 	}
 
-	movers, err := c.Service.GetTopMovers(limit)
+	movers, err := c.Service.GetTopMovers(query)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(assetErrorStatus(ctx, err), gin.H{"error": err.Error()})
 		return
 	}
-	_ = c.LedgerService.Append(0,  "GetTopMovers", "Fetched top movers with limit: " + limitStr)
+	_ = c.LedgerService.Append(0, "GetTopMovers", "Fetched "+movers.Window+" top movers")
 	ctx.JSON(http.StatusOK, movers)
 }
 
+// Screen godoc
+// @Summary      Screen the market
+// @Description  Filter the market universe (the largest coins by market cap) by price, market cap, change and 24h volume ranges, all in USD and inclusive, and sort the result
+// @Tags         coins
+// @Produce      json
+// @Param        min_price       query     number  false  "Minimum price"
+// @Param        max_price       query     number  false  "Maximum price"
+// @Param        min_market_cap  query     number  false  "Minimum market cap"
+// @Param        max_market_cap  query     number  false  "Maximum market cap"
+// @Param        min_change      query     number  false  "Minimum change in percent over change_window"
+// @Param        max_change      query     number  false  "Maximum change in percent over change_window"
+// @Param        min_volume      query     number  false  "Minimum 24h volume"
+// @Param        max_volume      query     number  false  "Maximum 24h volume"
+// @Param        change_window   query     string  false  "Window of the change filter and sort: 1h, 24h or 7d"  default(24h)
+// @Param        sort            query     string  false  "Sort by market_cap, price, change or volume"  default(market_cap)
+// @Param        order           query     string  false  "asc or desc"  default(desc)
+// @Param        limit           query     int     false  "Maximum results (max 250)"  default(50)
+// @Success      200  {array}   dto.CoinMarketDTO
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /assets/screener [get]
+func (c *AssetController) Screen(ctx *gin.Context) {
+	var filter dto.ScreenerFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	coins, err := c.Service.Screen(filter)
+	if err != nil {
+		ctx.JSON(assetErrorStatus(ctx, err), gin.H{"error": err.Error()})
+		return
+	}
+	ctx.JSON(http.StatusOK, coins)
+}
 
 // GetSupportedVSCurrencies godoc
 // @Summary      Get all supported vs_currency options
@@ -101,9 +176,103 @@ func (c *AssetController) GetTopMovers(ctx *gin.Context) {
 func (c *AssetController) GetSupportedVSCurrencies(ctx *gin.Context) {
     currencies, err := c.Service.GetSupportedVSCurrencies()
     if err != nil {
-        ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+        ctx.JSON(assetErrorStatus(ctx, err), gin.H{"error": err.Error()})
         return
     }
 	_ = c.LedgerService.Append(0,  "GetSupportedVSCurrencies", "Fetched supported vs_currencies")
     ctx.JSON(http.StatusOK, currencies)
 }
+
+
+// GetCandles godoc
+// @Summary      Get OHLCV candles
+// @Description  Returns USD candles for a coin between from and to. Closed candles missing from the store are backfilled from the price source's history.
+// @Tags         coins
+// @Produce      json
+// @Param        id        path      string  true   "Coin ID"
+// @Param        interval  query     string  false  "Candle interval: 1m, 1h or 1d"  default(1h)
+// @Param        from      query     string  false  "Start, RFC3339 or unix seconds (default: 100 candles before to)"
+// @Param        to        query     string  false  "End, RFC3339 or unix seconds (default: now)"
+// @Success      200  {object}  dto.CandlesDTO
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /assets/coins/{id}/candles [get]
+func (c *AssetController) GetCandles(ctx *gin.Context) {
+	id := ctx.Param("id")
+	interval := ctx.DefaultQuery("interval", "1h")
+	from, err := parseTimeQuery(ctx.Query("from"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid from: " + err.Error()})
+		return
+	}
+	to, err := parseTimeQuery(ctx.Query("to"))
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid to: " + err.Error()})
+		return
+	}
+
+	candles, err := c.CandleService.GetCandles(id, interval, from, to)
+	if err != nil {
+		status := assetErrorStatus(ctx, err)
+		if errors.Is(err, service.ErrInvalidInterval) || errors.Is(err, service.ErrInvalidRange) || errors.Is(err, service.ErrRangeTooLarge) {
+			status = http.StatusBadRequest
+		}
+		ctx.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	_ = c.LedgerService.Append(0, "GetCandles", "Fetched "+interval+" candles for coin ID: "+id)
+	ctx.JSON(http.StatusOK, candles)
+}
+
+// assetErrorStatus maps coin catalog and market data errors to HTTP status codes. When
+// the market data provider asked for a pause it is passed on as Retry-After.
+func assetErrorStatus(ctx *gin.Context, err error) int {
+	setRetryAfter(ctx, err)
+	if status, ok := marketDataErrorStatus(err); ok {
+		return status
+	}
+	if errors.Is(err, service.ErrInvalidCoinQuery) || errors.Is(err, service.ErrInvalidScreener) {
+		return http.StatusBadRequest
+	}
+	return http.StatusInternalServerError
+}
+
+// setRetryAfter passes on how long a rate limited market data source asked to wait
+func setRetryAfter(ctx *gin.Context, err error) {
+	var mdErr *service.MarketDataError
+	if errors.As(err, &mdErr) && mdErr.RetryAfter > 0 {
+		ctx.Header("Retry-After", strconv.Itoa(int(math.Ceil(mdErr.RetryAfter.Seconds()))))
+	}
+}
+
+// marketDataErrorStatus maps market data provider errors to HTTP status codes; ok is
+// false for any other error
+func marketDataErrorStatus(err error) (status int, ok bool) {
+	switch {
+	case errors.Is(err, service.ErrMarketDataNotFound):
+		return http.StatusNotFound, true
+	case errors.Is(err, service.ErrMarketDataRateLimited):
+		return http.StatusTooManyRequests, true
+	case errors.Is(err, service.ErrMarketDataUnavailable):
+		return http.StatusServiceUnavailable, true
+	case errors.Is(err, service.ErrMarketDataRejected):
+		return http.StatusBadGateway, true
+	default:
+		return 0, false
+	}
+}
+
+// parseTimeQuery reads an RFC3339 or unix-seconds query value; empty means the zero time
+func parseTimeQuery(v string) (time.Time, error) {
+	if v == "" {
+		return time.Time{}, nil
+	}
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(secs, 0).UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339, v)
+	if err != nil {
+		return time.Time{}, errors.New("must be RFC3339 or unix seconds")
+	}
+	return t, nil
+}
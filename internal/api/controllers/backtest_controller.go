@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"ares_api/internal/api/dto"
+	"ares_api/internal/common"
+	service "ares_api/internal/interfaces/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BacktestController struct {
+	Service       service.BacktestService
+	LedgerService service.LedgerService
+}
+
+func NewBacktestController(s service.BacktestService, l service.LedgerService) *BacktestController {
+	return &BacktestController{Service: s, LedgerService: l}
+}
+
+// @Summary Submit a backtest
+// @Description Queues a strategy replay over stored candles. Poll the returned run until its status is completed or failed.
+// @Tags Backtests
+// @Accept json
+// @Produce json
+// @Param request body dto.BacktestRequest true "Backtest"
+// @Success 202 {object} dto.BacktestRunDTO
+// @Failure 400 {object} map[string]string
+// @Security BearerAuth
+// @Router /backtests [post]
+func (c *BacktestController) Submit(ctx *gin.Context) {
+	var req dto.BacktestRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		common.JSON(ctx, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := ctx.GetUint("userID")
+
+	res, err := c.Service.Submit(userID, req)
+	if err != nil {
+		common.JSON(ctx, backtestErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	_ = c.LedgerService.Append(userID, "SubmitBacktest", "Queued "+req.Strategy.Type+" backtest "+strconv.FormatUint(uint64(res.ID), 10))
+	common.JSON(ctx, http.StatusAccepted, res)
+}
+
+// @Summary Get a backtest
+// @Description Status of a backtest run; completed runs include the summary, trade list and equity curve
+// @Tags Backtests
+// @Produce json
+// @Param id path int true "Backtest ID"
+// @Success 200 {object} dto.BacktestRunDTO
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /backtests/{id} [get]
+func (c *BacktestController) Get(ctx *gin.Context) {
+	runID, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		common.JSON(ctx, http.StatusBadRequest, gin.H{"error": "invalid backtest id"})
+		return
+	}
+
+	userID := ctx.GetUint("userID")
+
+	res, err := c.Service.Get(userID, uint(runID))
+	if err != nil {
+		common.JSON(ctx, backtestErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	common.JSON(ctx, http.StatusOK, res)
+}
+
+// @Summary List backtests
+// @Description The user's most recent backtest runs with their status and summary
+// @Tags Backtests
+// @Produce json
+// @Param limit query int false "Number of runs" default(20)
+// @Success 200 {array} dto.BacktestRunDTO
+// @Security BearerAuth
+// @Router /backtests [get]
+func (c *BacktestController) List(ctx *gin.Context) {
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 {
+		limit = 20
+	}
+
+	userID := ctx.GetUint("userID")
+
+	res, err := c.Service.List(userID, limit)
+	if err != nil {
+		common.JSON(ctx, http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	common.JSON(ctx, http.StatusOK, res)
+}
+
+// backtestErrorStatus maps backtest errors to HTTP status codes
+func backtestErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrBacktestNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrInvalidBacktest), errors.Is(err, service.ErrInvalidInterval):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
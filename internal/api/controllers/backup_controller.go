@@ -59,7 +59,7 @@ func (bc *BackupController) Export(c *gin.Context) {
 
 	// Export user data
 	tables := []string{
-		"users", "chats", "trades", "settings", "ledgers", "balances",
+		"users", "chats", "orders", "fills", "settings", "ledgers", "portfolios", "balances",
 		"memory_snapshots", "chat_messages", "conversation_imports",
 		"file_scan_results", "ares_configs",
 	}
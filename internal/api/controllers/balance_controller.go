@@ -3,6 +3,8 @@ package controllers
 import (
 	"ares_api/internal/api/dto"
 	"ares_api/internal/interfaces/service"
+	"ares_api/internal/models"
+	"errors"
 	"fmt"
 	"net/http"
 
@@ -11,46 +13,55 @@ import (
 
 type BalanceController struct {
 	Service service.BalanceService
+	EquityService service.EquityService
 	LedgerService service.LedgerService
 }
 
-func NewBalanceController(s service.BalanceService , l service.LedgerService ) *BalanceController {
-	return &BalanceController{Service: s , LedgerService: l}
+func NewBalanceController(s service.BalanceService , e service.EquityService, l service.LedgerService ) *BalanceController {
+	return &BalanceController{Service: s , EquityService: e, LedgerService: l}
 }
 
-// GetUSDBalance godoc
-// @Summary      Get user USD balance
-// @Description  Fetch the current USD balance for the authenticated user
+// GetBalance godoc
+// @Summary      Get user balance
+// @Description  Fetch the current cash balance in one currency for the authenticated user
 // @Tags         balance
 // @Produce      json
+// @Param        currency  query     string  false  "USD, EUR, GBP or BTC"  default(USD)
+// @Param        portfolio_id  query  int  false  "Portfolio; defaults to the user's default portfolio"
 // @Success      200  {object}  dto.BalanceDTO
 // @Security BearerAuth
+// @Failure      400  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
 // @Router       /balances [get]
-func (c *BalanceController) GetUSDBalance(ctx *gin.Context) {
+func (c *BalanceController) GetBalance(ctx *gin.Context) {
 	userID, exists := ctx.Get("userID")
 	if !exists {
 		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
 		return
 	}
+	portfolioID, ok := portfolioParam(ctx)
+	if !ok {
+		return
+	}
 
-	balance, err := c.Service.GetUSDBalance(userID.(uint))
+	balance, err := c.Service.GetBalance(userID.(uint), portfolioID, ctx.Query("currency"))
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(balanceErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
-    _ = c.LedgerService.Append(userID.(uint),  "GetUSDBalance", "Fetched USD balance")
+    _ = c.LedgerService.Append(userID.(uint),  "GetBalance", "Fetched "+balance.Asset+" balance")
 	ctx.JSON(http.StatusOK, balance)
 }
 
 // InitializeBalance godoc
 // @Summary      Initialize balance
-// @Description  Create a default balance of 10k USD for the authenticated user
+// @Description  Create the default portfolio with a balance of 10k USD for the authenticated user
 // @Tags         balance
 // @Produce      json
 // @Success      201  {object}  dto.BalanceDTO
 // @Security BearerAuth
 // @Failure      500  {object}  map[string]string
+// @Param        Idempotency-Key  header  string  false  "Replays the first response for retries with the same key"
 // @Router       /balances/init [post]
 func (c *BalanceController) InitializeBalance(ctx *gin.Context) {
 	userID, exists := ctx.Get("userID")
@@ -70,12 +81,14 @@ func (c *BalanceController) InitializeBalance(ctx *gin.Context) {
 
 // ResetBalance godoc
 // @Summary      Reset balance
-// @Description  Reset the user's balance back to default (10k USD)
+// @Description  Reset a portfolio's balance back to default (10k USD) and empty the other currencies. Cash held by open orders is kept, so USD may end above the default.
 // @Tags         balance
 // @Produce      json
+// @Param        portfolio_id  query  int  false  "Portfolio; defaults to the user's default portfolio"
 // @Success      200  {object}  dto.BalanceDTO
 // @Security BearerAuth
 // @Failure      500  {object}  map[string]string
+// @Param        Idempotency-Key  header  string  false  "Replays the first response for retries with the same key"
 // @Router       /balances/reset [post]
 func (c *BalanceController) ResetBalance(ctx *gin.Context) {
 	userID, exists := ctx.Get("userID")
@@ -83,10 +96,14 @@ func (c *BalanceController) ResetBalance(ctx *gin.Context) {
 		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
 		return
 	}
+	portfolioID, ok := portfolioParam(ctx)
+	if !ok {
+		return
+	}
 
-	balance, err := c.Service.ResetUSDBalance(userID.(uint))
+	balance, err := c.Service.ResetBalance(userID.(uint), portfolioID)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(balanceErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	_ = c.LedgerService.Append(userID.(uint),  "ResetBalance", "Reset USD balance to default")
@@ -95,14 +112,17 @@ func (c *BalanceController) ResetBalance(ctx *gin.Context) {
 
 // UpdateBalance godoc
 // @Summary      Update balance
-// @Description  Add or subtract USD from user's balance (used internally for trades)
+// @Description  Add or subtract cash from one of the user's balances (used internally for trades)
 // @Tags         balance
 // @Accept       json
 // @Produce      json
-// @Param        delta    body      dto.BalanceDTO    true  "Balance delta (amount field used)"
+// @Param        delta    body      dto.BalanceDTO    true  "Balance delta (amount field used; asset defaults to USD)"
+// @Param        portfolio_id  query  int  false  "Portfolio; defaults to the user's default portfolio"
 // @Success      200  {object}  dto.BalanceDTO
 // @Security BearerAuth
+// @Failure      400  {object}  map[string]string
 // @Failure      500  {object}  map[string]string
+// @Param        Idempotency-Key  header  string  false  "Replays the first response for retries with the same key"
 // @Router       /balances/update [put]
 func (c *BalanceController) UpdateBalance(ctx *gin.Context) {
 	userID, exists := ctx.Get("userID")
@@ -110,6 +130,10 @@ func (c *BalanceController) UpdateBalance(ctx *gin.Context) {
 		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
 		return
 	}
+	portfolioID, ok := portfolioParam(ctx)
+	if !ok {
+		return
+	}
 
 	var req dto.BalanceDTO
 	if err := ctx.ShouldBindJSON(&req); err != nil {
@@ -117,11 +141,137 @@ func (c *BalanceController) UpdateBalance(ctx *gin.Context) {
 		return
 	}
 
-	balance, err := c.Service.UpdateUSDBalance(userID.(uint), req.Amount)
+	balance, err := c.Service.UpdateBalance(userID.(uint), portfolioID, req.Asset, req.Amount)
 	if err != nil {
-		ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		ctx.JSON(balanceErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
-	_ = c.LedgerService.Append(userID.(uint),  "UpdateBalance", "Updated USD balance by " +  fmt.Sprintf("%.2f", req.Amount))
+	_ = c.LedgerService.Append(userID.(uint),  "UpdateBalance", "Updated " + balance.Asset + " balance by " +  req.Amount.StringFixed(models.CurrencyScale(balance.Asset)))
 	ctx.JSON(http.StatusOK, balance)
 }
+
+// Convert godoc
+// @Summary      Convert currency
+// @Description  Exchange part of one cash balance for another currency at the current rate
+// @Tags         balance
+// @Accept       json
+// @Produce      json
+// @Param        request  body      dto.ConvertRequest  true  "Conversion"
+// @Param        portfolio_id  query  int  false  "Portfolio; defaults to the user's default portfolio"
+// @Success      200  {object}  dto.ConversionDTO
+// @Security BearerAuth
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Param        Idempotency-Key  header  string  false  "Replays the first response for retries with the same key"
+// @Router       /balances/convert [post]
+func (c *BalanceController) Convert(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	portfolioID, ok := portfolioParam(ctx)
+	if !ok {
+		return
+	}
+
+	var req dto.ConvertRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	conversion, err := c.Service.Convert(userID.(uint), portfolioID, req)
+	if err != nil {
+		ctx.JSON(balanceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	_ = c.LedgerService.Append(userID.(uint), "Convert", fmt.Sprintf("Converted %s %s to %s %s",
+		conversion.Amount, conversion.From, conversion.Received, conversion.To))
+	ctx.JSON(http.StatusOK, conversion)
+}
+
+// balanceErrorStatus maps balance, portfolio and currency errors to HTTP status codes
+func balanceErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrPortfolioNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrPortfolioArchived):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrUnsupportedCurrency),
+		errors.Is(err, service.ErrInsufficientFunds),
+		errors.Is(err, service.ErrInvalidConversion):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// GetPortfolio godoc
+// @Summary      Get portfolio
+// @Description  List every cash balance and coin holding valued at the current market price in the display currency
+// @Tags         balance
+// @Produce      json
+// @Param        currency  query     string  false  "Display currency; defaults to the one in settings, else USD"
+// @Param        portfolio_id  query  int  false  "Portfolio; defaults to the user's default portfolio"
+// @Success      200  {object}  dto.PortfolioDTO
+// @Security BearerAuth
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /balances/portfolio [get]
+func (c *BalanceController) GetPortfolio(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	portfolioID, ok := portfolioParam(ctx)
+	if !ok {
+		return
+	}
+
+	portfolio, err := c.Service.GetPortfolio(userID.(uint), portfolioID, ctx.Query("currency"))
+	if err != nil {
+		ctx.JSON(balanceErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	_ = c.LedgerService.Append(userID.(uint), "GetPortfolio", "Fetched portfolio holdings")
+	ctx.JSON(http.StatusOK, portfolio)
+}
+
+// GetEquityCurve godoc
+// @Summary      Get equity curve
+// @Description  Portfolio value snapshots over a window with max drawdown, volatility, Sharpe and Sortino
+// @Tags         balance
+// @Produce      json
+// @Param        window  query     string  false  "1d, 7d, 30d, 90d, 1y or all"  default(30d)
+// @Param        portfolio_id  query  int  false  "Portfolio; defaults to the user's default portfolio"
+// @Success      200  {object}  dto.EquityCurveDTO
+// @Security BearerAuth
+// @Failure      400  {object}  map[string]string
+// @Failure      500  {object}  map[string]string
+// @Router       /balances/equity [get]
+func (c *BalanceController) GetEquityCurve(ctx *gin.Context) {
+	userID, exists := ctx.Get("userID")
+	if !exists {
+		ctx.JSON(http.StatusUnauthorized, gin.H{"error": "user not authenticated"})
+		return
+	}
+	portfolioID, ok := portfolioParam(ctx)
+	if !ok {
+		return
+	}
+
+	window := ctx.DefaultQuery("window", "30d")
+	curve, err := c.EquityService.GetEquityCurve(userID.(uint), portfolioID, window)
+	if err != nil {
+		status := portfolioErrorStatus(err)
+		if errors.Is(err, service.ErrInvalidWindow) {
+			status = http.StatusBadRequest
+		}
+		ctx.JSON(status, gin.H{"error": err.Error()})
+		return
+	}
+	_ = c.LedgerService.Append(userID.(uint), "GetEquityCurve", "Fetched equity curve for window "+window)
+	ctx.JSON(http.StatusOK, curve)
+}
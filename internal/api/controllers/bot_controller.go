@@ -0,0 +1,198 @@
+package controllers
+
+import (
+	"ares_api/internal/api/dto"
+	"ares_api/internal/common"
+	service "ares_api/internal/interfaces/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type BotController struct {
+	Service       service.BotService
+	LedgerService service.LedgerService
+}
+
+func NewBotController(s service.BotService, l service.LedgerService) *BotController {
+	return &BotController{Service: s, LedgerService: l}
+}
+
+// @Summary Create a bot
+// @Description Define a dca, grid or dip bot. Bots are created paused; start them to let the runner trade.
+// @Tags Bots
+// @Accept json
+// @Produce json
+// @Param request body dto.CreateBotRequest true "Bot"
+// @Success 201 {object} dto.BotDTO
+// @Failure 400 {object} map[string]string
+// @Security BearerAuth
+// @Router /bots [post]
+func (c *BotController) Create(ctx *gin.Context) {
+	var req dto.CreateBotRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		common.JSON(ctx, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := ctx.GetUint("userID")
+
+	res, err := c.Service.Create(userID, req)
+	if err != nil {
+		common.JSON(ctx, botErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	_ = c.LedgerService.Append(userID, "CreateBot", "Created "+res.Type+" bot "+strconv.FormatUint(uint64(res.ID), 10)+" for symbol: "+res.Symbol)
+	common.JSON(ctx, http.StatusCreated, res)
+}
+
+// @Summary List bots
+// @Tags Bots
+// @Produce json
+// @Success 200 {array} dto.BotDTO
+// @Security BearerAuth
+// @Router /bots [get]
+func (c *BotController) List(ctx *gin.Context) {
+	userID := ctx.GetUint("userID")
+
+	res, err := c.Service.List(userID)
+	if err != nil {
+		common.JSON(ctx, http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	common.JSON(ctx, http.StatusOK, res)
+}
+
+// @Summary Get a bot
+// @Tags Bots
+// @Produce json
+// @Param id path int true "Bot ID"
+// @Success 200 {object} dto.BotDTO
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /bots/{id} [get]
+func (c *BotController) Get(ctx *gin.Context) {
+	botID, ok := botIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	res, err := c.Service.Get(ctx.GetUint("userID"), botID)
+	if err != nil {
+		common.JSON(ctx, botErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	common.JSON(ctx, http.StatusOK, res)
+}
+
+// @Summary Start a bot
+// @Description Runs a paused bot from the next tick
+// @Tags Bots
+// @Produce json
+// @Param id path int true "Bot ID"
+// @Success 200 {object} dto.BotDTO
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Security BearerAuth
+// @Router /bots/{id}/start [post]
+func (c *BotController) Start(ctx *gin.Context) {
+	c.changeStatus(ctx, "StartBot", c.Service.Start)
+}
+
+// @Summary Pause a bot
+// @Tags Bots
+// @Produce json
+// @Param id path int true "Bot ID"
+// @Success 200 {object} dto.BotDTO
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Security BearerAuth
+// @Router /bots/{id}/pause [post]
+func (c *BotController) Pause(ctx *gin.Context) {
+	c.changeStatus(ctx, "PauseBot", c.Service.Pause)
+}
+
+// @Summary Stop a bot
+// @Description Retires a bot for good; coins it bought stay in the account
+// @Tags Bots
+// @Produce json
+// @Param id path int true "Bot ID"
+// @Success 200 {object} dto.BotDTO
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /bots/{id}/stop [post]
+func (c *BotController) Stop(ctx *gin.Context) {
+	c.changeStatus(ctx, "StopBot", c.Service.Stop)
+}
+
+func (c *BotController) changeStatus(ctx *gin.Context, action string, change func(userID, botID uint) (*dto.BotDTO, error)) {
+	botID, ok := botIDParam(ctx)
+	if !ok {
+		return
+	}
+
+	userID := ctx.GetUint("userID")
+
+	res, err := change(userID, botID)
+	if err != nil {
+		common.JSON(ctx, botErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	_ = c.LedgerService.Append(userID, action, "Bot "+ctx.Param("id")+" is now "+res.Status)
+	common.JSON(ctx, http.StatusOK, res)
+}
+
+// @Summary Get a bot's trade log
+// @Description Orders the bot placed, newest first, with the reason for each
+// @Tags Bots
+// @Produce json
+// @Param id path int true "Bot ID"
+// @Param limit query int false "Number of trades" default(50)
+// @Success 200 {array} dto.BotTradeDTO
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /bots/{id}/trades [get]
+func (c *BotController) GetTrades(ctx *gin.Context) {
+	botID, ok := botIDParam(ctx)
+	if !ok {
+		return
+	}
+	limit, err := strconv.Atoi(ctx.DefaultQuery("limit", "50"))
+	if err != nil || limit <= 0 {
+		limit = 50
+	}
+
+	res, err := c.Service.GetTrades(ctx.GetUint("userID"), botID, limit)
+	if err != nil {
+		common.JSON(ctx, botErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	common.JSON(ctx, http.StatusOK, res)
+}
+
+func botIDParam(ctx *gin.Context) (uint, bool) {
+	botID, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		common.JSON(ctx, http.StatusBadRequest, gin.H{"error": "invalid bot id"})
+		return 0, false
+	}
+	return uint(botID), true
+}
+
+// botErrorStatus maps bot and portfolio errors to HTTP status codes
+func botErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrBotNotFound),
+		errors.Is(err, service.ErrPortfolioNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrBotStopped),
+		errors.Is(err, service.ErrPortfolioArchived):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrInvalidBot):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
@@ -0,0 +1,127 @@
+package controllers
+
+import (
+	"ares_api/internal/api/dto"
+	"ares_api/internal/common"
+	service "ares_api/internal/interfaces/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type PortfolioController struct {
+	Service       service.PortfolioService
+	LedgerService service.LedgerService
+}
+
+func NewPortfolioController(s service.PortfolioService, l service.LedgerService) *PortfolioController {
+	return &PortfolioController{Service: s, LedgerService: l}
+}
+
+// @Summary Create a portfolio
+// @Description Open a named paper portfolio with its own balances, holdings, orders and performance
+// @Tags Portfolios
+// @Accept json
+// @Produce json
+// @Param request body dto.CreatePortfolioRequest true "Portfolio"
+// @Success 201 {object} dto.PortfolioSummaryDTO
+// @Failure 400 {object} map[string]string
+// @Security BearerAuth
+// @Router /portfolios [post]
+func (c *PortfolioController) Create(ctx *gin.Context) {
+	var req dto.CreatePortfolioRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		common.JSON(ctx, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := ctx.GetUint("userID")
+
+	res, err := c.Service.Create(userID, req)
+	if err != nil {
+		common.JSON(ctx, portfolioErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	_ = c.LedgerService.Append(userID, "CreatePortfolio", "Created portfolio "+strconv.FormatUint(uint64(res.ID), 10)+": "+res.Name)
+	common.JSON(ctx, http.StatusCreated, res)
+}
+
+// @Summary List portfolios
+// @Tags Portfolios
+// @Produce json
+// @Param include_archived query bool false "Include archived portfolios"
+// @Success 200 {array} dto.PortfolioSummaryDTO
+// @Security BearerAuth
+// @Router /portfolios [get]
+func (c *PortfolioController) List(ctx *gin.Context) {
+	userID := ctx.GetUint("userID")
+	includeArchived, _ := strconv.ParseBool(ctx.Query("include_archived"))
+
+	res, err := c.Service.List(userID, includeArchived)
+	if err != nil {
+		common.JSON(ctx, http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	common.JSON(ctx, http.StatusOK, res)
+}
+
+// @Summary Archive a portfolio
+// @Description Archived portfolios stay readable but can no longer trade. The default
+// @Description portfolio and portfolios with open orders can't be archived.
+// @Tags Portfolios
+// @Produce json
+// @Param id path int true "Portfolio ID"
+// @Success 200 {object} dto.PortfolioSummaryDTO
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Security BearerAuth
+// @Router /portfolios/{id}/archive [post]
+func (c *PortfolioController) Archive(ctx *gin.Context) {
+	portfolioID, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil || portfolioID == 0 {
+		common.JSON(ctx, http.StatusBadRequest, gin.H{"error": "invalid portfolio id"})
+		return
+	}
+
+	userID := ctx.GetUint("userID")
+
+	res, err := c.Service.Archive(userID, uint(portfolioID))
+	if err != nil {
+		common.JSON(ctx, portfolioErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	_ = c.LedgerService.Append(userID, "ArchivePortfolio", "Archived portfolio "+ctx.Param("id")+": "+res.Name)
+	common.JSON(ctx, http.StatusOK, res)
+}
+
+// portfolioParam reads the optional portfolio_id query parameter that selects the
+// portfolio a request acts on. Leaving it out selects the user's default portfolio (0).
+func portfolioParam(ctx *gin.Context) (uint, bool) {
+	raw := ctx.Query("portfolio_id")
+	if raw == "" {
+		return 0, true
+	}
+	portfolioID, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil || portfolioID == 0 {
+		common.JSON(ctx, http.StatusBadRequest, gin.H{"error": "invalid portfolio_id"})
+		return 0, false
+	}
+	return uint(portfolioID), true
+}
+
+// portfolioErrorStatus maps portfolio errors to HTTP status codes
+func portfolioErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrPortfolioNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrPortfolioArchived):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrInvalidPortfolio):
+		return http.StatusBadRequest
+	default:
+		return http.StatusInternalServerError
+	}
+}
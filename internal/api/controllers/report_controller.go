@@ -0,0 +1,181 @@
+package controllers
+
+import (
+	"ares_api/internal/api/dto"
+	"ares_api/internal/common"
+	service "ares_api/internal/interfaces/service"
+	"encoding/csv"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type ReportController struct {
+	Service service.ReportService
+}
+
+func NewReportController(s service.ReportService) *ReportController {
+	return &ReportController{Service: s}
+}
+
+// @Summary Export trades
+// @Description Download the portfolio's orders, or with kind=fills the executions that
+// @Description filled them, oldest first, as JSON or CSV. Dates take RFC 3339 or
+// @Description YYYY-MM-DD; to is exclusive.
+// @Tags Reports
+// @Produce json,text/csv
+// @Param portfolio_id query int false "Portfolio; defaults to the user's default portfolio"
+// @Param format query string false "json (default) or csv"
+// @Param kind query string false "orders (default) or fills"
+// @Param coin_id query string false "Coin ID"
+// @Param side query string false "buy or sell"
+// @Param from query string false "Created at or after"
+// @Param to query string false "Created before"
+// @Success 200 {array} dto.TradeExportDTO
+// @Failure 400 {object} map[string]string
+// @Security BearerAuth
+// @Router /trades/export [get]
+func (c *ReportController) ExportTrades(ctx *gin.Context) {
+	var filter dto.TradeExportFilter
+	if err := ctx.ShouldBindQuery(&filter); err != nil {
+		common.JSON(ctx, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	csvFormat, ok := reportFormat(ctx)
+	if !ok {
+		return
+	}
+
+	userID := ctx.GetUint("userID")
+	portfolioID, ok := portfolioParam(ctx)
+	if !ok {
+		return
+	}
+
+	rows, err := c.Service.ExportTrades(userID, portfolioID, filter)
+	if err != nil {
+		common.JSON(ctx, reportErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if !csvFormat {
+		common.JSON(ctx, http.StatusOK, rows)
+		return
+	}
+
+	records := [][]string{{
+		"id", "order_id", "created_at", "portfolio_id", "coin_id", "symbol", "side", "type", "status", "liquidity",
+		"quantity", "filled_quantity", "price", "average_price", "fee", "quote_currency", "fx_rate", "price_usd", "fee_usd",
+	}}
+	for _, r := range rows {
+		records = append(records, []string{
+			strconv.FormatUint(uint64(r.ID), 10),
+			strconv.FormatUint(uint64(r.OrderID), 10),
+			r.CreatedAt.UTC().Format(time.RFC3339),
+			strconv.FormatUint(uint64(r.PortfolioID), 10),
+			r.CoinID, r.Symbol, r.Side, r.Type, r.Status, r.Liquidity,
+			r.Quantity.String(), r.FilledQuantity.String(), r.Price.String(), r.AveragePrice.String(), r.Fee.String(),
+			r.QuoteCurrency, r.FXRate.String(), r.PriceUSD.String(), r.FeeUSD.String(),
+		})
+	}
+	filename := "orders.csv"
+	if filter.Kind == "fills" {
+		filename = "fills.csv"
+	}
+	writeCSV(ctx, filename, records)
+}
+
+// @Summary Tax-lot report
+// @Description Pair every sell with the buys it disposed of under FIFO, LIFO or HIFO and
+// @Description sum realized gains per year, split into short term (held a year or less)
+// @Description and long term. Amounts are in USD, fees included. The CSV form lists the
+// @Description disposals.
+// @Tags Reports
+// @Produce json,text/csv
+// @Param portfolio_id query int false "Portfolio; defaults to the user's default portfolio"
+// @Param method query string false "fifo (default), lifo or hifo"
+// @Param year query int false "Only report disposals in this year"
+// @Param format query string false "json (default) or csv"
+// @Success 200 {object} dto.TaxReportDTO
+// @Failure 400 {object} map[string]string
+// @Security BearerAuth
+// @Router /trades/tax-report [get]
+func (c *ReportController) TaxReport(ctx *gin.Context) {
+	year := 0
+	if raw := ctx.Query("year"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			common.JSON(ctx, http.StatusBadRequest, gin.H{"error": "invalid year"})
+			return
+		}
+		year = parsed
+	}
+	csvFormat, ok := reportFormat(ctx)
+	if !ok {
+		return
+	}
+
+	userID := ctx.GetUint("userID")
+	portfolioID, ok := portfolioParam(ctx)
+	if !ok {
+		return
+	}
+
+	report, err := c.Service.TaxReport(userID, portfolioID, ctx.Query("method"), year)
+	if err != nil {
+		common.JSON(ctx, reportErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	if !csvFormat {
+		common.JSON(ctx, http.StatusOK, report)
+		return
+	}
+
+	records := [][]string{{
+		"coin_id", "symbol", "quantity", "acquired_at", "disposed_at", "acquisition_trade_id", "disposal_trade_id",
+		"proceeds", "cost_basis", "gain", "term",
+	}}
+	for _, d := range report.Disposals {
+		records = append(records, []string{
+			d.CoinID, d.Symbol, d.Quantity.String(),
+			d.AcquiredAt.UTC().Format(time.RFC3339), d.DisposedAt.UTC().Format(time.RFC3339),
+			strconv.FormatUint(uint64(d.AcquisitionTradeID), 10), strconv.FormatUint(uint64(d.DisposalTradeID), 10),
+			d.Proceeds.String(), d.CostBasis.String(), d.Gain.String(), d.Term,
+		})
+	}
+	writeCSV(ctx, "tax-report-"+report.Method+".csv", records)
+}
+
+// reportFormat reads the format query parameter; true selects CSV
+func reportFormat(ctx *gin.Context) (bool, bool) {
+	switch ctx.DefaultQuery("format", "json") {
+	case "json":
+		return false, true
+	case "csv":
+		return true, true
+	default:
+		common.JSON(ctx, http.StatusBadRequest, gin.H{"error": "invalid format: must be json or csv"})
+		return false, false
+	}
+}
+
+// writeCSV sends records as a CSV attachment
+func writeCSV(ctx *gin.Context, filename string, records [][]string) {
+	ctx.Header("Content-Type", "text/csv; charset=utf-8")
+	ctx.Header("Content-Disposition", `attachment; filename="`+filename+`"`)
+	ctx.Status(http.StatusOK)
+	w := csv.NewWriter(ctx.Writer)
+	_ = w.WriteAll(records)
+}
+
+// reportErrorStatus maps report errors to HTTP status codes
+func reportErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidExportFilter), errors.Is(err, service.ErrInvalidLotMethod):
+		return http.StatusBadRequest
+	default:
+		return portfolioErrorStatus(err)
+	}
+}
@@ -0,0 +1,86 @@
+package controllers
+
+import (
+	"ares_api/internal/api/dto"
+	"ares_api/internal/common"
+	service "ares_api/internal/interfaces/service"
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+type RiskController struct {
+	Service       service.RiskService
+	LedgerService service.LedgerService
+}
+
+func NewRiskController(s service.RiskService, l service.LedgerService) *RiskController {
+	return &RiskController{Service: s, LedgerService: l}
+}
+
+// @Summary Get risk limits
+// @Description Pre-trade limits checked before market and limit orders commit; 0 means off
+// @Tags Risk
+// @Produce json
+// @Param portfolio_id query int false "Portfolio; defaults to the user's default portfolio"
+// @Success 200 {object} dto.RiskLimitsDTO
+// @Security BearerAuth
+// @Router /risk/limits [get]
+func (c *RiskController) GetLimits(ctx *gin.Context) {
+	userID := ctx.GetUint("userID")
+	portfolioID, ok := portfolioParam(ctx)
+	if !ok {
+		return
+	}
+
+	res, err := c.Service.GetLimits(userID, portfolioID)
+	if err != nil {
+		common.JSON(ctx, riskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	common.JSON(ctx, http.StatusOK, res)
+}
+
+// @Summary Set risk limits
+// @Description Replace a portfolio's limits on position value, order notional, daily loss,
+// @Description open orders and concentration. In reject mode breaking orders are refused;
+// @Description in warn mode they go through with the broken limits listed.
+// @Tags Risk
+// @Accept json
+// @Produce json
+// @Param portfolio_id query int false "Portfolio; defaults to the user's default portfolio"
+// @Param request body dto.RiskLimitsRequest true "Limits"
+// @Success 200 {object} dto.RiskLimitsDTO
+// @Failure 400 {object} map[string]string
+// @Security BearerAuth
+// @Router /risk/limits [put]
+func (c *RiskController) SetLimits(ctx *gin.Context) {
+	var req dto.RiskLimitsRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		common.JSON(ctx, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := ctx.GetUint("userID")
+	portfolioID, ok := portfolioParam(ctx)
+	if !ok {
+		return
+	}
+
+	res, err := c.Service.SetLimits(userID, portfolioID, req)
+	if err != nil {
+		common.JSON(ctx, riskErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	_ = c.LedgerService.Append(userID, "SetRiskLimits", res)
+	common.JSON(ctx, http.StatusOK, res)
+}
+
+// riskErrorStatus maps risk limit and portfolio errors to HTTP status codes
+func riskErrorStatus(err error) int {
+	if errors.Is(err, service.ErrInvalidRiskLimits) {
+		return http.StatusBadRequest
+	}
+	return portfolioErrorStatus(err)
+}
@@ -43,3 +43,90 @@ func (sc *SettingsController) SaveAPIKey(c *gin.Context) {
 	common.JSON(c, http.StatusOK, dto.APIKeyResponse{Message: "API key saved successfully"})
 }
 
+// @Summary Get fee schedule
+// @Description Current execution cost preset and every preset available
+// @Tags Settings
+// @Produce  json
+// @Success 200 {object} dto.FeeScheduleResponse
+// @Security BearerAuth
+// @Router /settings/fee-schedule [get]
+func (sc *SettingsController) GetFeeSchedule(c *gin.Context) {
+	userID := c.GetUint("userID")
+	res, err := sc.Service.GetFeeSchedule(userID)
+	if err != nil {
+		common.JSON(c, http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	common.JSON(c, http.StatusOK, res)
+}
+
+// @Summary Set fee schedule
+// @Description Choose the fee and slippage preset applied to future fills
+// @Tags Settings
+// @Accept  json
+// @Produce  json
+// @Param   request body dto.FeeScheduleRequest true "Fee schedule"
+// @Success 200 {object} dto.FeeScheduleResponse
+// @Security BearerAuth
+// @Router /settings/fee-schedule [post]
+func (sc *SettingsController) SetFeeSchedule(c *gin.Context) {
+	var req dto.FeeScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.JSON(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("userID")
+	res, err := sc.Service.SetFeeSchedule(userID, req.FeeSchedule)
+	if err != nil {
+		common.JSON(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	_ = sc.LedgerService.Append(userID, "settings", "Set fee schedule to "+req.FeeSchedule)
+
+	common.JSON(c, http.StatusOK, res)
+}
+
+// @Summary Get display currency
+// @Description Currency portfolio totals are reported in and every currency available
+// @Tags Settings
+// @Produce  json
+// @Success 200 {object} dto.DisplayCurrencyResponse
+// @Security BearerAuth
+// @Router /settings/display-currency [get]
+func (sc *SettingsController) GetDisplayCurrency(c *gin.Context) {
+	userID := c.GetUint("userID")
+	res, err := sc.Service.GetDisplayCurrency(userID)
+	if err != nil {
+		common.JSON(c, http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	common.JSON(c, http.StatusOK, res)
+}
+
+// @Summary Set display currency
+// @Description Choose the currency portfolio totals are reported in
+// @Tags Settings
+// @Accept  json
+// @Produce  json
+// @Param   request body dto.DisplayCurrencyRequest true "Display currency"
+// @Success 200 {object} dto.DisplayCurrencyResponse
+// @Security BearerAuth
+// @Router /settings/display-currency [post]
+func (sc *SettingsController) SetDisplayCurrency(c *gin.Context) {
+	var req dto.DisplayCurrencyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		common.JSON(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := c.GetUint("userID")
+	res, err := sc.Service.SetDisplayCurrency(userID, req.Currency)
+	if err != nil {
+		common.JSON(c, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	_ = sc.LedgerService.Append(userID, "settings", "Set display currency to "+res.Current)
+
+	common.JSON(c, http.StatusOK, res)
+}
@@ -4,6 +4,7 @@ import (
 	"ares_api/internal/api/dto"
 	"ares_api/internal/common"
 	service "ares_api/internal/interfaces/service"
+	"errors"
 	"net/http"
 	"strconv"
 
@@ -12,11 +13,12 @@ import (
 
 type TradeController struct {
 	Service service.TradeService
+	PerformanceService service.PerformanceService
 	LedgerService service.LedgerService
 }
 
-func NewTradeController(s service.TradeService , l service.LedgerService) *TradeController {
-	return &TradeController{Service: s , LedgerService: l}
+func NewTradeController(s service.TradeService , p service.PerformanceService, l service.LedgerService) *TradeController {
+	return &TradeController{Service: s , PerformanceService: p, LedgerService: l}
 }
 
 // @Summary Execute Market Order
@@ -24,8 +26,11 @@ func NewTradeController(s service.TradeService , l service.LedgerService) *Trade
 // @Accept json
 // @Produce json
 // @Param request body dto.MarketOrderRequest true "Market Order"
+// @Failure 422 {object} map[string]interface{} "Rejected by risk limits; violations lists them"
+// @Param portfolio_id query int false "Portfolio to trade in; defaults to the user's default portfolio"
 // @Success 200 {object} dto.TradeResponse
 // @Security BearerAuth
+// @Param Idempotency-Key header string false "Replays the first response for retries with the same key"
 // @Router /trades/market [post]
 func (c *TradeController) MarketOrder(ctx *gin.Context) {
 	var req dto.MarketOrderRequest
@@ -35,13 +40,17 @@ func (c *TradeController) MarketOrder(ctx *gin.Context) {
 	}
 
 	userID := ctx.GetUint("userID") // from JWT middleware
+	portfolioID, ok := portfolioParam(ctx)
+	if !ok {
+		return
+	}
 
-	res, err := c.Service.MarketOrder(userID, req)
+	res, err := c.Service.MarketOrder(userID, portfolioID, req)
 	if err != nil {
-		common.JSON(ctx, http.StatusInternalServerError, gin.H{"error": err.Error()})
+		common.JSON(ctx, orderErrorStatus(err), orderErrorBody(err))
 		return
 	}
-	_ = c.LedgerService.Append(userID,  "MarketOrder", "Executed market order for symbol: " + req.Symbol)
+	_ = c.LedgerService.Append(userID,  "MarketOrder", "Executed market order for symbol: " + orderSymbol(res))
 	common.JSON(ctx, http.StatusOK, res)
 }
 
@@ -50,8 +59,11 @@ func (c *TradeController) MarketOrder(ctx *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param request body dto.LimitOrderRequest true "Limit Order"
+// @Failure 422 {object} map[string]interface{} "Rejected by risk limits; violations lists them"
+// @Param portfolio_id query int false "Portfolio to trade in; defaults to the user's default portfolio"
 // @Success 200 {object} dto.TradeResponse
 // @Security BearerAuth
+// @Param Idempotency-Key header string false "Replays the first response for retries with the same key"
 // @Router /trades/limit [post]
 func (c *TradeController) LimitOrder(ctx *gin.Context) {
 	var req dto.LimitOrderRequest
@@ -61,20 +73,239 @@ func (c *TradeController) LimitOrder(ctx *gin.Context) {
 	}
 
 	userID := ctx.GetUint("userID") // from JWT middleware
+	portfolioID, ok := portfolioParam(ctx)
+	if !ok {
+		return
+	}
+
+	res, err := c.Service.LimitOrder(userID, portfolioID, req)
+	if err != nil {
+		common.JSON(ctx, orderErrorStatus(err), orderErrorBody(err))
+		return
+	}
+	_ = c.LedgerService.Append(userID,  "LimitOrder", "Placed limit order for symbol: " + orderSymbol(res))
+	common.JSON(ctx, http.StatusOK, res)
+}
+
+// @Summary Preview an order
+// @Description Run a market or limit order through order execution without placing it: expected fill price, fees,
+// @Description slippage, cash and holding before and after, and risk warnings. Nothing is saved.
+// @Description An order that would be turned away for lack of funds or coins, failed validation or risk limits comes back with rejected set, the reason and any violations.
+// @Tags Trading
+// @Accept json
+// @Produce json
+// @Param request body dto.TradePreviewRequest true "Order to preview"
+// @Param portfolio_id query int false "Portfolio to trade in; defaults to the user's default portfolio"
+// @Success 200 {object} dto.TradePreviewDTO
+// @Failure 400 {object} map[string]string
+// @Security BearerAuth
+// @Router /trades/preview [post]
+func (c *TradeController) PreviewOrder(ctx *gin.Context) {
+	var req dto.TradePreviewRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		common.JSON(ctx, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := ctx.GetUint("userID") // from JWT middleware
+	portfolioID, ok := portfolioParam(ctx)
+	if !ok {
+		return
+	}
+
+	res, err := c.Service.PreviewOrder(userID, portfolioID, req)
+	if err != nil {
+		common.JSON(ctx, orderErrorStatus(err), orderErrorBody(err))
+		return
+	}
+	common.JSON(ctx, http.StatusOK, res)
+}
+
+// @Summary Place a conditional order
+// @Description Place a stop_market, stop_limit, take_profit or trailing_stop order
+// @Tags Trading
+// @Accept json
+// @Produce json
+// @Param request body dto.ConditionalOrderRequest true "Conditional Order"
+// @Param portfolio_id query int false "Portfolio to trade in; defaults to the user's default portfolio"
+// @Success 200 {object} dto.TradeResponse
+// @Failure 422 {object} map[string]interface{} "Rejected by risk limits; violations lists them"
+// @Security BearerAuth
+// @Router /trades/conditional [post]
+func (c *TradeController) ConditionalOrder(ctx *gin.Context) {
+	var req dto.ConditionalOrderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		common.JSON(ctx, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := ctx.GetUint("userID") // from JWT middleware
+	portfolioID, ok := portfolioParam(ctx)
+	if !ok {
+		return
+	}
 
-	res, err := c.Service.LimitOrder(userID, req)
+	res, err := c.Service.ConditionalOrder(userID, portfolioID, req)
 	if err != nil {
-		common.JSON(ctx, http.StatusInternalServerError, gin.H{"error": err.Error()})
+		common.JSON(ctx, orderErrorStatus(err), orderErrorBody(err))
 		return
 	}
-	_ = c.LedgerService.Append(userID,  "LimitOrder", "Placed limit order for symbol: " + req.Symbol)
+	_ = c.LedgerService.Append(userID, "ConditionalOrder", "Placed "+req.Type+" order for symbol: "+orderSymbol(res))
 	common.JSON(ctx, http.StatusOK, res)
 }
 
+// @Summary Place a one-cancels-other bracket
+// @Description Place a take-profit leg and a stop leg; when one fills the other is cancelled
+// @Tags Trading
+// @Accept json
+// @Produce json
+// @Param request body dto.OCOOrderRequest true "OCO Order"
+// @Param portfolio_id query int false "Portfolio to trade in; defaults to the user's default portfolio"
+// @Success 200 {array} dto.TradeResponse
+// @Failure 422 {object} map[string]interface{} "Rejected by risk limits; violations lists them"
+// @Security BearerAuth
+// @Router /trades/oco [post]
+func (c *TradeController) OCOOrder(ctx *gin.Context) {
+	var req dto.OCOOrderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		common.JSON(ctx, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := ctx.GetUint("userID") // from JWT middleware
+	portfolioID, ok := portfolioParam(ctx)
+	if !ok {
+		return
+	}
+
+	res, err := c.Service.OCOOrder(userID, portfolioID, req)
+	if err != nil {
+		common.JSON(ctx, orderErrorStatus(err), orderErrorBody(err))
+		return
+	}
+	_ = c.LedgerService.Append(userID, "OCOOrder", "Placed OCO bracket for symbol: "+orderSymbol(&res[0]))
+	common.JSON(ctx, http.StatusOK, res)
+}
+
+// @Summary Cancel an open order
+// @Description Cancels an open or partially filled order and releases its reserved funds.
+// @Description Cancelling one leg of an OCO bracket cancels the other leg too.
+// @Tags Trading
+// @Produce json
+// @Param id path int true "Order ID"
+// @Success 200 {object} dto.TradeResponse
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Security BearerAuth
+// @Router /trades/orders/{id} [delete]
+func (c *TradeController) CancelOrder(ctx *gin.Context) {
+	orderID, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		common.JSON(ctx, http.StatusBadRequest, gin.H{"error": "invalid order id"})
+		return
+	}
+
+	userID := ctx.GetUint("userID")
+
+	res, err := c.Service.CancelOrder(userID, uint(orderID))
+	if err != nil {
+		common.JSON(ctx, orderErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	_ = c.LedgerService.Append(userID, "CancelOrder", "Cancelled order "+ctx.Param("id"))
+	common.JSON(ctx, http.StatusOK, res)
+}
+
+// @Summary Amend an open order
+// @Description Change the limit price, stop price, quantity or GTD expiry of an open order. The amended order is checked against the risk limits again.
+// @Tags Trading
+// @Accept json
+// @Produce json
+// @Param id path int true "Order ID"
+// @Param request body dto.AmendOrderRequest true "Fields to change"
+// @Success 200 {object} dto.TradeResponse
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Failure 422 {object} map[string]interface{} "Rejected by risk limits; violations lists them"
+// @Security BearerAuth
+// @Router /trades/orders/{id} [patch]
+func (c *TradeController) AmendOrder(ctx *gin.Context) {
+	orderID, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		common.JSON(ctx, http.StatusBadRequest, gin.H{"error": "invalid order id"})
+		return
+	}
+
+	var req dto.AmendOrderRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		common.JSON(ctx, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := ctx.GetUint("userID")
+
+	res, err := c.Service.AmendOrder(userID, uint(orderID), req)
+	if err != nil {
+		common.JSON(ctx, orderErrorStatus(err), orderErrorBody(err))
+		return
+	}
+	_ = c.LedgerService.Append(userID, "AmendOrder", "Amended order "+ctx.Param("id"))
+	common.JSON(ctx, http.StatusOK, res)
+}
+
+// orderErrorStatus maps order lifecycle, validation, funds, portfolio, coin, currency, risk and market data errors to HTTP status codes
+func orderErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrOrderNotFound),
+		errors.Is(err, service.ErrPortfolioNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrOrderNotOpen),
+		errors.Is(err, service.ErrPortfolioArchived):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrUnsupportedCurrency),
+		errors.Is(err, service.ErrInvalidOrderType),
+		errors.Is(err, service.ErrInvalidOrder),
+		errors.Is(err, service.ErrInvalidCoinQuery),
+		errors.Is(err, service.ErrUnknownCoin),
+		errors.Is(err, service.ErrSymbolMismatch),
+		errors.Is(err, service.ErrAmbiguousSymbol):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrRiskRejected),
+		errors.Is(err, service.ErrInsufficientFunds),
+		errors.Is(err, service.ErrInsufficientHolding):
+		return http.StatusUnprocessableEntity
+	}
+	if status, ok := marketDataErrorStatus(err); ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
+
+// orderErrorBody is the error response of order placement. Risk rejections also list
+// every limit the order broke.
+func orderErrorBody(err error) gin.H {
+	body := gin.H{"error": err.Error()}
+	var rejection *service.RiskRejection
+	if errors.As(err, &rejection) {
+		body["violations"] = rejection.Violations
+	}
+	return body
+}
+
+// orderSymbol names the coin of a placed order for the ledger: its resolved symbol, or
+// the coin id when the catalog had none
+func orderSymbol(res *dto.TradeResponse) string {
+	if res.Symbol != "" {
+		return res.Symbol
+	}
+	return res.CoinID
+}
+
 // @Summary Get last N trades for user
 // @Tags Trading
 // @Produce json
 // @Param limit query int true "Number of trades"
+// @Param portfolio_id query int false "Portfolio; defaults to the user's default portfolio"
 // @Success 200 {array} dto.TradeResponse
 // @Security BearerAuth
 // @Router /trades/history [get]
@@ -90,28 +321,37 @@ func (c *TradeController) GetHistory(ctx *gin.Context) {
 	}
 
 	userID := ctx.GetUint("userID")
+	portfolioID, ok := portfolioParam(ctx)
+	if !ok {
+		return
+	}
 
-	res, err := c.Service.GetHistory(userID, limit)
+	res, err := c.Service.GetHistory(userID, portfolioID, limit)
 	if err != nil {
-		common.JSON(ctx, http.StatusInternalServerError, gin.H{"error": err.Error()})
+		common.JSON(ctx, orderErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	_ = c.LedgerService.Append(userID,  "GetHistory", "Fetched last " + limitQuery + " trades")
 	common.JSON(ctx, http.StatusOK, res)
 }
 
-// @Summary Get pending limit orders for user
+// @Summary Get pending limit and conditional orders for user
 // @Tags Trading
 // @Produce json
+// @Param portfolio_id query int false "Portfolio; defaults to the user's default portfolio"
 // @Success 200 {array} dto.TradeResponse
 // @Security BearerAuth
 // @Router /trades/pending [get]
 func (c *TradeController) GetPendingLimitOrders(ctx *gin.Context) {
 	userID := ctx.GetUint("userID")
+	portfolioID, ok := portfolioParam(ctx)
+	if !ok {
+		return
+	}
 
-	res, err := c.Service.GetPendingLimitOrders(userID)
+	res, err := c.Service.GetPendingLimitOrders(userID, portfolioID)
 	if err != nil {
-		common.JSON(ctx, http.StatusInternalServerError, gin.H{"error": err.Error()})
+		common.JSON(ctx, orderErrorStatus(err), gin.H{"error": err.Error()})
 		return
 	}
 	_ = c.LedgerService.Append(userID,  "GetPendingLimitOrders", "Fetched pending limit orders")
@@ -119,71 +359,33 @@ func (c *TradeController) GetPendingLimitOrders(ctx *gin.Context) {
 }
 
 // @Summary Get trading performance stats
-// @Description Calculate P&L and performance metrics (scaffold - uses hardcoded prices)
+// @Description Realized P&L from closed lots, unrealized P&L marked to live prices,
+// @Description fees and round-trip win rate, in total and per coin
 // @Tags Trading
 // @Produce json
-// @Success 200 {object} map[string]interface{}
+// @Param cost_basis query string false "Cost basis method: fifo (default), lifo, hifo or average"
+// @Param portfolio_id query int false "Portfolio; defaults to the user's default portfolio"
+// @Success 200 {object} dto.PerformanceDTO
+// @Failure 400 {object} map[string]string
 // @Security BearerAuth
 // @Router /trades/performance [get]
 func (c *TradeController) GetPerformance(ctx *gin.Context) {
 	userID := ctx.GetUint("userID")
+	portfolioID, ok := portfolioParam(ctx)
+	if !ok {
+		return
+	}
 
-	trades, err := c.Service.GetHistory(userID, 1000) // Get all trades
+	stats, err := c.PerformanceService.GetPerformance(userID, portfolioID, ctx.Query("cost_basis"))
 	if err != nil {
-		common.JSON(ctx, http.StatusInternalServerError, gin.H{"error": err.Error()})
-		return
-	}
-
-	// Hardcoded prices for scaffold
-	prices := map[string]float64{
-		"BTC": 50000.0,
-		"ETH": 3000.0,
-		"SOL": 100.0,
-	}
-
-	totalPnL := 0.0
-	totalTrades := len(trades)
-	wins := 0
-	losses := 0
-
-	for _, trade := range trades {
-		if trade.Status == "filled" {
-			currentPrice := prices[trade.Symbol]
-			if currentPrice == 0 {
-				currentPrice = trade.Price // Fallback to trade price
-			}
-
-			pnl := 0.0
-			if trade.Side == "buy" {
-				pnl = (currentPrice - trade.Price) * trade.Quantity
-			} else {
-				pnl = (trade.Price - currentPrice) * trade.Quantity
-			}
-
-			totalPnL += pnl
-			if pnl > 0 {
-				wins++
-			} else if pnl < 0 {
-				losses++
-			}
+		status := portfolioErrorStatus(err)
+		if errors.Is(err, service.ErrInvalidCostBasis) {
+			status = http.StatusBadRequest
 		}
-	}
-
-	winRate := 0.0
-	if totalTrades > 0 {
-		winRate = float64(wins) / float64(totalTrades) * 100
-	}
-
-	stats := gin.H{
-		"total_trades":   totalTrades,
-		"total_pnl":      totalPnL,
-		"wins":           wins,
-		"losses":         losses,
-		"win_rate":       winRate,
-		"note":           "Using hardcoded prices - BTC: $50k, ETH: $3k, SOL: $100",
-		"current_prices": prices,
+		common.JSON(ctx, status, gin.H{"error": err.Error()})
+		return
 	}
 
 	_ = c.LedgerService.Append(userID, "GetPerformance", "Fetched trading performance stats")
 	common.JSON(ctx, http.StatusOK, stats)
-}
\ No newline at end of file
+}
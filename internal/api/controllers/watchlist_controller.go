@@ -0,0 +1,260 @@
+package controllers
+
+import (
+	"ares_api/internal/api/dto"
+	"ares_api/internal/common"
+	service "ares_api/internal/interfaces/service"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+type WatchlistController struct {
+	Service       service.WatchlistService
+	LedgerService service.LedgerService
+}
+
+func NewWatchlistController(s service.WatchlistService, l service.LedgerService) *WatchlistController {
+	return &WatchlistController{Service: s, LedgerService: l}
+}
+
+// watchlistID parses the :id path parameter, answering 400 when it isn't an id
+func watchlistID(ctx *gin.Context) (uint, bool) {
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 64)
+	if err != nil {
+		common.JSON(ctx, http.StatusBadRequest, gin.H{"error": "invalid watchlist id"})
+		return 0, false
+	}
+	return uint(id), true
+}
+
+// @Summary Create a watchlist
+// @Description With a default_alert (percent_move or change_24h), every coin added to the watchlist gets a copy of that alert.
+// @Tags Watchlists
+// @Accept json
+// @Produce json
+// @Param request body dto.CreateWatchlistRequest true "Watchlist"
+// @Success 201 {object} dto.WatchlistDTO
+// @Failure 400 {object} map[string]string
+// @Security BearerAuth
+// @Router /watchlists [post]
+func (c *WatchlistController) Create(ctx *gin.Context) {
+	var req dto.CreateWatchlistRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		common.JSON(ctx, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := ctx.GetUint("userID")
+
+	res, err := c.Service.Create(userID, req)
+	if err != nil {
+		common.JSON(ctx, watchlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	_ = c.LedgerService.Append(userID, "CreateWatchlist", "Created watchlist "+strconv.FormatUint(uint64(res.ID), 10)+": "+res.Name)
+	common.JSON(ctx, http.StatusCreated, res)
+}
+
+// @Summary List watchlists
+// @Tags Watchlists
+// @Produce json
+// @Success 200 {array} dto.WatchlistDTO
+// @Security BearerAuth
+// @Router /watchlists [get]
+func (c *WatchlistController) List(ctx *gin.Context) {
+	res, err := c.Service.List(ctx.GetUint("userID"))
+	if err != nil {
+		common.JSON(ctx, http.StatusInternalServerError, gin.H{"error": err.Error()})
+		return
+	}
+	common.JSON(ctx, http.StatusOK, res)
+}
+
+// @Summary Get a watchlist
+// @Tags Watchlists
+// @Produce json
+// @Param id path int true "Watchlist ID"
+// @Success 200 {object} dto.WatchlistDTO
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /watchlists/{id} [get]
+func (c *WatchlistController) Get(ctx *gin.Context) {
+	id, ok := watchlistID(ctx)
+	if !ok {
+		return
+	}
+	res, err := c.Service.Get(ctx.GetUint("userID"), id)
+	if err != nil {
+		common.JSON(ctx, watchlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	common.JSON(ctx, http.StatusOK, res)
+}
+
+// @Summary Delete a watchlist
+// @Description Alerts the watchlist's coins inherited are deleted with it.
+// @Tags Watchlists
+// @Produce json
+// @Param id path int true "Watchlist ID"
+// @Success 200 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /watchlists/{id} [delete]
+func (c *WatchlistController) Delete(ctx *gin.Context) {
+	id, ok := watchlistID(ctx)
+	if !ok {
+		return
+	}
+
+	userID := ctx.GetUint("userID")
+
+	if err := c.Service.Delete(userID, id); err != nil {
+		common.JSON(ctx, watchlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	_ = c.LedgerService.Append(userID, "DeleteWatchlist", "Deleted watchlist "+ctx.Param("id"))
+	common.JSON(ctx, http.StatusOK, gin.H{"message": "watchlist deleted"})
+}
+
+// @Summary Add a coin to a watchlist
+// @Description The coin is given by coin_id, symbol or both. It inherits the watchlist's default alert unless alert is false.
+// @Tags Watchlists
+// @Accept json
+// @Produce json
+// @Param id path int true "Watchlist ID"
+// @Param request body dto.AddWatchlistCoinRequest true "Coin"
+// @Success 200 {object} dto.WatchlistDTO
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Failure 409 {object} map[string]string
+// @Security BearerAuth
+// @Router /watchlists/{id}/coins [post]
+func (c *WatchlistController) AddCoin(ctx *gin.Context) {
+	id, ok := watchlistID(ctx)
+	if !ok {
+		return
+	}
+	var req dto.AddWatchlistCoinRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		common.JSON(ctx, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	userID := ctx.GetUint("userID")
+
+	res, err := c.Service.AddCoin(userID, id, req)
+	if err != nil {
+		common.JSON(ctx, watchlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	added := res.Coins[len(res.Coins)-1]
+	_ = c.LedgerService.Append(userID, "AddWatchlistCoin", "Added coin ID: "+added.CoinID+" to watchlist "+ctx.Param("id"))
+	common.JSON(ctx, http.StatusOK, res)
+}
+
+// @Summary Remove a coin from a watchlist
+// @Description The alert the coin inherited from the watchlist is deleted with it.
+// @Tags Watchlists
+// @Produce json
+// @Param id path int true "Watchlist ID"
+// @Param coin_id path string true "Coin ID"
+// @Success 200 {object} dto.WatchlistDTO
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /watchlists/{id}/coins/{coin_id} [delete]
+func (c *WatchlistController) RemoveCoin(ctx *gin.Context) {
+	id, ok := watchlistID(ctx)
+	if !ok {
+		return
+	}
+
+	userID := ctx.GetUint("userID")
+
+	res, err := c.Service.RemoveCoin(userID, id, ctx.Param("coin_id"))
+	if err != nil {
+		common.JSON(ctx, watchlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	_ = c.LedgerService.Append(userID, "RemoveWatchlistCoin", "Removed coin ID: "+ctx.Param("coin_id")+" from watchlist "+ctx.Param("id"))
+	common.JSON(ctx, http.StatusOK, res)
+}
+
+// @Summary Reorder a watchlist
+// @Description coin_ids must list every coin of the watchlist exactly once.
+// @Tags Watchlists
+// @Accept json
+// @Produce json
+// @Param id path int true "Watchlist ID"
+// @Param request body dto.ReorderWatchlistRequest true "New order"
+// @Success 200 {object} dto.WatchlistDTO
+// @Failure 400 {object} map[string]string
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /watchlists/{id}/order [put]
+func (c *WatchlistController) Reorder(ctx *gin.Context) {
+	id, ok := watchlistID(ctx)
+	if !ok {
+		return
+	}
+	var req dto.ReorderWatchlistRequest
+	if err := ctx.ShouldBindJSON(&req); err != nil {
+		common.JSON(ctx, http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	res, err := c.Service.Reorder(ctx.GetUint("userID"), id, req)
+	if err != nil {
+		common.JSON(ctx, watchlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	common.JSON(ctx, http.StatusOK, res)
+}
+
+// @Summary Get quotes for a watchlist
+// @Description Current quotes of every coin on the watchlist in its order, fetched in one batch.
+// @Tags Watchlists
+// @Produce json
+// @Param id path int true "Watchlist ID"
+// @Param vs_currency query string false "Currency to quote in (default: USD)"
+// @Success 200 {object} dto.WatchlistQuotesDTO
+// @Failure 404 {object} map[string]string
+// @Security BearerAuth
+// @Router /watchlists/{id}/quotes [get]
+func (c *WatchlistController) Quotes(ctx *gin.Context) {
+	id, ok := watchlistID(ctx)
+	if !ok {
+		return
+	}
+	res, err := c.Service.Quotes(ctx.GetUint("userID"), id, ctx.DefaultQuery("vs_currency", "usd"))
+	if err != nil {
+		setRetryAfter(ctx, err)
+		common.JSON(ctx, watchlistErrorStatus(err), gin.H{"error": err.Error()})
+		return
+	}
+	common.JSON(ctx, http.StatusOK, res)
+}
+
+// watchlistErrorStatus maps watchlist, coin, alert and market data errors to HTTP status codes
+func watchlistErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrWatchlistNotFound),
+		errors.Is(err, service.ErrCoinNotOnWatchlist):
+		return http.StatusNotFound
+	case errors.Is(err, service.ErrCoinOnWatchlist):
+		return http.StatusConflict
+	case errors.Is(err, service.ErrInvalidWatchlist),
+		errors.Is(err, service.ErrInvalidAlert),
+		errors.Is(err, service.ErrInvalidCoinQuery),
+		errors.Is(err, service.ErrUnknownCoin),
+		errors.Is(err, service.ErrSymbolMismatch),
+		errors.Is(err, service.ErrAmbiguousSymbol):
+		return http.StatusBadRequest
+	}
+	if status, ok := marketDataErrorStatus(err); ok {
+		return status
+	}
+	return http.StatusInternalServerError
+}
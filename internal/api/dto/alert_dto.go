@@ -0,0 +1,55 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CreateAlertRequest defines an alert. threshold is a USD price for price_above and
+// price_below, and a percentage for percent_move and change_24h, where a negative
+// value watches for a fall.
+type CreateAlertRequest struct {
+	CoinID     string          `json:"coin_id" binding:"required"`
+	Condition  string          `json:"condition" binding:"required"` // price_above, price_below, percent_move or change_24h
+	Threshold  decimal.Decimal `json:"threshold" binding:"required"`
+	Window     string          `json:"window"`   // percent_move: Go duration from 1m to 24h, e.g. "1h"
+	Mode       string          `json:"mode"`     // once (default) or recurring
+	Cooldown   string          `json:"cooldown"` // recurring: least time between firings, e.g. "30m"
+	Note       string          `json:"note"`
+	WebhookURL string          `json:"webhook_url"` // optional http(s) endpoint to POST fired alerts to
+}
+
+type AlertDTO struct {
+	ID              uint            `json:"id"`
+	CoinID          string          `json:"coin_id"`
+	Condition       string          `json:"condition"`
+	Threshold       decimal.Decimal `json:"threshold"`
+	WindowMinutes   int             `json:"window_minutes,omitempty"`
+	Mode            string          `json:"mode"`
+	CooldownMinutes int             `json:"cooldown_minutes,omitempty"`
+	Status          string          `json:"status"`
+	Armed           bool            `json:"armed"`
+	TriggerCount    int             `json:"trigger_count"`
+	LastTriggeredAt *time.Time      `json:"last_triggered_at,omitempty"`
+	Note            string          `json:"note,omitempty"`
+	WebhookURL      string          `json:"webhook_url,omitempty"`
+	// WebhookSecret is only returned when the alert is created. Each webhook carries
+	// an X-Ares-Signature header of the form t=<unix seconds>,v1=<hex HMAC-SHA256
+	// of "<t>.<body>" keyed with this secret>.
+	WebhookSecret string    `json:"webhook_secret,omitempty"`
+	CreatedAt     time.Time `json:"created_at"`
+}
+
+// AlertEventDTO is a fired alert as sent over the stream and to webhooks. Value is
+// the observed price, or the observed percentage for percent_move and change_24h.
+type AlertEventDTO struct {
+	AlertID     uint            `json:"alert_id"`
+	CoinID      string          `json:"coin_id"`
+	Condition   string          `json:"condition"`
+	Threshold   decimal.Decimal `json:"threshold"`
+	Value       decimal.Decimal `json:"value"`
+	Price       decimal.Decimal `json:"price"`
+	Note        string          `json:"note,omitempty"`
+	TriggeredAt time.Time       `json:"triggered_at"`
+}
@@ -11,6 +11,16 @@ type CoinDTO struct {
 	Name   string `json:"name"`
 }
 
+// CoinSearchResultDTO is a catalog coin matching a search, with how it matched:
+// symbol, name, symbol_prefix, name_prefix, word_prefix, substring or fuzzy
+type CoinSearchResultDTO struct {
+	ID            string `json:"id"`
+	Symbol        string `json:"symbol"`
+	Name          string `json:"name"`
+	MarketCapRank int    `json:"market_cap_rank"` // 0 below the ranked top coins
+	Match         string `json:"match"`
+}
+
 // ==========================
 // Coin Market Details DTO
 // ==========================
@@ -20,7 +30,10 @@ type CoinMarketDTO struct {
 	Name        string    `json:"name"`
 	PriceUSD    float64   `json:"price_usd"`
 	MarketCap   float64   `json:"market_cap"`
+	Volume24h   float64   `json:"volume_24h"` // zero when the source has none
+	Change1h    float64   `json:"change_1h"`
 	Change24h   float64   `json:"change_24h"`
+	Change7d    float64   `json:"change_7d"`
 	LastUpdated time.Time `json:"last_updated"`
 }
 
@@ -33,10 +46,51 @@ type TopMoverDTO struct {
 	Name        string    `json:"name"`
 	PriceUSD    float64   `json:"price_usd"`
 	MarketCap   float64   `json:"market_cap"`
+	Volume24h   float64   `json:"volume_24h"`
+	Change      float64   `json:"change"` // over the window ranked on
+	Change1h    float64   `json:"change_1h"`
 	Change24h   float64   `json:"change_24h"`
+	Change7d    float64   `json:"change_7d"`
 	LastUpdated time.Time `json:"last_updated"`
 }
 
+// TopMoversQuery ranks movers over Window, leaving out coins that traded less than
+// MinVolume USD in 24 hours
+type TopMoversQuery struct {
+	Window    string  `form:"window"` // 1h, 24h (default) or 7d
+	Limit     int     `form:"limit"`  // per side; default 10
+	MinVolume float64 `form:"min_volume"`
+}
+
+// TopMoversDTO lists the biggest gainers and losers over Window, biggest move first
+type TopMoversDTO struct {
+	Window  string        `json:"window"`
+	Gainers []TopMoverDTO `json:"gainers"`
+	Losers  []TopMoverDTO `json:"losers"`
+}
+
+// ==========================
+// Screener DTO
+// ==========================
+
+// ScreenerFilter selects coins of the market universe. Every bound is optional and
+// inclusive; prices, market caps and volumes are in USD, changes in percent over
+// ChangeWindow.
+type ScreenerFilter struct {
+	MinPrice     *float64 `form:"min_price"`
+	MaxPrice     *float64 `form:"max_price"`
+	MinMarketCap *float64 `form:"min_market_cap"`
+	MaxMarketCap *float64 `form:"max_market_cap"`
+	MinChange    *float64 `form:"min_change"`
+	MaxChange    *float64 `form:"max_change"`
+	MinVolume    *float64 `form:"min_volume"`
+	MaxVolume    *float64 `form:"max_volume"`
+	ChangeWindow string   `form:"change_window"` // 1h, 24h (default) or 7d
+	Sort         string   `form:"sort"`          // market_cap (default), price, change or volume
+	Order        string   `form:"order"`         // desc (default) or asc
+	Limit        int      `form:"limit"`         // default 50
+}
+
 //===========================
 // Vs currency DTO
 //===========================
@@ -44,3 +98,32 @@ type TopMoverDTO struct {
 type VsCurrencyDTO struct {
 	Currency string `json:"currency"`
 }
+
+//===========================
+// Price history DTOs
+//===========================
+
+// PricePointDTO is one sample of a coin's price history
+type PricePointDTO struct {
+	Time   time.Time `json:"time"`
+	Price  float64   `json:"price"`
+	Volume float64   `json:"volume"` // rolling 24h volume at Time; zero when the source has none
+}
+
+// CandleDTO is one OHLCV bar starting at OpenTime
+type CandleDTO struct {
+	OpenTime time.Time `json:"open_time"`
+	Open     float64   `json:"open"`
+	High     float64   `json:"high"`
+	Low      float64   `json:"low"`
+	Close    float64   `json:"close"`
+	Volume   float64   `json:"volume"`
+}
+
+type CandlesDTO struct {
+	CoinID   string      `json:"coin_id"`
+	Interval string      `json:"interval"`
+	From     time.Time   `json:"from"`
+	To       time.Time   `json:"to"`
+	Candles  []CandleDTO `json:"candles"`
+}
@@ -0,0 +1,78 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// BacktestStrategyDTO selects a strategy and its parameters:
+//
+//	buy_and_hold  splits the starting balance evenly across the coins on the first candle
+//	dca           buys amount USD of each coin every `every` candles until cash runs out
+//	sma_cross     buys a coin with an even share of the starting balance when its fast SMA
+//	              crosses above the slow one, and sells the position when it crosses back
+type BacktestStrategyDTO struct {
+	Type       string          `json:"type" binding:"required"`
+	FastPeriod int             `json:"fast_period,omitempty"`
+	SlowPeriod int             `json:"slow_period,omitempty"`
+	Amount     decimal.Decimal `json:"amount,omitempty"`
+	Every      int             `json:"every,omitempty"`
+}
+
+type BacktestRequest struct {
+	Strategy        BacktestStrategyDTO `json:"strategy" binding:"required"`
+	CoinIDs         []string            `json:"coin_ids" binding:"required"`
+	Interval        string              `json:"interval"` // 1m, 1h or 1d; defaults to 1d
+	From            time.Time           `json:"from" binding:"required"`
+	To              time.Time           `json:"to" binding:"required"`
+	StartingBalance decimal.Decimal     `json:"starting_balance"` // USD; defaults to 10000
+	FeeSchedule     string              `json:"fee_schedule"`     // defaults to the account's schedule
+}
+
+type BacktestTradeDTO struct {
+	ExecutedAt  time.Time       `json:"executed_at"`
+	CoinID      string          `json:"coin_id"`
+	Side        string          `json:"side"`
+	Quantity    decimal.Decimal `json:"quantity"`
+	Price       decimal.Decimal `json:"price"`
+	Fee         decimal.Decimal `json:"fee"`
+	RealizedPnL decimal.Decimal `json:"realized_pnl"`
+}
+
+// BacktestSummaryDTO holds a completed run's statistics. Returns and drawdown are fractions;
+// volatility, Sharpe and Sortino are annualised from the candle interval.
+type BacktestSummaryDTO struct {
+	StartingBalance decimal.Decimal `json:"starting_balance"`
+	EndValue        decimal.Decimal `json:"end_value"`
+	RealizedPnL     decimal.Decimal `json:"realized_pnl"`
+	FeesPaid        decimal.Decimal `json:"fees_paid"`
+	TradeCount      int             `json:"trade_count"`
+	WinRate         float64         `json:"win_rate"` // % of round trips that closed with a gain after fees
+	TotalReturn     float64         `json:"total_return"`
+	MaxDrawdown     float64         `json:"max_drawdown"`
+	Volatility      float64         `json:"volatility"`
+	Sharpe          float64         `json:"sharpe"`
+	Sortino         float64         `json:"sortino"`
+}
+
+// BacktestRunDTO is a backtest job. Summary is set once it completes; trades and
+// equity are only included when a single run is fetched.
+type BacktestRunDTO struct {
+	ID              uint                `json:"id"`
+	Status          string              `json:"status"`
+	Error           string              `json:"error,omitempty"`
+	Strategy        BacktestStrategyDTO `json:"strategy"`
+	CoinIDs         []string            `json:"coin_ids"`
+	Interval        string              `json:"interval"`
+	From            time.Time           `json:"from"`
+	To              time.Time           `json:"to"`
+	StartingBalance decimal.Decimal     `json:"starting_balance"`
+	FeeSchedule     string              `json:"fee_schedule"`
+	CreatedAt       time.Time           `json:"created_at"`
+	StartedAt       *time.Time          `json:"started_at,omitempty"`
+	FinishedAt      *time.Time          `json:"finished_at,omitempty"`
+	Summary         *BacktestSummaryDTO `json:"summary,omitempty"`
+	Trades          []BacktestTradeDTO  `json:"trades,omitempty"`
+	Equity          []EquityPointDTO    `json:"equity,omitempty"`
+}
@@ -1,7 +1,56 @@
 package dto
 
+import "github.com/shopspring/decimal"
+
 type BalanceDTO struct {
-	UserID uint    `json:"user_id"`
-	Asset  string  `json:"asset"`  // Always USD
-	Amount float64 `json:"amount"`
+	UserID      uint            `json:"user_id"`
+	PortfolioID uint            `json:"portfolio_id"`
+	Asset       string          `json:"asset"` // quote currency: USD (default), EUR, GBP or BTC
+	Amount      decimal.Decimal `json:"amount"`
+	Reserved    decimal.Decimal `json:"reserved"` // held by open buy limit orders
+}
+
+// ConvertRequest exchanges Amount of From for To at the current rate
+type ConvertRequest struct {
+	From   string          `json:"from" binding:"required" example:"USD"`
+	To     string          `json:"to" binding:"required" example:"EUR"`
+	Amount decimal.Decimal `json:"amount" binding:"required"` // in From
+}
+
+type ConversionDTO struct {
+	From     string          `json:"from"`
+	To       string          `json:"to"`
+	Amount   decimal.Decimal `json:"amount"`   // debited from From
+	Received decimal.Decimal `json:"received"` // credited to To
+	Rate     decimal.Decimal `json:"rate"`     // units of To per unit of From
+	Balances []BalanceDTO    `json:"balances"` // both balances after the conversion
+}
+
+// CashDTO is one cash balance of a portfolio
+type CashDTO struct {
+	Asset    string          `json:"asset"`
+	Amount   decimal.Decimal `json:"amount"`
+	Reserved decimal.Decimal `json:"reserved"`
+	Value    decimal.Decimal `json:"value"` // amount in the display currency
+}
+
+type HoldingDTO struct {
+	CoinID   string          `json:"coin_id"`
+	Symbol   string          `json:"symbol"`
+	Quantity decimal.Decimal `json:"quantity"`
+	Reserved decimal.Decimal `json:"reserved"` // held by open sell limit orders
+	Price    decimal.Decimal `json:"price"`    // current price in the display currency
+	Value    decimal.Decimal `json:"value"`    // quantity * price
+}
+
+type PortfolioDTO struct {
+	UserID        uint            `json:"user_id"`
+	PortfolioID   uint            `json:"portfolio_id"`
+	Name          string          `json:"name"`
+	Currency      string          `json:"currency"` // display currency every value and total is in
+	Cash          decimal.Decimal `json:"cash"`     // all cash balances together
+	Balances      []CashDTO       `json:"balances"`
+	Holdings      []HoldingDTO    `json:"holdings"`
+	HoldingsValue decimal.Decimal `json:"holdings_value"`
+	TotalValue    decimal.Decimal `json:"total_value"`
 }
@@ -0,0 +1,64 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CreateBotRequest defines a bot. Which fields apply depends on the type:
+//
+//	dca   schedule (cron, UTC), e.g. "0 9 * * 1" for Mondays at 09:00
+//	grid  grid_lower, grid_upper and grid_levels (2-100)
+//	dip   dip_percent, e.g. 5 to buy when the 24h change is -5% or worse, and cooldown (default 24h)
+type CreateBotRequest struct {
+	Name        string          `json:"name"`
+	Type        string          `json:"type" binding:"required"`
+	CoinID      string          `json:"coin_id" binding:"required"`
+	Symbol      string          `json:"symbol" binding:"required"`
+	Amount      decimal.Decimal `json:"amount" binding:"required"` // USD per buy, fee included
+	Budget      decimal.Decimal `json:"budget" binding:"required"` // most USD the bot may have deployed at once
+	Schedule    string          `json:"schedule"`
+	GridLower   decimal.Decimal `json:"grid_lower"`
+	GridUpper   decimal.Decimal `json:"grid_upper"`
+	GridLevels  int             `json:"grid_levels"`
+	DipPercent  decimal.Decimal `json:"dip_percent"`
+	Cooldown    string          `json:"cooldown"`     // Go duration, e.g. "12h"
+	PortfolioID uint            `json:"portfolio_id"` // portfolio the bot trades in; 0 for the default one
+}
+
+type BotDTO struct {
+	ID              uint            `json:"id"`
+	PortfolioID     uint            `json:"portfolio_id"`
+	Name            string          `json:"name"`
+	Type            string          `json:"type"`
+	Status          string          `json:"status"`
+	CoinID          string          `json:"coin_id"`
+	Symbol          string          `json:"symbol"`
+	Amount          decimal.Decimal `json:"amount"`
+	Budget          decimal.Decimal `json:"budget"`
+	Deployed        decimal.Decimal `json:"deployed"` // USD in the coin, buys net of sales
+	Position        decimal.Decimal `json:"position"` // coins bought by the bot and not yet sold
+	Schedule        string          `json:"schedule,omitempty"`
+	NextRunAt       *time.Time      `json:"next_run_at,omitempty"`
+	GridLower       decimal.Decimal `json:"grid_lower,omitempty"`
+	GridUpper       decimal.Decimal `json:"grid_upper,omitempty"`
+	GridLevels      int             `json:"grid_levels,omitempty"`
+	GridLots        int             `json:"grid_lots,omitempty"`
+	DipPercent      decimal.Decimal `json:"dip_percent,omitempty"`
+	CooldownMinutes int             `json:"cooldown_minutes,omitempty"`
+	LastTradeAt     *time.Time      `json:"last_trade_at,omitempty"`
+	LastError       string          `json:"last_error,omitempty"`
+	CreatedAt       time.Time       `json:"created_at"`
+}
+
+type BotTradeDTO struct {
+	ID        uint            `json:"id"`
+	TradeID   uint            `json:"trade_id"`
+	Side      string          `json:"side"`
+	Quantity  decimal.Decimal `json:"quantity"`
+	Price     decimal.Decimal `json:"price"`
+	Fee       decimal.Decimal `json:"fee"`
+	Reason    string          `json:"reason"`
+	CreatedAt time.Time       `json:"created_at"`
+}
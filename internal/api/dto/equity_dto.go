@@ -0,0 +1,30 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+type EquityPointDTO struct {
+	TakenAt       time.Time       `json:"taken_at"`
+	Cash          decimal.Decimal `json:"cash"`
+	HoldingsValue decimal.Decimal `json:"holdings_value"`
+	TotalValue    decimal.Decimal `json:"total_value"`
+}
+
+// EquityCurveDTO is the portfolio value series over a window with risk metrics.
+// Percentages are fractions (0.05 = 5%); volatility, Sharpe and Sortino are
+// annualised from the snapshot spacing with a zero risk-free rate.
+type EquityCurveDTO struct {
+	PortfolioID uint             `json:"portfolio_id"`
+	Window      string           `json:"window"`
+	Points      []EquityPointDTO `json:"points"`
+	StartValue  decimal.Decimal  `json:"start_value"`
+	EndValue    decimal.Decimal  `json:"end_value"`
+	TotalReturn float64          `json:"total_return"`
+	MaxDrawdown float64          `json:"max_drawdown"`
+	Volatility  float64          `json:"volatility"`
+	Sharpe      float64          `json:"sharpe"`
+	Sortino     float64          `json:"sortino"`
+}
@@ -0,0 +1,38 @@
+package dto
+
+import "github.com/shopspring/decimal"
+
+// CoinPerformanceDTO is the P&L breakdown for a single coin
+type CoinPerformanceDTO struct {
+	CoinID        string          `json:"coin_id"`
+	Symbol        string          `json:"symbol"`
+	Trades        int             `json:"trades"`
+	Quantity      decimal.Decimal `json:"quantity"`     // open quantity
+	AverageCost   decimal.Decimal `json:"average_cost"` // cost per unit of the open quantity
+	CostBasis     decimal.Decimal `json:"cost_basis"`   // total cost of the open quantity
+	CurrentPrice  decimal.Decimal `json:"current_price"`
+	MarketValue   decimal.Decimal `json:"market_value"`
+	RealizedPnL   decimal.Decimal `json:"realized_pnl"`
+	UnrealizedPnL decimal.Decimal `json:"unrealized_pnl"`
+	Fees          decimal.Decimal `json:"fees"`
+	NetPnL        decimal.Decimal `json:"net_pnl"` // realized + unrealized - fees
+	RoundTrips    int             `json:"round_trips"`
+	Wins          int             `json:"wins"`
+	Losses        int             `json:"losses"`
+}
+
+// PerformanceDTO is the account-wide P&L summary with a per-coin breakdown
+type PerformanceDTO struct {
+	PortfolioID     uint                 `json:"portfolio_id"`
+	CostBasisMethod string               `json:"cost_basis_method"` // fifo, lifo, hifo or average
+	TotalTrades     int                  `json:"total_trades"`
+	RealizedPnL     decimal.Decimal      `json:"realized_pnl"`
+	UnrealizedPnL   decimal.Decimal      `json:"unrealized_pnl"`
+	Fees            decimal.Decimal      `json:"fees"`
+	NetPnL          decimal.Decimal      `json:"net_pnl"`
+	RoundTrips      int                  `json:"round_trips"` // positions opened from flat and closed back to flat
+	Wins            int                  `json:"wins"`
+	Losses          int                  `json:"losses"`
+	WinRate         float64              `json:"win_rate"` // percentage of round trips closed in profit
+	Coins           []CoinPerformanceDTO `json:"coins"`
+}
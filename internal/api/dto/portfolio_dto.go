@@ -0,0 +1,22 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// CreatePortfolioRequest opens a new paper portfolio. It starts with starting_balance
+// USD, or the default 10k when omitted.
+type CreatePortfolioRequest struct {
+	Name            string           `json:"name" binding:"required"`
+	StartingBalance *decimal.Decimal `json:"starting_balance"`
+}
+
+type PortfolioSummaryDTO struct {
+	ID         uint       `json:"id"`
+	Name       string     `json:"name"`
+	IsDefault  bool       `json:"is_default"`
+	ArchivedAt *time.Time `json:"archived_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
@@ -0,0 +1,78 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// TradeExportFilter narrows a trade export. From and To take RFC 3339 instants or
+// YYYY-MM-DD dates (UTC midnight); To is exclusive.
+type TradeExportFilter struct {
+	Kind   string `form:"kind"` // orders (default) or fills
+	CoinID string `form:"coin_id"`
+	Side   string `form:"side"`
+	From   string `form:"from"`
+	To     string `form:"to"`
+}
+
+// TradeExportDTO is one exported order or fill. Fill rows carry their order's id and
+// the liquidity role but no type or status; order rows carry no liquidity or exchange
+// rate, and their USD figures sum up their fills.
+type TradeExportDTO struct {
+	ID             uint            `json:"id"`
+	OrderID        uint            `json:"order_id"`
+	CreatedAt      time.Time       `json:"created_at"`
+	PortfolioID    uint            `json:"portfolio_id"`
+	CoinID         string          `json:"coin_id"`
+	Symbol         string          `json:"symbol"`
+	Side           string          `json:"side"`
+	Type           string          `json:"type,omitempty"`
+	Status         string          `json:"status,omitempty"`
+	Liquidity      string          `json:"liquidity,omitempty"`
+	Quantity       decimal.Decimal `json:"quantity"`
+	FilledQuantity decimal.Decimal `json:"filled_quantity"`
+	Price          decimal.Decimal `json:"price"`
+	AveragePrice   decimal.Decimal `json:"average_price"` // of the fills
+	Fee            decimal.Decimal `json:"fee"`
+	QuoteCurrency  string          `json:"quote_currency"`
+	FXRate         decimal.Decimal `json:"fx_rate"`   // quote currency units per USD at execution
+	PriceUSD       decimal.Decimal `json:"price_usd"` // average fill price in USD
+	FeeUSD         decimal.Decimal `json:"fee_usd"`
+}
+
+// TaxDisposalDTO is the part of one sell matched against one acquisition lot
+type TaxDisposalDTO struct {
+	CoinID             string          `json:"coin_id"`
+	Symbol             string          `json:"symbol"`
+	Quantity           decimal.Decimal `json:"quantity"`
+	AcquiredAt         time.Time       `json:"acquired_at"`
+	DisposedAt         time.Time       `json:"disposed_at"`
+	AcquisitionTradeID uint            `json:"acquisition_trade_id"`
+	DisposalTradeID    uint            `json:"disposal_trade_id"`
+	Proceeds           decimal.Decimal `json:"proceeds"`   // net of the sell fee
+	CostBasis          decimal.Decimal `json:"cost_basis"` // including the buy fee
+	Gain               decimal.Decimal `json:"gain"`       // negative for a loss
+	Term               string          `json:"term"`       // short or long
+}
+
+// TaxYearDTO sums the realized gains of one calendar year (UTC)
+type TaxYearDTO struct {
+	Year          int             `json:"year"`
+	Disposals     int             `json:"disposals"`
+	Proceeds      decimal.Decimal `json:"proceeds"`
+	CostBasis     decimal.Decimal `json:"cost_basis"`
+	ShortTermGain decimal.Decimal `json:"short_term_gain"`
+	LongTermGain  decimal.Decimal `json:"long_term_gain"`
+	TotalGain     decimal.Decimal `json:"total_gain"`
+}
+
+// TaxReportDTO pairs every disposal with acquisitions under one lot method. Amounts
+// are in USD; lots held more than a year are long term.
+type TaxReportDTO struct {
+	PortfolioID uint             `json:"portfolio_id"`
+	Method      string           `json:"method"` // fifo, lifo or hifo
+	Currency    string           `json:"currency"`
+	Years       []TaxYearDTO     `json:"years"`
+	Disposals   []TaxDisposalDTO `json:"disposals"`
+}
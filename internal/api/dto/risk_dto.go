@@ -0,0 +1,32 @@
+package dto
+
+import "github.com/shopspring/decimal"
+
+// RiskLimitsRequest replaces a portfolio's risk limits. Money limits are in USD and a
+// limit left at 0 is switched off.
+type RiskLimitsRequest struct {
+	MaxPositionValue decimal.Decimal `json:"max_position_value"`
+	MaxOrderNotional decimal.Decimal `json:"max_order_notional"`
+	MaxDailyLoss     decimal.Decimal `json:"max_daily_loss"`
+	MaxOpenOrders    int             `json:"max_open_orders"`
+	MaxConcentration decimal.Decimal `json:"max_concentration"`     // percent of portfolio value, e.g. 25
+	Mode             string          `json:"mode" example:"reject"` // reject (default) or warn
+}
+
+type RiskLimitsDTO struct {
+	PortfolioID      uint            `json:"portfolio_id"`
+	MaxPositionValue decimal.Decimal `json:"max_position_value"`
+	MaxOrderNotional decimal.Decimal `json:"max_order_notional"`
+	MaxDailyLoss     decimal.Decimal `json:"max_daily_loss"`
+	MaxOpenOrders    int             `json:"max_open_orders"`
+	MaxConcentration decimal.Decimal `json:"max_concentration"`
+	Mode             string          `json:"mode"`
+}
+
+// RiskViolationDTO is one limit an order breaks
+type RiskViolationDTO struct {
+	Rule    string          `json:"rule"`   // the limit's field name, e.g. max_order_notional
+	Limit   decimal.Decimal `json:"limit"`  // configured limit
+	Actual  decimal.Decimal `json:"actual"` // value the order would reach
+	Message string          `json:"message"`
+}
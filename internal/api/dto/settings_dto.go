@@ -1,5 +1,7 @@
 package dto
 
+import "github.com/shopspring/decimal"
+
 // Save API Key
 type APIKeyRequest struct {
 	APIKey string `json:"api_key" binding:"required" example:"your-secret-api-key"`
@@ -8,5 +10,32 @@ type APIKeyResponse struct {
 	Message string `json:"message" example:"API key saved successfully"`
 }
 
+// Fee schedule selection
+type FeeScheduleRequest struct {
+	FeeSchedule string `json:"fee_schedule" binding:"required" example:"standard"`
+}
+
+type FeeScheduleDTO struct {
+	Name       string          `json:"name"`
+	MakerRate  decimal.Decimal `json:"maker_rate"`  // fraction of notional
+	TakerRate  decimal.Decimal `json:"taker_rate"`  // fraction of notional
+	FixedFee   decimal.Decimal `json:"fixed_fee"`   // USD per fill
+	SpreadRate decimal.Decimal `json:"spread_rate"` // slippage on every taker fill
+	ImpactRate decimal.Decimal `json:"impact_rate"` // slippage per unit of notional / depth
+	DepthUSD   decimal.Decimal `json:"depth_usd"`
+}
+
+type FeeScheduleResponse struct {
+	Current   string           `json:"current"`
+	Available []FeeScheduleDTO `json:"available"`
+}
 
+// Display currency selection
+type DisplayCurrencyRequest struct {
+	Currency string `json:"currency" binding:"required" example:"EUR"`
+}
 
+type DisplayCurrencyResponse struct {
+	Current   string   `json:"current"`
+	Available []string `json:"available"`
+}
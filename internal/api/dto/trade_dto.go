@@ -1,34 +1,160 @@
 package dto
 
-type MarketOrderRequest struct {
-	CoinID   string  `json:"coin_id" binding:"required"`
-	Currency string  `json:"currency" binding:"required"`
-	Symbol   string  `json:"symbol" binding:"required"`
-	Side     string  `json:"side" binding:"required"`
-	Quantity float64 `json:"quantity" binding:"required"`
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
 
+// MarketOrderRequest names its coin by coin_id, symbol or both; the server resolves
+// them against the coin catalog
+type MarketOrderRequest struct {
+	CoinID   string          `json:"coin_id"`
+	Currency string          `json:"currency" binding:"required"` // quote currency: USD, EUR, GBP or BTC
+	Symbol   string          `json:"symbol"`
+	Side     string          `json:"side" binding:"required"`
+	Quantity decimal.Decimal `json:"quantity" binding:"required"`
 }
 
 type LimitOrderRequest struct {
-    CoinID    string  `json:"coin_id"`
-    Symbol    string  `json:"symbol"`
-    Side      string  `json:"side"`
-    Quantity  float64 `json:"quantity"`
-    LimitPrice float64 `json:"limit_price"`
-    Currency  string  `json:"currency"`
+	CoinID      string          `json:"coin_id"`
+	Symbol      string          `json:"symbol"`
+	Side        string          `json:"side"`
+	Quantity    decimal.Decimal `json:"quantity"`
+	LimitPrice  decimal.Decimal `json:"limit_price"`
+	Currency    string          `json:"currency"`      // quote currency: USD (default), EUR, GBP or BTC
+	TimeInForce string          `json:"time_in_force"` // GTC (default), IOC, FOK or GTD
+	ExpiresAt   *time.Time      `json:"expires_at"`    // required for GTD
+}
+
+// ConditionalOrderRequest places a stop_market, stop_limit, take_profit or trailing_stop order
+type ConditionalOrderRequest struct {
+	CoinID          string          `json:"coin_id"` // coin_id, symbol or both
+	Symbol          string          `json:"symbol"`
+	Side            string          `json:"side" binding:"required"`
+	Type            string          `json:"type" binding:"required"`
+	Quantity        decimal.Decimal `json:"quantity" binding:"required"`
+	StopPrice       decimal.Decimal `json:"stop_price"`       // trigger price; not used by trailing_stop
+	LimitPrice      decimal.Decimal `json:"limit_price"`      // stop_limit only
+	TrailingPercent decimal.Decimal `json:"trailing_percent"` // trailing_stop: distance as a percentage ...
+	TrailingOffset  decimal.Decimal `json:"trailing_offset"`  // ... or as an absolute amount of the quote currency
+	Currency        string          `json:"currency"`
+	TimeInForce     string          `json:"time_in_force"` // GTC (default) or GTD
+	ExpiresAt       *time.Time      `json:"expires_at"`
+}
+
+// OCOOrderRequest places a take-profit and a stop leg where filling one cancels the other
+type OCOOrderRequest struct {
+	CoinID          string          `json:"coin_id"` // coin_id, symbol or both
+	Symbol          string          `json:"symbol"`
+	Side            string          `json:"side" binding:"required"`
+	Quantity        decimal.Decimal `json:"quantity" binding:"required"`
+	TakeProfitPrice decimal.Decimal `json:"take_profit_price" binding:"required"`
+	StopPrice       decimal.Decimal `json:"stop_price" binding:"required"`
+	StopLimitPrice  decimal.Decimal `json:"stop_limit_price"` // makes the stop leg a stop_limit
+	Currency        string          `json:"currency"`
+	TimeInForce     string          `json:"time_in_force"`
+	ExpiresAt       *time.Time      `json:"expires_at"`
 }
 
+// AmendOrderRequest changes an open order; omitted fields are left as they are
+type AmendOrderRequest struct {
+	LimitPrice *decimal.Decimal `json:"limit_price"`
+	StopPrice  *decimal.Decimal `json:"stop_price"`
+	Quantity   *decimal.Decimal `json:"quantity"`
+	ExpiresAt  *time.Time       `json:"expires_at"`
+}
 
+// TradeResponse is an order together with the fills that executed it
 type TradeResponse struct {
-	ID       uint    `json:"id"`
-	UserID   uint    `json:"user_id"`
-	CoinID   string  `json:"coin_id"`
-	Symbol   string  `json:"symbol"`
-	Side     string  `json:"side"`
-	Quantity float64 `json:"quantity"`
-	Price    float64 `json:"price"`
-	Type     string  `json:"type"`
-	Status   string  `json:"status"`
-	CreatedAt string `json:"created_at"`
-	UpdatedAt string `json:"updated_at"`
+	ID              uint               `json:"id"`
+	UserID          uint               `json:"user_id"`
+	PortfolioID     uint               `json:"portfolio_id"`
+	CoinID          string             `json:"coin_id"`
+	Symbol          string             `json:"symbol"`
+	Side            string             `json:"side"`
+	Quantity        decimal.Decimal    `json:"quantity"`
+	Price           decimal.Decimal    `json:"price"`
+	AveragePrice    decimal.Decimal    `json:"average_price"`  // of the fills
+	Fee             decimal.Decimal    `json:"fee"`            // of all fills
+	QuoteCurrency   string             `json:"quote_currency"` // currency price, fee and reservation are in
+	Type            string             `json:"type"`
+	Status          string             `json:"status"`
+	TimeInForce     string             `json:"time_in_force"`
+	FilledQuantity  decimal.Decimal    `json:"filled_quantity"`
+	ExpiresAt       string             `json:"expires_at,omitempty"`
+	StopPrice       decimal.Decimal    `json:"stop_price,omitempty"`
+	TrailingPercent decimal.Decimal    `json:"trailing_percent,omitempty"`
+	TrailingOffset  decimal.Decimal    `json:"trailing_offset,omitempty"`
+	WaterMark       decimal.Decimal    `json:"water_mark,omitempty"`
+	TriggeredAt     string             `json:"triggered_at,omitempty"`
+	OCOGroupID      string             `json:"oco_group_id,omitempty"`
+	Fills           []FillDTO          `json:"fills"`
+	RiskWarnings    []RiskViolationDTO `json:"risk_warnings,omitempty"` // limits broken by the order in warn mode
+	CreatedAt       string             `json:"created_at"`
+	UpdatedAt       string             `json:"updated_at"`
+}
+
+// FillDTO is one execution of an order
+type FillDTO struct {
+	ID        uint            `json:"id"`
+	Quantity  decimal.Decimal `json:"quantity"`
+	Price     decimal.Decimal `json:"price"`
+	Fee       decimal.Decimal `json:"fee"`
+	Liquidity string          `json:"liquidity"` // maker or taker
+	FXRate    decimal.Decimal `json:"fx_rate"`   // quote currency units per USD at execution
+	CreatedAt string          `json:"created_at"`
+}
+
+// TradePreviewRequest asks what a market or limit order would do if it were placed
+// now. The other fields are those of MarketOrderRequest and LimitOrderRequest.
+type TradePreviewRequest struct {
+	Type        string          `json:"type" binding:"required"` // market or limit
+	CoinID      string          `json:"coin_id"`
+	Symbol      string          `json:"symbol"`
+	Side        string          `json:"side" binding:"required"`
+	Quantity    decimal.Decimal `json:"quantity" binding:"required"`
+	LimitPrice  decimal.Decimal `json:"limit_price"` // limit only
+	Currency    string          `json:"currency"`    // quote currency: USD (default), EUR, GBP or BTC
+	TimeInForce string          `json:"time_in_force"`
+	ExpiresAt   *time.Time      `json:"expires_at"`
+}
+
+// PreviewBalanceDTO is a balance before and after a previewed order
+type PreviewBalanceDTO struct {
+	Asset  string          `json:"asset"`
+	Before decimal.Decimal `json:"before"`
+	After  decimal.Decimal `json:"after"`
+}
+
+// TradePreviewDTO is what an order would do if it were placed now. Prices, fees and
+// cash are in the quote currency. An order that would be turned away is reported as
+// rejected with the reason, and the balances it would have left untouched.
+type TradePreviewDTO struct {
+	Type             string             `json:"type"`
+	CoinID           string             `json:"coin_id"`
+	Symbol           string             `json:"symbol"`
+	Side             string             `json:"side"`
+	Quantity         decimal.Decimal    `json:"quantity"`
+	QuoteCurrency    string             `json:"quote_currency"`
+	TimeInForce      string             `json:"time_in_force"`
+	Status           string             `json:"status"`               // the order's status right after placement
+	Rejected         bool               `json:"rejected"`             // the order would be turned away
+	Reason           string             `json:"reason,omitempty"`     // why it would be rejected
+	Violations       []RiskViolationDTO `json:"violations,omitempty"` // limits that would reject it in reject mode
+	MarketPrice      decimal.Decimal    `json:"market_price"`         // quote the order is priced against
+	FilledQuantity   decimal.Decimal    `json:"filled_quantity"`      // filled on placement
+	ExpectedPrice    decimal.Decimal    `json:"expected_price"`       // average price of the fills on placement
+	Fee              decimal.Decimal    `json:"fee"`                  // of the fills on placement
+	Slippage         decimal.Decimal    `json:"slippage"`             // fraction expected_price is off market_price
+	SlippageCost     decimal.Decimal    `json:"slippage_cost"`        // what the slippage costs across the fills
+	RestingQuantity  decimal.Decimal    `json:"resting_quantity"`     // left on the book at the limit price
+	RestingFee       decimal.Decimal    `json:"resting_fee"`          // maker fee when the resting quantity fills
+	ReservedAmount   decimal.Decimal    `json:"reserved_amount"`      // cash held back for a resting buy
+	ReservedQuantity decimal.Decimal    `json:"reserved_quantity"`    // coins held back for a resting sell
+	Cash             PreviewBalanceDTO  `json:"cash"`
+	CashAvailable    decimal.Decimal    `json:"cash_available"` // after the order, less every reservation
+	Holding          PreviewBalanceDTO  `json:"holding"`
+	HoldingAvailable decimal.Decimal    `json:"holding_available"`       // after the order, less every reservation
+	RiskWarnings     []RiskViolationDTO `json:"risk_warnings,omitempty"` // limits broken by the order in warn mode
 }
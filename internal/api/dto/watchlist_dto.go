@@ -0,0 +1,60 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// WatchlistAlertDTO is the alert every coin added to a watchlist inherits. Only
+// percent_move and change_24h can be defaults: one price threshold can't fit every coin.
+type WatchlistAlertDTO struct {
+	Condition string          `json:"condition" binding:"required"` // percent_move or change_24h
+	Threshold decimal.Decimal `json:"threshold" binding:"required"`
+	Window    string          `json:"window,omitempty"`   // percent_move: Go duration from 1m to 24h, e.g. "1h"
+	Mode      string          `json:"mode,omitempty"`     // once (default) or recurring
+	Cooldown  string          `json:"cooldown,omitempty"` // recurring: least time between firings, e.g. "30m"
+}
+
+type CreateWatchlistRequest struct {
+	Name         string             `json:"name" binding:"required"`
+	DefaultAlert *WatchlistAlertDTO `json:"default_alert"`
+}
+
+// AddWatchlistCoinRequest adds a coin by coin_id, symbol or both. The coin gets the
+// watchlist's default alert unless alert is false.
+type AddWatchlistCoinRequest struct {
+	CoinID string `json:"coin_id"`
+	Symbol string `json:"symbol"`
+	Alert  *bool  `json:"alert"`
+}
+
+// ReorderWatchlistRequest lists every coin of the watchlist in its new order
+type ReorderWatchlistRequest struct {
+	CoinIDs []string `json:"coin_ids" binding:"required"`
+}
+
+type WatchlistItemDTO struct {
+	CoinID   string    `json:"coin_id"`
+	Position int       `json:"position"`
+	AlertID  *uint     `json:"alert_id,omitempty"`
+	AddedAt  time.Time `json:"added_at"`
+}
+
+type WatchlistDTO struct {
+	ID           uint               `json:"id"`
+	Name         string             `json:"name"`
+	DefaultAlert *WatchlistAlertDTO `json:"default_alert,omitempty"`
+	Coins        []WatchlistItemDTO `json:"coins"`
+	CreatedAt    time.Time          `json:"created_at"`
+}
+
+// WatchlistQuotesDTO holds the current quote of every coin on a watchlist, in the
+// watchlist's order. Coins the market data source has no quote for are listed in missing.
+type WatchlistQuotesDTO struct {
+	WatchlistID uint            `json:"watchlist_id"`
+	Name        string          `json:"name"`
+	Currency    string          `json:"currency"`
+	Quotes      []CoinMarketDTO `json:"quotes"`
+	Missing     []string        `json:"missing,omitempty"`
+}
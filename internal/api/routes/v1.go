@@ -4,11 +4,14 @@ import (
 	controllers "ares_api/internal/api/controllers"
 	"ares_api/internal/middleware"
 	"ares_api/internal/ollama"
+	"ares_api/internal/prices"
 	repositories "ares_api/internal/repositories"
 	service "ares_api/internal/services"
 
 	"fmt"
+	"log"
 	"os"
+	"strconv"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -31,12 +34,53 @@ func RegisterRoutes(r *gin.Engine, db *gorm.DB) {
 	userService := service.NewUserService(userRepo)
 	userController := controllers.NewUserController(userService, ledgerService)
 
+	// --------------------------
+	//  ASSETS MODULE
+	// --------------------------
+	priceProvider, err := prices.NewProviderFromEnv()
+	if err != nil {
+		log.Fatal("Failed to configure price provider:", err)
+	}
+	quoteTTL, err := time.ParseDuration(os.Getenv("QUOTE_CACHE_TTL"))
+	if err != nil || quoteTTL <= 0 {
+		quoteTTL = 10 * time.Second // fallback
+	}
+	assetRepo := repositories.NewAssetRepository(priceProvider, quoteTTL)
+	coinRepo := repositories.NewCoinRepository(db)
+	assetService := service.NewAssetService(assetRepo, coinRepo)
+	candleRepo := repositories.NewCandleRepository(db)
+	candleService := service.NewCandleService(candleRepo, assetRepo)
+	assetContoller := controllers.NewAssetController(assetService , candleService, ledgerService)
+
+	// --------------------------
+	// SETTINGS MODULE
+	// --------------------------
+	settingsRepo := repositories.NewSettingsRepository(db)
+	settingsService := service.NewSettingsService(settingsRepo)
+	settingsController := controllers.NewSettingsController(settingsService, ledgerService)
+
+	// --------------------------
+	// IDEMPOTENCY MODULE
+	// --------------------------
+	idempotencyTTL, err := time.ParseDuration(os.Getenv("IDEMPOTENCY_KEY_TTL"))
+	if err != nil || idempotencyTTL <= 0 {
+		idempotencyTTL = 24 * time.Hour // fallback
+	}
+	idempotencyRepo := repositories.NewIdempotencyRepository(db)
+	idempotencyService := service.NewIdempotencyService(idempotencyRepo, idempotencyTTL)
+	idempotent := middleware.IdempotencyMiddleware(idempotencyService)
+
 	// --------------------------
 	// BALANCE MODULE
 	// --------------------------
+	txManager := repositories.NewTxManager(db)
 	balanceRepo := repositories.NewBalanceRepository(db)
-	balanceService := service.NewBalanceService(balanceRepo)
-	balanceController := controllers.NewBalanceController(balanceService , ledgerService)
+	holdingRepo := repositories.NewHoldingRepository(db)
+	portfolioRepo := repositories.NewPortfolioRepository(db)
+	balanceService := service.NewBalanceService(balanceRepo, holdingRepo, portfolioRepo, assetRepo, settingsRepo, txManager)
+	snapshotRepo := repositories.NewSnapshotRepository(db)
+	equityService := service.NewEquityService(snapshotRepo, portfolioRepo, balanceService)
+	balanceController := controllers.NewBalanceController(balanceService , equityService, ledgerService)
 
 	// --------------------------
 	// CHAT MODULE
@@ -46,25 +90,57 @@ func RegisterRoutes(r *gin.Engine, db *gorm.DB) {
 	chatService := service.NewChatService(chatRepo, ollamaService)
 	chatController := controllers.NewChatController(chatService, ledgerService)
 
-	// --------------------------
-	//  ASSETS MODULE
-	// --------------------------
-	assetRepo := repositories.NewAssetRepository()
-	assetService := service.NewAssetService(assetRepo)
-	assetContoller := controllers.NewAssetController(assetService , ledgerService)
 	// --------------------------
 	// TRADE MODULE
 	// --------------------------
 	tradeRepo := repositories.NewTradeRepository(db)
-	tradeService := service.NewTradeService(tradeRepo, balanceRepo, assetRepo)
-	tradeController := controllers.NewTradeController(tradeService, ledgerService)
+	riskRepo := repositories.NewRiskLimitRepository(db)
+	riskService := service.NewRiskService(riskRepo, portfolioRepo, tradeRepo, snapshotRepo, assetRepo, balanceService, ledgerService)
+	riskController := controllers.NewRiskController(riskService, ledgerService)
+	tradeService := service.NewTradeService(tradeRepo, balanceRepo, holdingRepo, portfolioRepo, assetRepo, settingsRepo, txManager, riskService, assetService)
+	performanceService := service.NewPerformanceService(tradeRepo, portfolioRepo, assetRepo)
+	tradeController := controllers.NewTradeController(tradeService, performanceService, ledgerService)
+	reportService := service.NewReportService(tradeRepo, portfolioRepo)
+	reportController := controllers.NewReportController(reportService)
 
 	// --------------------------
-	// SETTINGS MODULE
+	// PORTFOLIO MODULE
 	// --------------------------
-	settingsRepo := repositories.NewSettingsRepository(db)
-	settingsService := service.NewSettingsService(settingsRepo)
-	settingsController := controllers.NewSettingsController(settingsService, ledgerService)
+	portfolioService := service.NewPortfolioService(portfolioRepo, balanceRepo, tradeRepo, txManager)
+	portfolioController := controllers.NewPortfolioController(portfolioService, ledgerService)
+
+	// --------------------------
+	// ALERT MODULE
+	// --------------------------
+	alertRepo := repositories.NewAlertRepository(db)
+	alertService := service.NewAlertService(alertRepo, assetRepo, candleRepo, ledgerService)
+	alertController := controllers.NewAlertController(alertService, ledgerService)
+
+	// --------------------------
+	// WATCHLIST MODULE
+	// --------------------------
+	watchlistRepo := repositories.NewWatchlistRepository(db)
+	watchlistService := service.NewWatchlistService(watchlistRepo, assetRepo, txManager, assetService, alertService)
+	watchlistController := controllers.NewWatchlistController(watchlistService, ledgerService)
+
+	// --------------------------
+	// BOT MODULE
+	// --------------------------
+	botRepo := repositories.NewBotRepository(db)
+	botService := service.NewBotService(botRepo, tradeService, portfolioRepo, assetRepo, settingsRepo)
+	botController := controllers.NewBotController(botService, ledgerService)
+
+	// --------------------------
+	// BACKTEST MODULE
+	// --------------------------
+	backtestRepo := repositories.NewBacktestRepository(db)
+	backtestService := service.NewBacktestService(backtestRepo, candleService, settingsRepo)
+	backtestWorkers, err := strconv.Atoi(os.Getenv("BACKTEST_WORKERS"))
+	if err != nil || backtestWorkers <= 0 {
+		backtestWorkers = 2 // fallback
+	}
+	backtestService.Start(backtestWorkers)
+	backtestController := controllers.NewBacktestController(backtestService, ledgerService)
 
 	// --------------------------
 	// MEMORY MODULE
@@ -116,14 +192,116 @@ func RegisterRoutes(r *gin.Engine, db *gorm.DB) {
 	backupController := controllers.NewBackupController(db)
 
 	// --------------------------
-	//  BACKGROUND JOB TO PROCESS OPEN LIMIT ORDERS
+	//  BACKGROUND JOB TO PROCESS OPEN LIMIT AND CONDITIONAL ORDERS
 	// --------------------------
 	go func() {
 		ticker := time.NewTicker(10 * time.Second)
 		defer ticker.Stop()
 
 		for range ticker.C {
-			tradeService.ProcessOpenLimitOrders()
+			tradeService.ProcessOpenOrders()
+		}
+	}()
+
+	// --------------------------
+	//  BACKGROUND JOB TO EVALUATE PRICE ALERTS
+	// --------------------------
+	alertInterval, err := time.ParseDuration(os.Getenv("ALERT_TICK_INTERVAL"))
+	if err != nil || alertInterval <= 0 {
+		alertInterval = 15 * time.Second // fallback
+	}
+	go func() {
+		ticker := time.NewTicker(alertInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := alertService.Evaluate(); err != nil {
+				fmt.Printf("⚠️ Alert evaluation error: %v\n", err)
+			}
+		}
+	}()
+
+	// --------------------------
+	//  BACKGROUND JOB TO RUN TRADING BOTS
+	// --------------------------
+	botInterval, err := time.ParseDuration(os.Getenv("BOT_TICK_INTERVAL"))
+	if err != nil || botInterval <= 0 {
+		botInterval = 30 * time.Second // fallback
+	}
+	go func() {
+		ticker := time.NewTicker(botInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := botService.RunDue(); err != nil {
+				fmt.Printf("⚠️ Bot runner error: %v\n", err)
+			}
+		}
+	}()
+
+	// --------------------------
+	//  BACKGROUND JOB TO SNAPSHOT PORTFOLIO EQUITY
+	// --------------------------
+	snapshotInterval, err := time.ParseDuration(os.Getenv("EQUITY_SNAPSHOT_INTERVAL"))
+	if err != nil || snapshotInterval <= 0 {
+		snapshotInterval = time.Hour // fallback
+	}
+	go func() {
+		ticker := time.NewTicker(snapshotInterval)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := equityService.RecordSnapshots(); err != nil {
+				fmt.Printf("⚠️ Equity snapshot error: %v\n", err)
+			}
+		}
+	}()
+
+	// --------------------------
+	//  BACKGROUND JOB TO REFRESH THE COIN CATALOG
+	// --------------------------
+	catalogInterval, err := time.ParseDuration(os.Getenv("COIN_CATALOG_REFRESH_INTERVAL"))
+	if err != nil || catalogInterval <= 0 {
+		catalogInterval = 24 * time.Hour // fallback
+	}
+	go func() {
+		ticker := time.NewTicker(catalogInterval)
+		defer ticker.Stop()
+
+		// Refresh once at startup, then on every tick
+		for {
+			if _, err := assetService.RefreshCatalog(); err != nil {
+				fmt.Printf("⚠️ Coin catalog refresh error: %v\n", err)
+			}
+			<-ticker.C
+		}
+	}()
+
+	// --------------------------
+	//  BACKGROUND JOB TO PURGE EXPIRED IDEMPOTENCY KEYS
+	// --------------------------
+	go func() {
+		ticker := time.NewTicker(time.Hour)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := idempotencyService.PurgeExpired(); err != nil {
+				fmt.Printf("⚠️ Idempotency key purge error: %v\n", err)
+			}
+		}
+	}()
+
+	// --------------------------
+	//  BACKGROUND JOB TO SAMPLE QUOTES INTO CANDLES
+	// --------------------------
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+
+		for range ticker.C {
+			if _, err := candleService.RecordQuotes(); err != nil {
+				fmt.Printf("⚠️ Candle recording error: %v\n", err)
+			}
 		}
 	}()
 
@@ -191,11 +369,95 @@ func RegisterRoutes(r *gin.Engine, db *gorm.DB) {
 	trades := api.Group("/trades")
 	trades.Use(middleware.AuthMiddleware())
 	{
-		trades.POST("/market", tradeController.MarketOrder)
-		trades.POST("/limit", tradeController.LimitOrder)
+		trades.POST("/market", idempotent, tradeController.MarketOrder)
+		trades.POST("/limit", idempotent, tradeController.LimitOrder)
+		trades.POST("/preview", tradeController.PreviewOrder)
+		trades.POST("/conditional", tradeController.ConditionalOrder)
+		trades.POST("/oco", tradeController.OCOOrder)
 		trades.GET("/history", tradeController.GetHistory)
 		trades.GET("/pending", tradeController.GetPendingLimitOrders)
+		trades.DELETE("/orders/:id", tradeController.CancelOrder)
+		trades.PATCH("/orders/:id", tradeController.AmendOrder)
 		trades.GET("/performance", tradeController.GetPerformance)
+		trades.GET("/export", reportController.ExportTrades)
+		trades.GET("/tax-report", reportController.TaxReport)
+	}
+
+	// --------------------------
+	// Portfolio endpoints
+	// --------------------------
+	portfolios := api.Group("/portfolios")
+	portfolios.Use(middleware.AuthMiddleware())
+	{
+		portfolios.POST("", portfolioController.Create)
+		portfolios.GET("", portfolioController.List)
+		portfolios.POST("/:id/archive", portfolioController.Archive)
+	}
+
+	// --------------------------
+	// Risk endpoints
+	// --------------------------
+	risk := api.Group("/risk")
+	risk.Use(middleware.AuthMiddleware())
+	{
+		risk.GET("/limits", riskController.GetLimits)
+		risk.PUT("/limits", riskController.SetLimits)
+	}
+
+	// --------------------------
+	// Alert endpoints
+	// --------------------------
+	alerts := api.Group("/alerts")
+	alerts.GET("/stream", middleware.QueryTokenMiddleware(), middleware.AuthMiddleware(), alertController.Stream)
+	alertsAuth := alerts.Group("")
+	alertsAuth.Use(middleware.AuthMiddleware())
+	{
+		alertsAuth.POST("", alertController.Create)
+		alertsAuth.GET("", alertController.List)
+		alertsAuth.DELETE("/:id", alertController.Delete)
+	}
+
+	// --------------------------
+	// Watchlist endpoints
+	// --------------------------
+	watchlists := api.Group("/watchlists")
+	watchlistsAuth := watchlists.Group("")
+	watchlistsAuth.Use(middleware.AuthMiddleware())
+	{
+		watchlistsAuth.POST("", watchlistController.Create)
+		watchlistsAuth.GET("", watchlistController.List)
+		watchlistsAuth.GET("/:id", watchlistController.Get)
+		watchlistsAuth.DELETE("/:id", watchlistController.Delete)
+		watchlistsAuth.POST("/:id/coins", watchlistController.AddCoin)
+		watchlistsAuth.DELETE("/:id/coins/:coin_id", watchlistController.RemoveCoin)
+		watchlistsAuth.PUT("/:id/order", watchlistController.Reorder)
+		watchlistsAuth.GET("/:id/quotes", watchlistController.Quotes)
+	}
+
+	// --------------------------
+	// Bot endpoints
+	// --------------------------
+	bots := api.Group("/bots")
+	bots.Use(middleware.AuthMiddleware())
+	{
+		bots.POST("", botController.Create)
+		bots.GET("", botController.List)
+		bots.GET("/:id", botController.Get)
+		bots.POST("/:id/start", botController.Start)
+		bots.POST("/:id/pause", botController.Pause)
+		bots.POST("/:id/stop", botController.Stop)
+		bots.GET("/:id/trades", botController.GetTrades)
+	}
+
+	// --------------------------
+	// Backtest endpoints
+	// --------------------------
+	backtests := api.Group("/backtests")
+	backtests.Use(middleware.AuthMiddleware())
+	{
+		backtests.POST("", backtestController.Submit)
+		backtests.GET("", backtestController.List)
+		backtests.GET("/:id", backtestController.Get)
 	}
 
 	// --------------------------
@@ -206,6 +468,10 @@ func RegisterRoutes(r *gin.Engine, db *gorm.DB) {
 	{
 
 		settings.POST("/apikey", settingsController.SaveAPIKey)
+		settings.GET("/fee-schedule", settingsController.GetFeeSchedule)
+		settings.POST("/fee-schedule", settingsController.SetFeeSchedule)
+		settings.GET("/display-currency", settingsController.GetDisplayCurrency)
+		settings.POST("/display-currency", settingsController.SetDisplayCurrency)
 
 	}
 
@@ -214,10 +480,13 @@ func RegisterRoutes(r *gin.Engine, db *gorm.DB) {
 	balances := api.Group("/balances")
 	balances.Use(middleware.AuthMiddleware())
 	{
-		balances.GET("/", balanceController.GetUSDBalance)
-		balances.POST("/init", balanceController.InitializeBalance)
-		balances.POST("/reset", balanceController.ResetBalance)
-		balances.POST("/update", balanceController.UpdateBalance)
+		balances.GET("/", balanceController.GetBalance)
+		balances.POST("/init", idempotent, balanceController.InitializeBalance)
+		balances.POST("/reset", idempotent, balanceController.ResetBalance)
+		balances.POST("/update", idempotent, balanceController.UpdateBalance)
+		balances.POST("/convert", idempotent, balanceController.Convert)
+		balances.GET("/portfolio", balanceController.GetPortfolio)
+		balances.GET("/equity", balanceController.GetEquityCurve)
 	}
 	// --------------------------
 	// Asset endpoints
@@ -225,8 +494,11 @@ func RegisterRoutes(r *gin.Engine, db *gorm.DB) {
 	{
 
 		assets.GET("/coins", assetContoller.GetAllCoins)
+		assets.GET("/coins/search", assetContoller.SearchCoins)
 		assets.GET("/coins/:id/market", assetContoller.GetCoinMarket)
+		assets.GET("/coins/:id/candles", assetContoller.GetCandles)
 		assets.GET("/coins/top-movers", assetContoller.GetTopMovers)
+		assets.GET("/screener", assetContoller.Screen)
 		assets.GET("/vs_currencies", assetContoller.GetSupportedVSCurrencies)
 	}
 
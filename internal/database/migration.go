@@ -16,10 +16,26 @@ func AutoMigrateAll(db *gorm.DB) error {
 	// Add all your models here
 	 &models.User{},
 	 &models.Chat{},
-	 &models.Trade{},
+	 &models.Order{},
+	 &models.Fill{},
 	 &models.Setting{},
 	 &models.Ledger{},
+	 &models.Portfolio{},
 	 &models.Balance{},
+	 &models.Holding{},
+	 &models.PortfolioSnapshot{},
+	 &models.RiskLimit{},
+	 &models.IdempotencyKey{},
+	 &models.Coin{},
+	 &models.Candle{},
+	 &models.Alert{},
+	 &models.Watchlist{},
+	 &models.WatchlistItem{},
+	 &models.Bot{},
+	 &models.BotTrade{},
+	 &models.BacktestRun{},
+	 &models.BacktestTrade{},
+	 &models.BacktestEquityPoint{},
 	 &models.MemorySnapshot{},
 	 // Memory embeddings and semantic search
 	 &models.MemoryEmbedding{},
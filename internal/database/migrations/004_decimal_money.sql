@@ -0,0 +1,36 @@
+-- Exact Decimal Money
+-- Migration 004: money, price and quantity columns from double precision to numeric
+
+-- Existing values are rounded to the precision the application keeps from now on:
+-- cents for USD amounts and fees, 8 decimal places for prices and coin quantities.
+-- Quantities are truncated so no holding grows in the conversion.
+
+BEGIN;
+
+ALTER TABLE balances
+    ALTER COLUMN amount   TYPE NUMERIC(36,18) USING ROUND(amount::NUMERIC, 2),
+    ALTER COLUMN reserved TYPE NUMERIC(36,18) USING ROUND(reserved::NUMERIC, 2);
+
+ALTER TABLE holdings
+    ALTER COLUMN quantity TYPE NUMERIC(36,18) USING TRUNC(quantity::NUMERIC, 8);
+
+ALTER TABLE trades
+    ALTER COLUMN quantity         TYPE NUMERIC(36,18) USING TRUNC(quantity::NUMERIC, 8),
+    ALTER COLUMN price            TYPE NUMERIC(36,18) USING ROUND(price::NUMERIC, 8),
+    ALTER COLUMN fee              TYPE NUMERIC(36,18) USING ROUND(fee::NUMERIC, 2),
+    ALTER COLUMN filled_quantity  TYPE NUMERIC(36,18) USING TRUNC(filled_quantity::NUMERIC, 8),
+    ALTER COLUMN reserved_amount  TYPE NUMERIC(36,18) USING ROUND(reserved_amount::NUMERIC, 2),
+    ALTER COLUMN stop_price       TYPE NUMERIC(36,18) USING ROUND(stop_price::NUMERIC, 8),
+    ALTER COLUMN trailing_percent TYPE NUMERIC(36,18) USING trailing_percent::NUMERIC,
+    ALTER COLUMN trailing_offset  TYPE NUMERIC(36,18) USING ROUND(trailing_offset::NUMERIC, 8),
+    ALTER COLUMN water_mark       TYPE NUMERIC(36,18) USING ROUND(water_mark::NUMERIC, 8);
+
+ALTER TABLE portfolio_snapshots
+    ALTER COLUMN cash           TYPE NUMERIC(36,18) USING ROUND(cash::NUMERIC, 2),
+    ALTER COLUMN holdings_value TYPE NUMERIC(36,18) USING ROUND(holdings_value::NUMERIC, 2),
+    ALTER COLUMN total_value    TYPE NUMERIC(36,18) USING ROUND(total_value::NUMERIC, 2);
+
+-- Reservations must still fit inside the rounded balances
+UPDATE balances SET reserved = amount WHERE reserved > amount;
+
+COMMIT;
@@ -0,0 +1,40 @@
+-- Multi-Currency Balances
+-- Migration 005: one cash balance per user and quote currency, and a quote currency per order
+
+-- Run this before starting the server so AutoMigrate can add idx_balances_user_asset.
+-- Repeated balance initialisation could leave a user with several USD rows; they are
+-- folded into the oldest row, which keeps the combined amount and reservation.
+
+BEGIN;
+
+UPDATE balances AS keep
+SET amount   = dup.amount,
+    reserved = dup.reserved
+FROM (
+    SELECT MIN(id) AS id, SUM(amount) AS amount, SUM(reserved) AS reserved
+    FROM balances
+    WHERE deleted_at IS NULL
+    GROUP BY user_id, UPPER(asset)
+    HAVING COUNT(*) > 1
+) AS dup
+WHERE keep.id = dup.id;
+
+DELETE FROM balances AS b
+USING balances AS keep
+WHERE keep.user_id = b.user_id
+  AND UPPER(keep.asset) = UPPER(b.asset)
+  AND keep.id < b.id
+  AND keep.deleted_at IS NULL;
+
+-- Soft-deleted rows would still collide with the unique index
+DELETE FROM balances WHERE deleted_at IS NOT NULL;
+
+UPDATE balances SET asset = UPPER(asset);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_balances_user_asset ON balances (user_id, asset);
+
+-- Every existing order was priced and settled in USD
+ALTER TABLE trades ADD COLUMN IF NOT EXISTS quote_currency VARCHAR(10) NOT NULL DEFAULT 'USD';
+ALTER TABLE trades ADD COLUMN IF NOT EXISTS fx_rate NUMERIC(36,18) NOT NULL DEFAULT 1;
+
+COMMIT;
@@ -0,0 +1,65 @@
+-- Named Portfolios
+-- Migration 006: balances, holdings, orders, snapshots and bots move under portfolios
+
+-- Run this before starting the server: AutoMigrate can't add the NOT NULL
+-- portfolio_id columns to tables that already hold rows. Every user with any trading
+-- data gets a portfolio named "Default" that takes over all of it.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS portfolios (
+    id          BIGSERIAL PRIMARY KEY,
+    created_at  TIMESTAMPTZ,
+    updated_at  TIMESTAMPTZ,
+    deleted_at  TIMESTAMPTZ,
+    user_id     BIGINT       NOT NULL,
+    name        VARCHAR(100) NOT NULL,
+    is_default  BOOLEAN      NOT NULL DEFAULT FALSE,
+    archived_at TIMESTAMPTZ
+);
+CREATE INDEX IF NOT EXISTS idx_portfolios_deleted_at ON portfolios (deleted_at);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_portfolios_user_name ON portfolios (user_id, name);
+
+INSERT INTO portfolios (created_at, updated_at, user_id, name, is_default)
+SELECT NOW(), NOW(), owners.user_id, 'Default', TRUE
+FROM (
+    SELECT user_id FROM balances
+    UNION SELECT user_id FROM holdings
+    UNION SELECT user_id FROM trades
+    UNION SELECT user_id FROM portfolio_snapshots
+    UNION SELECT user_id FROM bots
+) AS owners
+ON CONFLICT (user_id, name) DO NOTHING;
+
+ALTER TABLE balances            ADD COLUMN IF NOT EXISTS portfolio_id BIGINT;
+ALTER TABLE holdings            ADD COLUMN IF NOT EXISTS portfolio_id BIGINT;
+ALTER TABLE trades              ADD COLUMN IF NOT EXISTS portfolio_id BIGINT;
+ALTER TABLE portfolio_snapshots ADD COLUMN IF NOT EXISTS portfolio_id BIGINT;
+ALTER TABLE bots                ADD COLUMN IF NOT EXISTS portfolio_id BIGINT;
+
+UPDATE balances t SET portfolio_id = p.id FROM portfolios p
+WHERE p.user_id = t.user_id AND p.is_default AND t.portfolio_id IS NULL;
+UPDATE holdings t SET portfolio_id = p.id FROM portfolios p
+WHERE p.user_id = t.user_id AND p.is_default AND t.portfolio_id IS NULL;
+UPDATE trades t SET portfolio_id = p.id FROM portfolios p
+WHERE p.user_id = t.user_id AND p.is_default AND t.portfolio_id IS NULL;
+UPDATE portfolio_snapshots t SET portfolio_id = p.id FROM portfolios p
+WHERE p.user_id = t.user_id AND p.is_default AND t.portfolio_id IS NULL;
+UPDATE bots t SET portfolio_id = p.id FROM portfolios p
+WHERE p.user_id = t.user_id AND p.is_default AND t.portfolio_id IS NULL;
+
+ALTER TABLE balances            ALTER COLUMN portfolio_id SET NOT NULL;
+ALTER TABLE holdings            ALTER COLUMN portfolio_id SET NOT NULL;
+ALTER TABLE trades              ALTER COLUMN portfolio_id SET NOT NULL;
+ALTER TABLE portfolio_snapshots ALTER COLUMN portfolio_id SET NOT NULL;
+ALTER TABLE bots                ALTER COLUMN portfolio_id SET NOT NULL;
+
+-- Uniqueness moves from the user to the portfolio
+DROP INDEX IF EXISTS idx_balances_user_asset;
+DROP INDEX IF EXISTS idx_holdings_user_coin;
+DROP INDEX IF EXISTS idx_snapshots_user_taken;
+CREATE UNIQUE INDEX IF NOT EXISTS idx_balances_portfolio_asset ON balances (portfolio_id, asset);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_holdings_portfolio_coin ON holdings (portfolio_id, coin_id);
+CREATE INDEX IF NOT EXISTS idx_snapshots_portfolio_taken ON portfolio_snapshots (portfolio_id, taken_at);
+
+COMMIT;
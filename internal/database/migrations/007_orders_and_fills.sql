@@ -0,0 +1,116 @@
+-- Orders and Fills
+-- Migration 007: trades splits into orders (what was asked for) and fills (what executed)
+
+-- Run this before starting the server. Every execution used to be stored as a filled
+-- market row, and a limit or conditional order that executed kept its own row as well,
+-- so history listed each of those executions twice. The trades table becomes orders
+-- and every filled market row becomes a fill. A market row that executed a limit or
+-- conditional order is attached to that order as its fill and the duplicate row is
+-- removed; any other market row is a market order of its own and keeps its id, so bot
+-- trade logs still point at it.
+--
+-- Old rows carry no link from an execution to its order, so they are paired on
+-- portfolio, coin, side and quote currency, with the execution created while the order
+-- was live and never more quantity than the order reports filled.
+
+BEGIN;
+
+ALTER TABLE trades RENAME TO orders;
+ALTER SEQUENCE IF EXISTS trades_id_seq RENAME TO orders_id_seq;
+ALTER INDEX IF EXISTS idx_trades_deleted_at   RENAME TO idx_orders_deleted_at;
+ALTER INDEX IF EXISTS idx_trades_user_id      RENAME TO idx_orders_user_id;
+ALTER INDEX IF EXISTS idx_trades_portfolio_id RENAME TO idx_orders_portfolio_id;
+ALTER INDEX IF EXISTS idx_trades_coin_id      RENAME TO idx_orders_coin_id;
+ALTER INDEX IF EXISTS idx_trades_symbol       RENAME TO idx_orders_symbol;
+ALTER INDEX IF EXISTS idx_trades_expires_at   RENAME TO idx_orders_expires_at;
+ALTER INDEX IF EXISTS idx_trades_oco_group_id RENAME TO idx_orders_oco_group_id;
+
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS average_price NUMERIC(36,18) NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS fills (
+    id             BIGSERIAL PRIMARY KEY,
+    created_at     TIMESTAMPTZ,
+    updated_at     TIMESTAMPTZ,
+    deleted_at     TIMESTAMPTZ,
+    order_id       BIGINT         NOT NULL,
+    user_id        BIGINT         NOT NULL,
+    portfolio_id   BIGINT         NOT NULL,
+    coin_id        VARCHAR(100)   NOT NULL,
+    symbol         VARCHAR(20)    NOT NULL,
+    side           VARCHAR(10)    NOT NULL,
+    quantity       NUMERIC(36,18) NOT NULL,
+    price          NUMERIC(36,18) NOT NULL,
+    fee            NUMERIC(36,18) NOT NULL DEFAULT 0,
+    liquidity      VARCHAR(5)     NOT NULL DEFAULT 'taker',
+    quote_currency VARCHAR(10)    NOT NULL DEFAULT 'USD',
+    fx_rate        NUMERIC(36,18) NOT NULL DEFAULT 1
+);
+CREATE INDEX IF NOT EXISTS idx_fills_deleted_at ON fills (deleted_at);
+CREATE INDEX IF NOT EXISTS idx_fills_order_id ON fills (order_id);
+CREATE INDEX IF NOT EXISTS idx_fills_user_id ON fills (user_id);
+CREATE INDEX IF NOT EXISTS idx_fills_portfolio_id ON fills (portfolio_id);
+CREATE INDEX IF NOT EXISTS idx_fills_coin_id ON fills (coin_id);
+
+-- Each execution row goes to the latest matching order created before it that was
+-- still being updated when it executed, while that order's matched quantity fits its
+-- filled quantity. Bots only place market orders, so their rows are never duplicates.
+CREATE TEMP TABLE execution_parents ON COMMIT DROP AS
+WITH candidates AS (
+    SELECT e.id AS execution_id, o.id AS order_id, e.quantity, o.filled_quantity,
+           ROW_NUMBER() OVER (PARTITION BY e.id ORDER BY o.created_at DESC, o.id DESC) AS rank
+    FROM orders e
+    JOIN orders o
+      ON o.portfolio_id = e.portfolio_id
+     AND o.coin_id = e.coin_id
+     AND o.side = e.side
+     AND o.quote_currency = e.quote_currency
+     AND o.type <> 'market'
+     AND o.filled_quantity > 0
+     AND o.deleted_at IS NULL
+     AND e.created_at >= o.created_at
+     AND e.created_at <= o.updated_at + INTERVAL '1 second'
+    WHERE e.type = 'market'
+      AND e.status = 'filled'
+      AND e.deleted_at IS NULL
+      AND NOT EXISTS (SELECT 1 FROM bot_trades b WHERE b.trade_id = e.id)
+),
+matched AS (
+    SELECT execution_id, order_id, filled_quantity,
+           SUM(quantity) OVER (PARTITION BY order_id ORDER BY execution_id) AS running
+    FROM candidates
+    WHERE rank = 1
+)
+SELECT execution_id, order_id FROM matched WHERE running <= filled_quantity;
+
+-- Every execution row becomes a fill. Limit-style orders that executed after resting
+-- on the book were makers; everything else took liquidity.
+INSERT INTO fills (created_at, updated_at, order_id, user_id, portfolio_id, coin_id, symbol, side,
+                   quantity, price, fee, liquidity, quote_currency, fx_rate)
+SELECT e.created_at, e.created_at, COALESCE(p.order_id, e.id), e.user_id, e.portfolio_id, e.coin_id,
+       e.symbol, e.side, e.quantity, e.price, e.fee,
+       CASE WHEN parent.type IN ('limit', 'stop_limit') AND e.created_at > parent.created_at + INTERVAL '1 second'
+            THEN 'maker' ELSE 'taker' END,
+       e.quote_currency, e.fx_rate
+FROM orders e
+LEFT JOIN execution_parents p ON p.execution_id = e.id
+LEFT JOIN orders parent ON parent.id = p.order_id
+WHERE e.type = 'market' AND e.status = 'filled' AND e.deleted_at IS NULL
+ORDER BY e.id;
+
+DELETE FROM orders WHERE id IN (SELECT execution_id FROM execution_parents);
+
+-- Orders sum up their fills
+UPDATE orders o
+SET fee           = f.fee,
+    average_price = ROUND(f.notional / f.quantity, 8)
+FROM (
+    SELECT order_id, SUM(fee) AS fee, SUM(quantity * price) AS notional, SUM(quantity) AS quantity
+    FROM fills
+    GROUP BY order_id
+) AS f
+WHERE o.id = f.order_id AND f.quantity > 0;
+
+-- Exchange rates belong to fills now
+ALTER TABLE orders DROP COLUMN IF EXISTS fx_rate;
+
+COMMIT;
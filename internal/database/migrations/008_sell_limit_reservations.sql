@@ -0,0 +1,49 @@
+-- Sell Limit Reservations
+-- Migration 008: open sell limit orders reserve the coins they sell
+
+-- Run this before starting the server. Sell limits used to check the holding when they
+-- were placed and hold nothing back, so the same coins could back several open sells.
+-- Each open sell limit now reserves its unfilled quantity in holdings.reserved until it
+-- fills, is cancelled or expires.
+--
+-- Orders already on the book are backed oldest first, for as long as the holding
+-- covers them. An order the holding can't cover keeps no reservation and is rejected
+-- when it tries to fill without coins, as before. Re-running recomputes the same state.
+
+BEGIN;
+
+ALTER TABLE holdings ADD COLUMN IF NOT EXISTS reserved NUMERIC(36,18) NOT NULL DEFAULT 0;
+ALTER TABLE orders ADD COLUMN IF NOT EXISTS reserved_quantity NUMERIC(36,18) NOT NULL DEFAULT 0;
+
+WITH open_sells AS (
+    SELECT o.id,
+           o.quantity - o.filled_quantity AS remaining,
+           h.quantity AS held,
+           SUM(o.quantity - o.filled_quantity) OVER (
+               PARTITION BY o.portfolio_id, o.coin_id
+               ORDER BY o.created_at, o.id
+           ) AS running
+    FROM orders o
+    JOIN holdings h ON h.portfolio_id = o.portfolio_id AND h.coin_id = o.coin_id AND h.deleted_at IS NULL
+    WHERE o.deleted_at IS NULL
+      AND o.side = 'sell'
+      AND o.type = 'limit'
+      AND o.status IN ('open', 'partially_filled')
+)
+UPDATE orders o
+SET reserved_quantity = CASE WHEN s.running <= s.held THEN s.remaining ELSE 0 END
+FROM open_sells s
+WHERE o.id = s.id;
+
+UPDATE holdings h
+SET reserved = COALESCE((
+    SELECT SUM(o.reserved_quantity)
+    FROM orders o
+    WHERE o.portfolio_id = h.portfolio_id
+      AND o.coin_id = h.coin_id
+      AND o.deleted_at IS NULL
+      AND o.status IN ('open', 'partially_filled')
+), 0)
+WHERE h.deleted_at IS NULL;
+
+COMMIT;
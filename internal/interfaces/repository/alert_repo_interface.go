@@ -0,0 +1,11 @@
+package Repositories
+
+import "ares_api/internal/models"
+
+type AlertRepository interface {
+	Create(alert *models.Alert) error
+	Update(alert *models.Alert) error
+	Delete(userID, alertID uint) (bool, error)
+	GetByUser(userID uint) ([]models.Alert, error)
+	GetActive() ([]models.Alert, error)
+}
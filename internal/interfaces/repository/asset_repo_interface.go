@@ -2,11 +2,17 @@ package Repositories
 
 import (
 	"ares_api/internal/api/dto"
+	"time"
 )
 
 type AssetRepository interface {
 	FetchAllCoins() ([]dto.CoinDTO, error)
 	FetchCoinMarket(id string , vsCurrency string) (*dto.CoinMarketDTO, error)
-	FetchTopMovers(limit int) ([]dto.TopMoverDTO, error)
+	FetchCoinMarkets(ids []string, vsCurrency string) (map[string]dto.CoinMarketDTO, error)
+	// FetchMarketUniverse returns USD market data of the largest coins by market cap,
+	// largest first, cached for a short while
+	FetchMarketUniverse() ([]dto.CoinMarketDTO, error)
+	FetchMarketChart(id string, vsCurrency string, from, to time.Time) ([]dto.PricePointDTO, error)
+	CachedCoinMarkets(vsCurrency string) []dto.CoinMarketDTO
 	FetchSupportedVSCurrencies() ([]string, error)
 }
\ No newline at end of file
@@ -0,0 +1,15 @@
+package Repositories
+
+import "ares_api/internal/models"
+
+type BacktestRepository interface {
+	Create(run *models.BacktestRun) error
+	Update(run *models.BacktestRun) error
+	GetRun(runID uint) (*models.BacktestRun, error)
+	GetByID(userID, runID uint) (*models.BacktestRun, error)
+	GetByUser(userID uint, limit int) ([]models.BacktestRun, error)
+	GetByStatus(statuses ...string) ([]models.BacktestRun, error)
+	SaveResults(run *models.BacktestRun, trades []models.BacktestTrade, equity []models.BacktestEquityPoint) error
+	GetTrades(runID uint) ([]models.BacktestTrade, error)
+	GetEquity(runID uint) ([]models.BacktestEquityPoint, error)
+}
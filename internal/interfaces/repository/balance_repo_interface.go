@@ -1,10 +1,20 @@
 package Repositories
-import "ares_api/internal/models"
+
+import (
+	"ares_api/internal/models"
+
+	"github.com/shopspring/decimal"
+
+	"gorm.io/gorm"
+)
 
 type BalanceRepository interface {
-	GetUSDBalance(userID uint) (*models.Balance, error)
-	UpdateUSDBalance(userID uint, delta float64) (*models.Balance, error)
-	ResetUSDBalance(userID uint, defaultBalance float64) error
-	CreateUSDBalance(userID uint, defaultBalance float64) (*models.Balance, error)
+	WithTx(tx *gorm.DB) BalanceRepository
+	GetBalance(portfolioID uint, asset string) (*models.Balance, error)
+	GetBalanceForUpdate(portfolioID uint, asset string) (*models.Balance, error)
+	GetBalances(portfolioID uint) ([]models.Balance, error)
+	UpdateBalance(userID, portfolioID uint, asset string, delta decimal.Decimal) (*models.Balance, error)
+	Reserve(portfolioID uint, asset string, delta decimal.Decimal) (*models.Balance, error)
+	ResetBalances(portfolioID uint, defaultBalance decimal.Decimal) error
+	CreateUSDBalance(userID, portfolioID uint, defaultBalance decimal.Decimal) (*models.Balance, error)
 }
-
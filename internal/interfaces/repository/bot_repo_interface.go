@@ -0,0 +1,13 @@
+package Repositories
+
+import "ares_api/internal/models"
+
+type BotRepository interface {
+	Create(bot *models.Bot) error
+	Update(bot *models.Bot) error
+	GetByID(userID, botID uint) (*models.Bot, error)
+	GetByUser(userID uint) ([]models.Bot, error)
+	GetRunning() ([]models.Bot, error)
+	RecordTrade(bot *models.Bot, trade *models.BotTrade) error
+	GetTrades(botID uint, limit int) ([]models.BotTrade, error)
+}
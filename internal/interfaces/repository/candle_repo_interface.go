@@ -0,0 +1,12 @@
+package Repositories
+
+import (
+	"ares_api/internal/models"
+	"time"
+)
+
+type CandleRepository interface {
+	Upsert(candles []models.Candle) error
+	Merge(candle *models.Candle) error
+	GetRange(coinID, interval string, from, to time.Time) ([]models.Candle, error)
+}
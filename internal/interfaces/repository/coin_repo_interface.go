@@ -0,0 +1,19 @@
+package Repositories
+
+import (
+	"ares_api/internal/models"
+	"time"
+)
+
+type CoinRepository interface {
+	Upsert(coins []models.Coin) error
+	// DeleteStale removes the coins no refresh has listed since before
+	DeleteStale(before time.Time) (int64, error)
+	Count() (int64, error)
+	List(limit int) ([]models.Coin, error)
+	GetByID(id string) (*models.Coin, error)
+	GetBySymbol(symbol string) ([]models.Coin, error)
+	// SearchCandidates returns the coins a search for query can rank: every coin whose
+	// symbol or name contains it, or starts with the same letter
+	SearchCandidates(query string) ([]models.Coin, error)
+}
@@ -0,0 +1,18 @@
+package Repositories
+
+import (
+	"ares_api/internal/models"
+
+	"github.com/shopspring/decimal"
+
+	"gorm.io/gorm"
+)
+
+type HoldingRepository interface {
+	WithTx(tx *gorm.DB) HoldingRepository
+	GetHolding(portfolioID uint, coinID string) (*models.Holding, error)
+	GetHoldingForUpdate(portfolioID uint, coinID string) (*models.Holding, error)
+	GetHoldings(portfolioID uint) ([]models.Holding, error)
+	UpdateHolding(userID, portfolioID uint, coinID string, symbol string, delta decimal.Decimal) (*models.Holding, error)
+	Reserve(portfolioID uint, coinID string, delta decimal.Decimal) (*models.Holding, error)
+}
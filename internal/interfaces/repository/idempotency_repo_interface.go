@@ -0,0 +1,16 @@
+package Repositories
+
+import (
+	"ares_api/internal/models"
+	"time"
+)
+
+type IdempotencyRepository interface {
+	// Claim inserts record unless the user already has a row for its key; claimed
+	// reports whether the insert won
+	Claim(record *models.IdempotencyKey) (claimed bool, err error)
+	Get(userID uint, key string) (*models.IdempotencyKey, error)
+	Save(record *models.IdempotencyKey) error
+	Delete(record *models.IdempotencyKey) error
+	DeleteExpired(now time.Time) (int64, error)
+}
@@ -0,0 +1,17 @@
+package Repositories
+
+import (
+	"ares_api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type PortfolioRepository interface {
+	WithTx(tx *gorm.DB) PortfolioRepository
+	Create(portfolio *models.Portfolio) error
+	Update(portfolio *models.Portfolio) error
+	GetByID(userID, portfolioID uint) (*models.Portfolio, error)
+	GetByUser(userID uint) ([]models.Portfolio, error)
+	GetDefault(userID uint) (*models.Portfolio, error)
+	GetActive() ([]models.Portfolio, error)
+}
@@ -0,0 +1,20 @@
+package Repositories
+
+import (
+	"ares_api/internal/api/dto"
+	"time"
+)
+
+// PriceProvider is a source of coin and market data. AssetRepository reads through
+// one, so services never know whether prices come from CoinGecko, a replay file or
+// a simulator.
+type PriceProvider interface {
+	FetchAllCoins() ([]dto.CoinDTO, error)
+	// FetchCoinMarkets returns market data for every id it knows; unknown ids are omitted
+	FetchCoinMarkets(ids []string, vsCurrency string) ([]dto.CoinMarketDTO, error)
+	// FetchTopMarkets returns the USD market data of the limit largest coins by market cap
+	FetchTopMarkets(limit int) ([]dto.CoinMarketDTO, error)
+	// FetchMarketChart returns the coin's price history between from and to, oldest first
+	FetchMarketChart(id, vsCurrency string, from, to time.Time) ([]dto.PricePointDTO, error)
+	FetchSupportedVSCurrencies() ([]string, error)
+}
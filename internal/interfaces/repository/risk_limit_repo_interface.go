@@ -0,0 +1,8 @@
+package Repositories
+
+import "ares_api/internal/models"
+
+type RiskLimitRepository interface {
+	GetByPortfolio(portfolioID uint) (*models.RiskLimit, error)
+	Save(limit *models.RiskLimit) error
+}
@@ -0,0 +1,12 @@
+package Repositories
+
+import (
+	"ares_api/internal/models"
+	"time"
+)
+
+type SnapshotRepository interface {
+	Create(snapshot *models.PortfolioSnapshot) error
+	GetByPortfolioSince(portfolioID uint, from time.Time) ([]models.PortfolioSnapshot, error)
+	GetLatestBefore(portfolioID uint, before time.Time) (*models.PortfolioSnapshot, error)
+}
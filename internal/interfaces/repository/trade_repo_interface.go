@@ -1,11 +1,36 @@
 package Repositories
 
-import "ares_api/internal/models"
+import (
+	"ares_api/internal/models"
+	"time"
 
+	"gorm.io/gorm"
+)
+
+// TradeFilter narrows an order or fill search; zero fields don't filter
+type TradeFilter struct {
+	PortfolioID uint
+	CoinID      string
+	Side        string
+	From        time.Time // created at or after
+	To          time.Time // created before
+}
+
+// TradeRepository stores orders and the fills that execute them
 type TradeRepository interface {
-	Create(trade *models.Trade) error
-	GetByUserID(userID uint, limit int) ([]models.Trade, error)
-	GetOpenLimitOrders() ([]models.Trade, error)
-	MarkOrderFilled(tradeID uint) error
-	GetOpenLimitOrdersByUser(userID uint) ([]models.Trade, error)
+	WithTx(tx *gorm.DB) TradeRepository
+	Create(order *models.Order) error
+	GetByPortfolio(portfolioID uint, limit int) ([]models.Order, error)
+	Search(filter TradeFilter) ([]models.Order, error)
+	GetOpenOrders() ([]models.Order, error)
+	GetOrderByID(orderID uint) (*models.Order, error)
+	GetOrderForUpdate(orderID uint) (*models.Order, error)
+	GetOCOGroupForUpdate(groupID string) ([]models.Order, error)
+	UpdateOrder(order *models.Order) error
+	GetOpenOrdersByPortfolio(portfolioID uint) ([]models.Order, error)
+
+	CreateFill(fill *models.Fill) error
+	GetFills(orderIDs []uint) ([]models.Fill, error)
+	GetFillsByPortfolio(portfolioID uint) ([]models.Fill, error)
+	SearchFills(filter TradeFilter) ([]models.Fill, error)
 }
@@ -0,0 +1,9 @@
+package Repositories
+
+import "gorm.io/gorm"
+
+// TxManager runs a unit of work inside a single database transaction.
+// Repositories join the transaction through their WithTx method.
+type TxManager interface {
+	Transaction(fn func(tx *gorm.DB) error) error
+}
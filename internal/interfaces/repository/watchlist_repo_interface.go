@@ -0,0 +1,20 @@
+package Repositories
+
+import (
+	"ares_api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type WatchlistRepository interface {
+	WithTx(tx *gorm.DB) WatchlistRepository
+	Create(watchlist *models.Watchlist) error
+	// GetByID and GetByUser load the coins of each watchlist in order
+	GetByID(userID, watchlistID uint) (*models.Watchlist, error)
+	GetByUser(userID uint) ([]models.Watchlist, error)
+	// Delete removes the watchlist with its coins
+	Delete(watchlist *models.Watchlist) error
+	AddItem(item *models.WatchlistItem) error
+	DeleteItem(item *models.WatchlistItem) error
+	UpdatePositions(items []models.WatchlistItem) error
+}
@@ -0,0 +1,19 @@
+package service
+
+import (
+	"ares_api/internal/api/dto"
+	"errors"
+)
+
+var (
+	ErrAlertNotFound = errors.New("alert not found")
+	ErrInvalidAlert  = errors.New("invalid alert")
+)
+
+type AlertService interface {
+	Create(userID uint, req dto.CreateAlertRequest) (*dto.AlertDTO, error)
+	List(userID uint) ([]dto.AlertDTO, error)
+	Delete(userID, alertID uint) error
+	// Subscribe streams the user's fired alerts until the returned cancel func is called
+	Subscribe(userID uint) (<-chan dto.AlertEventDTO, func())
+}
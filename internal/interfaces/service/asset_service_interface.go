@@ -1,9 +1,54 @@
 package service
 
-import "ares_api/internal/api/dto"
+import (
+	"ares_api/internal/api/dto"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrInvalidCoinQuery = errors.New("invalid coin query")
+	ErrUnknownCoin      = errors.New("coin is not in the catalog")
+	ErrSymbolMismatch   = errors.New("symbol does not match coin")
+	ErrAmbiguousSymbol  = errors.New("symbol matches several coins")
+	ErrInvalidScreener  = errors.New("invalid screener query")
+)
+
+// Errors the market data provider fails with, wrapped in a *MarketDataError
+var (
+	ErrMarketDataRateLimited = errors.New("market data rate limited")
+	ErrMarketDataUnavailable = errors.New("market data unavailable")
+	ErrMarketDataNotFound    = errors.New("market data not found")
+	ErrMarketDataRejected    = errors.New("market data request rejected")
+)
+
+// MarketDataError is the error of a failed market data request. It matches its Kind,
+// one of the ErrMarketData errors, and the underlying error.
+type MarketDataError struct {
+	Kind       error
+	StatusCode int           // upstream HTTP status; 0 when no response arrived
+	RetryAfter time.Duration // how long the upstream asked callers to wait, if it did
+	Err        error
+}
+
+func (e *MarketDataError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Kind, e.Err)
+}
+
+func (e *MarketDataError) Unwrap() []error {
+	return []error{e.Kind, e.Err}
+}
+
 type AssetService interface {
 	GetAllCoins(limit int) ([]dto.CoinDTO, error)
+	SearchCoins(query string, limit int) ([]dto.CoinSearchResultDTO, error)
+	// ResolveCoin finds the catalog coin an order names by coin id, symbol or both,
+	// checking that the two agree
+	ResolveCoin(coinID, symbol string) (*dto.CoinDTO, error)
+	RefreshCatalog() (int, error)
 	GetCoinMarket(id string , vsCurrency string) (*dto.CoinMarketDTO, error)
-	GetTopMovers(limit int) ([]dto.TopMoverDTO, error)
+	GetTopMovers(query dto.TopMoversQuery) (*dto.TopMoversDTO, error)
+	Screen(filter dto.ScreenerFilter) ([]dto.CoinMarketDTO, error)
 	GetSupportedVSCurrencies() ([]string, error)
 }
@@ -0,0 +1,17 @@
+package service
+
+import (
+	"ares_api/internal/api/dto"
+	"errors"
+)
+
+var (
+	ErrBacktestNotFound = errors.New("backtest not found")
+	ErrInvalidBacktest  = errors.New("invalid backtest")
+)
+
+type BacktestService interface {
+	Submit(userID uint, req dto.BacktestRequest) (*dto.BacktestRunDTO, error)
+	Get(userID, runID uint) (*dto.BacktestRunDTO, error)
+	List(userID uint, limit int) ([]dto.BacktestRunDTO, error)
+}
@@ -2,12 +2,22 @@ package service
 
 import (
 	"ares_api/internal/api/dto"
+	"errors"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrUnsupportedCurrency = errors.New("unsupported currency")
+	ErrInsufficientFunds   = errors.New("insufficient funds")
+	ErrInvalidConversion   = errors.New("invalid conversion")
 )
 
 type BalanceService interface {
-	GetUSDBalance(userID uint) (*dto.BalanceDTO, error)
-	UpdateUSDBalance(userID uint, delta float64) (*dto.BalanceDTO, error)
-	ResetUSDBalance(userID uint) (*dto.BalanceDTO, error)
+	GetBalance(userID, portfolioID uint, currency string) (*dto.BalanceDTO, error)
+	UpdateBalance(userID, portfolioID uint, currency string, delta decimal.Decimal) (*dto.BalanceDTO, error)
+	ResetBalance(userID, portfolioID uint) (*dto.BalanceDTO, error)
 	InitializeBalance(userID uint) (*dto.BalanceDTO, error)
+	Convert(userID, portfolioID uint, req dto.ConvertRequest) (*dto.ConversionDTO, error)
+	GetPortfolio(userID, portfolioID uint, displayCurrency string) (*dto.PortfolioDTO, error)
 }
-
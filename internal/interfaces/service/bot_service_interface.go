@@ -0,0 +1,22 @@
+package service
+
+import (
+	"ares_api/internal/api/dto"
+	"errors"
+)
+
+var (
+	ErrBotNotFound = errors.New("bot not found")
+	ErrBotStopped  = errors.New("bot is stopped")
+	ErrInvalidBot  = errors.New("invalid bot")
+)
+
+type BotService interface {
+	Create(userID uint, req dto.CreateBotRequest) (*dto.BotDTO, error)
+	List(userID uint) ([]dto.BotDTO, error)
+	Get(userID, botID uint) (*dto.BotDTO, error)
+	Start(userID, botID uint) (*dto.BotDTO, error)
+	Pause(userID, botID uint) (*dto.BotDTO, error)
+	Stop(userID, botID uint) (*dto.BotDTO, error)
+	GetTrades(userID, botID uint, limit int) ([]dto.BotTradeDTO, error)
+}
@@ -0,0 +1,18 @@
+package service
+
+import (
+	"ares_api/internal/api/dto"
+	"errors"
+	"time"
+)
+
+var (
+	ErrInvalidInterval = errors.New("invalid interval: must be 1m, 1h or 1d")
+	ErrInvalidRange    = errors.New("invalid range: from must be before to")
+	ErrRangeTooLarge   = errors.New("range too large: at most 1000 candles per request")
+)
+
+type CandleService interface {
+	GetCandles(coinID, interval string, from, to time.Time) (*dto.CandlesDTO, error)
+	RecordQuotes() (int, error)
+}
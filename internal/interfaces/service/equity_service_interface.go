@@ -0,0 +1,12 @@
+package service
+
+import (
+	"ares_api/internal/api/dto"
+	"errors"
+)
+
+var ErrInvalidWindow = errors.New("invalid window: must be 1d, 7d, 30d, 90d, 1y or all")
+
+type EquityService interface {
+	GetEquityCurve(userID, portfolioID uint, window string) (*dto.EquityCurveDTO, error)
+}
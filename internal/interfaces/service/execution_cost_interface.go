@@ -0,0 +1,26 @@
+package service
+
+import "github.com/shopspring/decimal"
+
+// Liquidity roles of a simulated fill
+const (
+	LiquidityMaker = "maker" // a resting limit order that was filled
+	LiquidityTaker = "taker" // a market order or any order that filled on arrival
+)
+
+// ExecutionQuote is the simulated outcome of a fill
+type ExecutionQuote struct {
+	Price    decimal.Decimal // fill price after slippage
+	Fee      decimal.Decimal // fee charged on the fill, in the quote currency
+	Slippage decimal.Decimal // fraction the fill price moved away from the market price
+}
+
+// ExecutionCostModel prices simulated fills
+type ExecutionCostModel interface {
+	// Slippage returns the fraction a fill of notional USD moves the price.
+	// marketCap is the coin's USD market capitalisation and may be zero when unknown.
+	Slippage(notional, marketCap decimal.Decimal, liquidity string) decimal.Decimal
+	// Fee returns the USD fee charged on a fill of notional USD, unrounded. Callers round
+	// it to the scale of the currency the fill settles in.
+	Fee(notional decimal.Decimal, liquidity string) decimal.Decimal
+}
@@ -0,0 +1,26 @@
+package service
+
+import "errors"
+
+var (
+	ErrInvalidIdempotencyKey    = errors.New("invalid idempotency key: must be 1 to 255 printable characters")
+	ErrIdempotencyKeyMismatch   = errors.New("idempotency key was already used for a different request")
+	ErrIdempotencyKeyInProgress = errors.New("a request with this idempotency key is still being processed")
+)
+
+// IdempotentResponse is the stored response replayed for a repeated Idempotency-Key
+type IdempotentResponse struct {
+	StatusCode  int
+	ContentType string
+	Body        []byte
+}
+
+type IdempotencyService interface {
+	// Begin claims key for a request with the given fingerprint. It returns the stored
+	// response when the key already completed, or nil when the caller now owns the key
+	// and must Complete or Release it.
+	Begin(userID uint, key, fingerprint string) (*IdempotentResponse, error)
+	Complete(userID uint, key string, response IdempotentResponse) error
+	Release(userID uint, key string) error
+	PurgeExpired() (int64, error)
+}
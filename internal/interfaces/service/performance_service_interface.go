@@ -0,0 +1,12 @@
+package service
+
+import (
+	"ares_api/internal/api/dto"
+	"errors"
+)
+
+var ErrInvalidCostBasis = errors.New("invalid cost basis: must be fifo, lifo, hifo or average")
+
+type PerformanceService interface {
+	GetPerformance(userID, portfolioID uint, costBasis string) (*dto.PerformanceDTO, error)
+}
@@ -0,0 +1,18 @@
+package service
+
+import (
+	"ares_api/internal/api/dto"
+	"errors"
+)
+
+var (
+	ErrPortfolioNotFound = errors.New("portfolio not found")
+	ErrPortfolioArchived = errors.New("portfolio is archived")
+	ErrInvalidPortfolio  = errors.New("invalid portfolio")
+)
+
+type PortfolioService interface {
+	Create(userID uint, req dto.CreatePortfolioRequest) (*dto.PortfolioSummaryDTO, error)
+	List(userID uint, includeArchived bool) ([]dto.PortfolioSummaryDTO, error)
+	Archive(userID, portfolioID uint) (*dto.PortfolioSummaryDTO, error)
+}
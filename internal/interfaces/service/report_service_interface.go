@@ -0,0 +1,18 @@
+package service
+
+import (
+	"ares_api/internal/api/dto"
+	"errors"
+)
+
+var (
+	ErrInvalidExportFilter = errors.New("invalid export filter")
+	ErrInvalidLotMethod    = errors.New("invalid lot method: must be fifo, lifo or hifo")
+)
+
+type ReportService interface {
+	ExportTrades(userID, portfolioID uint, filter dto.TradeExportFilter) ([]dto.TradeExportDTO, error)
+	// TaxReport replays every fill of the portfolio; year, when non-zero, limits the
+	// years and disposals reported
+	TaxReport(userID, portfolioID uint, method string, year int) (*dto.TaxReportDTO, error)
+}
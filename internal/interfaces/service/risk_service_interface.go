@@ -0,0 +1,54 @@
+package service
+
+import (
+	"ares_api/internal/api/dto"
+	"errors"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+var (
+	ErrRiskRejected      = errors.New("order rejected by risk limits")
+	ErrInvalidRiskLimits = errors.New("invalid risk limits")
+)
+
+// RiskRejection is the error for an order that breaks its portfolio's risk limits.
+// It matches ErrRiskRejected and lists every limit broken.
+type RiskRejection struct {
+	Violations []dto.RiskViolationDTO
+}
+
+func (e *RiskRejection) Error() string {
+	if len(e.Violations) == 0 {
+		return ErrRiskRejected.Error()
+	}
+	return fmt.Sprintf("%s: %s", ErrRiskRejected, e.Violations[0].Message)
+}
+
+func (e *RiskRejection) Unwrap() error {
+	return ErrRiskRejected
+}
+
+// OrderIntent is an order about to be placed, as the pre-trade risk checks see it
+type OrderIntent struct {
+	UserID      uint
+	PortfolioID uint
+	CoinID      string
+	Side        string
+	Currency    string
+	Quantity    decimal.Decimal
+	Price       decimal.Decimal // expected fill, limit or trigger price, in Currency
+	Resting     bool            // the order may rest on the book and count as open
+	Orders      int             // resting orders placed together, such as the legs of an OCO bracket; zero means one
+	Amends      uint            // the open order being amended, which the open order count already includes
+	DryRun      bool            // a preview: nothing is logged to the ledger
+}
+
+type RiskService interface {
+	GetLimits(userID, portfolioID uint) (*dto.RiskLimitsDTO, error)
+	SetLimits(userID, portfolioID uint, req dto.RiskLimitsRequest) (*dto.RiskLimitsDTO, error)
+	// CheckOrder evaluates an order against its portfolio's limits. In warn mode the
+	// broken limits are returned; in reject mode they come back as a *RiskRejection.
+	CheckOrder(order OrderIntent) ([]dto.RiskViolationDTO, error)
+}
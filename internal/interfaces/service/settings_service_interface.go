@@ -1,5 +1,11 @@
 package service
 
+import "ares_api/internal/api/dto"
+
 type SettingsService interface {
 	SaveAPIKey(userID uint, apiKey string) error
+	GetFeeSchedule(userID uint) (*dto.FeeScheduleResponse, error)
+	SetFeeSchedule(userID uint, name string) (*dto.FeeScheduleResponse, error)
+	GetDisplayCurrency(userID uint) (*dto.DisplayCurrencyResponse, error)
+	SetDisplayCurrency(userID uint, currency string) (*dto.DisplayCurrencyResponse, error)
 }
@@ -1,10 +1,27 @@
 package service
 
-import "ares_api/internal/api/dto"
+import (
+	"ares_api/internal/api/dto"
+	"errors"
+)
+
+var (
+	ErrOrderNotFound       = errors.New("order not found")
+	ErrOrderNotOpen        = errors.New("order is no longer open")
+	ErrInvalidOrderType    = errors.New("invalid order type")
+	ErrInvalidOrder        = errors.New("invalid order")
+	ErrInsufficientHolding = errors.New("insufficient holding")
+)
 
 type TradeService interface {
-	MarketOrder(userID uint, req dto.MarketOrderRequest) (*dto.TradeResponse, error)
-	LimitOrder(userID uint, req dto.LimitOrderRequest) (*dto.TradeResponse, error)
-	GetHistory(userID uint, limit int) ([]dto.TradeResponse, error)
-	GetPendingLimitOrders(userID uint ) ([]dto.TradeResponse, error)
+	MarketOrder(userID, portfolioID uint, req dto.MarketOrderRequest) (*dto.TradeResponse, error)
+	LimitOrder(userID, portfolioID uint, req dto.LimitOrderRequest) (*dto.TradeResponse, error)
+	// PreviewOrder reports what a market or limit order would do without placing it
+	PreviewOrder(userID, portfolioID uint, req dto.TradePreviewRequest) (*dto.TradePreviewDTO, error)
+	ConditionalOrder(userID, portfolioID uint, req dto.ConditionalOrderRequest) (*dto.TradeResponse, error)
+	OCOOrder(userID, portfolioID uint, req dto.OCOOrderRequest) ([]dto.TradeResponse, error)
+	CancelOrder(userID uint, orderID uint) (*dto.TradeResponse, error)
+	AmendOrder(userID uint, orderID uint, req dto.AmendOrderRequest) (*dto.TradeResponse, error)
+	GetHistory(userID, portfolioID uint, limit int) ([]dto.TradeResponse, error)
+	GetPendingLimitOrders(userID, portfolioID uint) ([]dto.TradeResponse, error)
 }
@@ -0,0 +1,25 @@
+package service
+
+import (
+	"ares_api/internal/api/dto"
+	"errors"
+)
+
+var (
+	ErrWatchlistNotFound  = errors.New("watchlist not found")
+	ErrInvalidWatchlist   = errors.New("invalid watchlist")
+	ErrCoinOnWatchlist    = errors.New("coin is already on the watchlist")
+	ErrCoinNotOnWatchlist = errors.New("coin is not on the watchlist")
+)
+
+type WatchlistService interface {
+	Create(userID uint, req dto.CreateWatchlistRequest) (*dto.WatchlistDTO, error)
+	List(userID uint) ([]dto.WatchlistDTO, error)
+	Get(userID, watchlistID uint) (*dto.WatchlistDTO, error)
+	Delete(userID, watchlistID uint) error
+	AddCoin(userID, watchlistID uint, req dto.AddWatchlistCoinRequest) (*dto.WatchlistDTO, error)
+	RemoveCoin(userID, watchlistID uint, coinID string) (*dto.WatchlistDTO, error)
+	Reorder(userID, watchlistID uint, req dto.ReorderWatchlistRequest) (*dto.WatchlistDTO, error)
+	// Quotes returns the current quote of every coin on the watchlist in one batch
+	Quotes(userID, watchlistID uint, vsCurrency string) (*dto.WatchlistQuotesDTO, error)
+}
@@ -0,0 +1,117 @@
+package middleware
+
+import (
+	"ares_api/internal/common"
+	service "ares_api/internal/interfaces/service"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// IdempotencyKeyHeader is the request header carrying the client's idempotency key
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotencyMiddleware makes a mutation safe to retry. A request carrying an
+// Idempotency-Key header runs once; later requests with the same key and the same
+// method, path, query and body get the first response replayed, marked with an
+// Idempotent-Replayed header. Reusing a key for a different request is a 422, and a
+// duplicate arriving while the first is still running is a 409. Server errors aren't
+// stored, so the client can retry them. Requests without the header pass through.
+// It must run after AuthMiddleware, since keys are scoped to the user.
+func IdempotencyMiddleware(idempotency service.IdempotencyService) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			common.JSON(c, http.StatusBadRequest, gin.H{"error": "failed to read request body"})
+			c.Abort()
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		userID := c.GetUint("userID")
+		stored, err := idempotency.Begin(userID, key, requestFingerprint(c.Request, body))
+		if err != nil {
+			common.JSON(c, idempotencyErrorStatus(err), gin.H{"error": err.Error()})
+			c.Abort()
+			return
+		}
+		if stored != nil {
+			c.Header("Idempotent-Replayed", "true")
+			c.Data(stored.StatusCode, stored.ContentType, stored.Body)
+			c.Abort()
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		completed := false
+		defer func() {
+			// A panicking handler must not leave the key claimed until it expires
+			if !completed {
+				_ = idempotency.Release(userID, key)
+			}
+		}()
+
+		c.Next()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			return
+		}
+		err = idempotency.Complete(userID, key, service.IdempotentResponse{
+			StatusCode:  status,
+			ContentType: recorder.Header().Get("Content-Type"),
+			Body:        recorder.body.Bytes(),
+		})
+		completed = err == nil
+	}
+}
+
+// requestFingerprint identifies what a request asks for, so a reused key can be told
+// apart from a genuine retry
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func idempotencyErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, service.ErrInvalidIdempotencyKey):
+		return http.StatusBadRequest
+	case errors.Is(err, service.ErrIdempotencyKeyMismatch):
+		return http.StatusUnprocessableEntity
+	case errors.Is(err, service.ErrIdempotencyKeyInProgress):
+		return http.StatusConflict
+	default:
+		return http.StatusInternalServerError
+	}
+}
+
+// responseRecorder copies the response body while it is written to the client
+type responseRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *responseRecorder) Write(data []byte) (int, error) {
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *responseRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
@@ -0,0 +1,15 @@
+package middleware
+
+import "github.com/gin-gonic/gin"
+
+// QueryTokenMiddleware lets clients that can't set headers, such as a browser
+// EventSource, pass their JWT as an access_token query parameter. It must run
+// before AuthMiddleware; an Authorization header takes precedence.
+func QueryTokenMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if token := c.Query("access_token"); token != "" && c.GetHeader("Authorization") == "" {
+			c.Request.Header.Set("Authorization", "Bearer "+token)
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,49 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Alert conditions
+const (
+	AlertPriceAbove  = "price_above"  // USD price at or above Threshold
+	AlertPriceBelow  = "price_below"  // USD price at or below Threshold
+	AlertPercentMove = "percent_move" // price moved Threshold % over the last WindowMinutes; negative for falls
+	AlertChange24h   = "change_24h"   // 24h change at or beyond Threshold %; negative for falls
+)
+
+// Alert modes
+const (
+	AlertModeOnce      = "once"      // fires once, then stays triggered
+	AlertModeRecurring = "recurring" // re-arms whenever the condition clears
+)
+
+// Alert statuses
+const (
+	AlertStatusActive    = "active"
+	AlertStatusTriggered = "triggered"
+)
+
+// Alert watches one coin for a condition. Alerts fire on the edge: the condition has
+// to be seen false (arming the alert) before it can fire on turning true, so a new
+// alert whose condition already holds waits for the next crossing.
+type Alert struct {
+	gorm.Model
+	UserID          uint            `gorm:"not null;index" json:"user_id"`
+	CoinID          string          `gorm:"size:100;not null" json:"coin_id"`
+	Condition       string          `gorm:"size:20;not null" json:"condition"` // see Alert* condition constants
+	Threshold       decimal.Decimal `gorm:"type:numeric(36,18);not null" json:"threshold"`
+	WindowMinutes   int             `gorm:"not null;default:0" json:"window_minutes"` // percent_move only
+	Mode            string          `gorm:"size:10;not null" json:"mode"`
+	CooldownMinutes int             `gorm:"not null;default:0" json:"cooldown_minutes"` // recurring: least time between firings
+	Status          string          `gorm:"size:10;not null;index" json:"status"`
+	Armed           bool            `gorm:"not null;default:false" json:"armed"`
+	TriggerCount    int             `gorm:"not null;default:0" json:"trigger_count"`
+	LastTriggeredAt *time.Time      `json:"last_triggered_at"`
+	Note            string          `gorm:"size:255" json:"note"`
+	WebhookURL      string          `gorm:"size:500" json:"webhook_url"`
+	WebhookSecret   string          `gorm:"size:64" json:"-"` // HMAC key for webhook signatures
+}
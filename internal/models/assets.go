@@ -5,10 +5,15 @@ import "time"
 // ==========================
 // All Coins List
 // ==========================
+
+// Coin is an entry of the coin catalog, copied from the price provider's coin list on
+// every refresh. Symbols are stored in lower case.
 type Coin struct {
-	ID     string `gorm:"primaryKey;size:100" json:"id"`
-	Symbol string `gorm:"size:50" json:"symbol"`
-	Name   string `gorm:"size:100" json:"name"`
+	ID            string    `gorm:"primaryKey;size:100" json:"id"`
+	Symbol        string    `gorm:"size:50;index" json:"symbol"`
+	Name          string    `gorm:"size:255" json:"name"`
+	MarketCapRank int       `gorm:"not null;default:0" json:"market_cap_rank"` // 0 below the ranked top coins
+	UpdatedAt     time.Time `gorm:"index" json:"updated_at"`                   // last refresh that listed the coin
 }
 
 // ==========================
@@ -0,0 +1,83 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Backtest run statuses
+const (
+	BacktestStatusQueued    = "queued"
+	BacktestStatusRunning   = "running"
+	BacktestStatusCompleted = "completed"
+	BacktestStatusFailed    = "failed"
+)
+
+// Backtest strategies
+const (
+	StrategyBuyAndHold = "buy_and_hold"
+	StrategyDCA        = "dca"
+	StrategySMACross   = "sma_cross"
+)
+
+// BacktestRun is one asynchronous replay of a strategy over stored candles. The
+// configuration is kept as submitted; the summary columns are filled in when the
+// run completes.
+type BacktestRun struct {
+	gorm.Model
+	UserID uint   `gorm:"not null;index" json:"user_id"`
+	Status string `gorm:"size:20;not null;index" json:"status"` // see BacktestStatus* constants
+	Error  string `gorm:"type:text" json:"error,omitempty"`
+
+	// Configuration
+	Strategy        string          `gorm:"size:30;not null" json:"strategy"`   // see Strategy* constants
+	CoinIDs         string          `gorm:"type:text;not null" json:"coin_ids"` // comma-separated
+	Interval        string          `gorm:"size:3;not null" json:"interval"`
+	From            time.Time       `gorm:"not null" json:"from"`
+	To              time.Time       `gorm:"not null" json:"to"`
+	StartingBalance decimal.Decimal `gorm:"type:numeric(36,18);not null" json:"starting_balance"`
+	FeeSchedule     string          `gorm:"size:20;not null" json:"fee_schedule"`
+	FastPeriod      int             `gorm:"not null;default:0" json:"fast_period"`                // sma_cross
+	SlowPeriod      int             `gorm:"not null;default:0" json:"slow_period"`                // sma_cross
+	Amount          decimal.Decimal `gorm:"type:numeric(36,18);not null;default:0" json:"amount"` // dca: USD per coin per purchase
+	Every           int             `gorm:"not null;default:0" json:"every"`                      // dca: candles between purchases
+
+	// Results
+	StartedAt   *time.Time      `json:"started_at"`
+	FinishedAt  *time.Time      `json:"finished_at"`
+	EndValue    decimal.Decimal `gorm:"type:numeric(36,18);not null;default:0" json:"end_value"`
+	RealizedPnL decimal.Decimal `gorm:"type:numeric(36,18);not null;default:0" json:"realized_pnl"`
+	FeesPaid    decimal.Decimal `gorm:"type:numeric(36,18);not null;default:0" json:"fees_paid"`
+	TradeCount  int             `gorm:"not null;default:0" json:"trade_count"`
+	WinRate     float64         `gorm:"not null;default:0" json:"win_rate"`
+	TotalReturn float64         `gorm:"not null;default:0" json:"total_return"`
+	MaxDrawdown float64         `gorm:"not null;default:0" json:"max_drawdown"`
+	Volatility  float64         `gorm:"not null;default:0" json:"volatility"`
+	Sharpe      float64         `gorm:"not null;default:0" json:"sharpe"`
+	Sortino     float64         `gorm:"not null;default:0" json:"sortino"`
+}
+
+// BacktestTrade is one simulated fill of a backtest run
+type BacktestTrade struct {
+	ID          uint            `gorm:"primaryKey" json:"id"`
+	RunID       uint            `gorm:"not null;index" json:"run_id"`
+	ExecutedAt  time.Time       `gorm:"not null" json:"executed_at"`
+	CoinID      string          `gorm:"size:100;not null" json:"coin_id"`
+	Side        string          `gorm:"size:10;not null" json:"side"`
+	Quantity    decimal.Decimal `gorm:"type:numeric(36,18);not null" json:"quantity"`
+	Price       decimal.Decimal `gorm:"type:numeric(36,18);not null" json:"price"`
+	Fee         decimal.Decimal `gorm:"type:numeric(36,18);not null" json:"fee"`
+	RealizedPnL decimal.Decimal `gorm:"type:numeric(36,18);not null;default:0" json:"realized_pnl"` // sells only, FIFO
+}
+
+// BacktestEquityPoint is the simulated portfolio value at the close of one candle
+type BacktestEquityPoint struct {
+	ID            uint            `gorm:"primaryKey" json:"id"`
+	RunID         uint            `gorm:"not null;index" json:"run_id"`
+	At            time.Time       `gorm:"not null" json:"at"`
+	Cash          decimal.Decimal `gorm:"type:numeric(36,18);not null" json:"cash"`
+	HoldingsValue decimal.Decimal `gorm:"type:numeric(36,18);not null" json:"holdings_value"`
+	TotalValue    decimal.Decimal `gorm:"type:numeric(36,18);not null" json:"total_value"`
+}
@@ -1,10 +1,17 @@
 package models
 
-import "gorm.io/gorm"
+import (
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
 
+// Balance is a portfolio's cash in one quote currency; Asset is one of the Currency* codes
 type Balance struct {
 	gorm.Model
-	UserID uint    `gorm:"index;not null"`
-	Asset  string  `gorm:"size:10;not null"`
-	Amount float64 `gorm:"not null"`
+	UserID      uint            `gorm:"not null;index"`
+	PortfolioID uint            `gorm:"not null;uniqueIndex:idx_balances_portfolio_asset"`
+	Asset       string          `gorm:"size:10;not null;uniqueIndex:idx_balances_portfolio_asset"`
+	Amount      decimal.Decimal `gorm:"type:numeric(36,18);not null"`
+	// Reserved is the part of Amount held by open buy limit orders
+	Reserved decimal.Decimal `gorm:"type:numeric(36,18);not null;default:0"`
 }
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Bot types
+const (
+	BotTypeDCA  = "dca"
+	BotTypeGrid = "grid"
+	BotTypeDip  = "dip"
+)
+
+// Bot statuses. Bots are created paused; stopped is final.
+const (
+	BotStatusRunning = "running"
+	BotStatusPaused  = "paused"
+	BotStatusStopped = "stopped"
+)
+
+// Bot is a user-defined trading rule the bot runner evaluates on every tick and acts
+// on with market orders through the trade service
+type Bot struct {
+	gorm.Model
+	UserID      uint   `gorm:"not null;index" json:"user_id"`
+	PortfolioID uint   `gorm:"not null;index" json:"portfolio_id"` // portfolio the bot trades for
+	Name        string `gorm:"size:100;not null" json:"name"`
+	Type        string `gorm:"size:10;not null" json:"type"`         // see BotType* constants
+	Status      string `gorm:"size:10;not null;index" json:"status"` // see BotStatus* constants
+	CoinID      string `gorm:"size:100;not null" json:"coin_id"`
+	Symbol      string `gorm:"size:20;not null" json:"symbol"`
+
+	// Amount is the USD each buy spends, fee included. Budget caps the USD the bot
+	// has deployed, its buys net of its sales; buys that would exceed it are skipped.
+	Amount   decimal.Decimal `gorm:"type:numeric(36,18);not null" json:"amount"`
+	Budget   decimal.Decimal `gorm:"type:numeric(36,18);not null" json:"budget"`
+	Deployed decimal.Decimal `gorm:"type:numeric(36,18);not null;default:0" json:"deployed"`
+	Position decimal.Decimal `gorm:"type:numeric(36,18);not null;default:0" json:"position"` // coins bought by the bot and not yet sold
+
+	// dca
+	Schedule  string     `gorm:"size:100" json:"schedule"` // cron expression, evaluated in UTC
+	NextRunAt *time.Time `json:"next_run_at"`
+
+	// grid: Levels evenly spaced lines from GridLower to GridUpper. Each line crossed
+	// downwards buys Amount; each line crossed upwards sells one open lot.
+	GridLower  decimal.Decimal `gorm:"type:numeric(36,18);not null;default:0" json:"grid_lower"`
+	GridUpper  decimal.Decimal `gorm:"type:numeric(36,18);not null;default:0" json:"grid_upper"`
+	GridLevels int             `gorm:"not null;default:0" json:"grid_levels"`
+	GridBand   int             `gorm:"not null;default:-1" json:"grid_band"` // lines at or below the last seen price; -1 until first seen
+	GridLots   int             `gorm:"not null;default:0" json:"grid_lots"`  // buys not yet matched by a sell
+
+	// dip: buy Amount when the 24h change is at or below -DipPercent, at most once per cooldown
+	DipPercent      decimal.Decimal `gorm:"type:numeric(36,18);not null;default:0" json:"dip_percent"`
+	CooldownMinutes int             `gorm:"not null;default:0" json:"cooldown_minutes"`
+
+	LastTradeAt *time.Time `json:"last_trade_at"`
+	LastError   string     `gorm:"type:text" json:"last_error"`
+}
+
+// BotTrade links a bot to an order it placed, with the reason it placed it
+type BotTrade struct {
+	ID        uint            `gorm:"primaryKey" json:"id"`
+	CreatedAt time.Time       `json:"created_at"`
+	BotID     uint            `gorm:"not null;index" json:"bot_id"`
+	TradeID   uint            `gorm:"not null" json:"trade_id"`
+	Side      string          `gorm:"size:10;not null" json:"side"`
+	Quantity  decimal.Decimal `gorm:"type:numeric(36,18);not null" json:"quantity"`
+	Price     decimal.Decimal `gorm:"type:numeric(36,18);not null" json:"price"`
+	Fee       decimal.Decimal `gorm:"type:numeric(36,18);not null" json:"fee"`
+	Reason    string          `gorm:"size:255" json:"reason"`
+}
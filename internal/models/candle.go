@@ -0,0 +1,35 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Candle intervals
+const (
+	CandleInterval1m = "1m"
+	CandleInterval1h = "1h"
+	CandleInterval1d = "1d"
+)
+
+// CandleIntervals maps each supported interval to its length
+var CandleIntervals = map[string]time.Duration{
+	CandleInterval1m: time.Minute,
+	CandleInterval1h: time.Hour,
+	CandleInterval1d: 24 * time.Hour,
+}
+
+// Candle is one OHLCV bar of a coin's USD price. OpenTime is aligned to the
+// interval in UTC, so daily bars start at midnight UTC.
+type Candle struct {
+	gorm.Model
+	CoinID   string    `gorm:"size:100;not null;uniqueIndex:idx_candles_coin_interval_open" json:"coin_id"`
+	Interval string    `gorm:"size:3;not null;uniqueIndex:idx_candles_coin_interval_open" json:"interval"`
+	OpenTime time.Time `gorm:"not null;uniqueIndex:idx_candles_coin_interval_open" json:"open_time"`
+	Open     float64   `gorm:"not null" json:"open"`
+	High     float64   `gorm:"not null" json:"high"`
+	Low      float64   `gorm:"not null" json:"low"`
+	Close    float64   `gorm:"not null" json:"close"`
+	Volume   float64   `gorm:"not null;default:0" json:"volume"` // rolling 24h USD volume at the close, as reported by the source
+}
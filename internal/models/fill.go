@@ -0,0 +1,25 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Fill is one execution of an Order: the quantity that changed hands, at what price
+// and fee, and the USD exchange rate of the quote currency at that moment. Fills are
+// what moves balances and holdings, so P&L and tax reports replay them.
+type Fill struct {
+	gorm.Model
+	OrderID       uint            `gorm:"not null;index" json:"order_id"`
+	UserID        uint            `gorm:"not null;index" json:"user_id"`
+	PortfolioID   uint            `gorm:"not null;index" json:"portfolio_id"`
+	CoinID        string          `gorm:"size:100;not null;index" json:"coin_id"`
+	Symbol        string          `gorm:"size:20;not null" json:"symbol"`
+	Side          string          `gorm:"size:10;not null" json:"side"` // buy or sell
+	Quantity      decimal.Decimal `gorm:"type:numeric(36,18);not null" json:"quantity"`
+	Price         decimal.Decimal `gorm:"type:numeric(36,18);not null" json:"price"`
+	Fee           decimal.Decimal `gorm:"type:numeric(36,18);not null;default:0" json:"fee"` // in QuoteCurrency
+	Liquidity     string          `gorm:"size:5;not null;default:taker" json:"liquidity"`    // maker or taker
+	QuoteCurrency string          `gorm:"size:10;not null;default:USD" json:"quote_currency"`
+	FXRate        decimal.Decimal `gorm:"type:numeric(36,18);not null;default:1" json:"fx_rate"` // units of QuoteCurrency one USD bought when the fill executed
+}
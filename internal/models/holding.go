@@ -0,0 +1,18 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Holding is the quantity of a single coin owned by a portfolio
+type Holding struct {
+	gorm.Model
+	UserID      uint            `gorm:"not null;index" json:"user_id"`
+	PortfolioID uint            `gorm:"not null;uniqueIndex:idx_holdings_portfolio_coin" json:"portfolio_id"`
+	CoinID      string          `gorm:"size:100;not null;uniqueIndex:idx_holdings_portfolio_coin" json:"coin_id"`
+	Symbol      string          `gorm:"size:20;not null" json:"symbol"`
+	Quantity    decimal.Decimal `gorm:"type:numeric(36,18);not null" json:"quantity"`
+	// Reserved is the part of Quantity held by open sell limit orders
+	Reserved decimal.Decimal `gorm:"type:numeric(36,18);not null;default:0" json:"reserved"`
+}
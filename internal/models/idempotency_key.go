@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// IdempotencyKey records the first response to a mutation sent with an Idempotency-Key
+// header, so a retry with the same key replays it instead of running again. A zero
+// StatusCode marks a request that is still being handled.
+type IdempotencyKey struct {
+	gorm.Model
+	UserID      uint      `gorm:"not null;uniqueIndex:idx_idempotency_keys_user_key" json:"user_id"`
+	Key         string    `gorm:"size:255;not null;uniqueIndex:idx_idempotency_keys_user_key" json:"key"`
+	RequestHash string    `gorm:"size:64;not null" json:"request_hash"` // SHA-256 of method, path, query and body
+	StatusCode  int       `gorm:"not null;default:0" json:"status_code"`
+	ContentType string    `gorm:"size:100" json:"content_type"`
+	Body        []byte    `json:"body"`
+	ExpiresAt   time.Time `gorm:"not null;index" json:"expires_at"`
+}
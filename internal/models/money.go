@@ -0,0 +1,107 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// Money, price and quantity columns are numeric(36,18) so every value round-trips
+// exactly. Services round amounts to the scales below where they are computed, so
+// stored values never carry more digits than the asset supports and repeated fills
+// can't accumulate drift.
+const (
+	CashScale     int32 = 2 // USD (and other fiat) balances, costs, proceeds and fees
+	PriceScale    int32 = 8 // per-unit coin prices
+	QuantityScale int32 = 8 // coin quantities unless the coin is listed in coinScales
+)
+
+// coinScales overrides QuantityScale for coins that trade in coarser units
+var coinScales = map[string]int32{
+	"tether":   6,
+	"usd-coin": 6,
+	"ripple":   6,
+	"cardano":  6,
+	"tron":     6,
+}
+
+// CoinScale returns the number of decimal places a quantity of coinID is tracked in
+func CoinScale(coinID string) int32 {
+	if scale, ok := coinScales[coinID]; ok {
+		return scale
+	}
+	return QuantityScale
+}
+
+// RoundCash rounds a USD amount half-to-even to CashScale
+func RoundCash(amount decimal.Decimal) decimal.Decimal {
+	return amount.RoundBank(CashScale)
+}
+
+// RoundPrice rounds a per-unit price half-to-even to PriceScale
+func RoundPrice(price decimal.Decimal) decimal.Decimal {
+	return price.RoundBank(PriceScale)
+}
+
+// RoundQuantity truncates quantity to the precision of coinID, so rounding never
+// fills or sells more than was asked for
+func RoundQuantity(coinID string, quantity decimal.Decimal) decimal.Decimal {
+	return quantity.Truncate(CoinScale(coinID))
+}
+
+// Quote currencies cash balances can be held, and orders settled, in
+const (
+	CurrencyUSD = "USD"
+	CurrencyEUR = "EUR"
+	CurrencyGBP = "GBP"
+	CurrencyBTC = "BTC"
+)
+
+// currencyScales is the number of decimal places each quote currency is tracked in
+var currencyScales = map[string]int32{
+	CurrencyUSD: CashScale,
+	CurrencyEUR: CashScale,
+	CurrencyGBP: CashScale,
+	CurrencyBTC: 8,
+}
+
+// QuoteCurrencies lists the supported quote currencies, USD first
+func QuoteCurrencies() []string {
+	return []string{CurrencyUSD, CurrencyEUR, CurrencyGBP, CurrencyBTC}
+}
+
+// ParseCurrency normalises a currency code such as "eur" to its upper-case form.
+// An empty code means USD; ok is false for unsupported currencies.
+func ParseCurrency(code string) (string, bool) {
+	if code == "" {
+		return CurrencyUSD, true
+	}
+	code = strings.ToUpper(strings.TrimSpace(code))
+	_, ok := currencyScales[code]
+	return code, ok
+}
+
+// CurrencyScale returns the number of decimal places an amount of currency is tracked in
+func CurrencyScale(currency string) int32 {
+	if scale, ok := currencyScales[currency]; ok {
+		return scale
+	}
+	return CashScale
+}
+
+// RoundAmount rounds an amount of currency half-to-even to its scale, the way RoundCash does for USD
+func RoundAmount(currency string, amount decimal.Decimal) decimal.Decimal {
+	return amount.RoundBank(CurrencyScale(currency))
+}
+
+// RoundCost rounds what a buy costs in currency up to its scale, so coins are never
+// bought for less than they are worth, however small the order
+func RoundCost(currency string, amount decimal.Decimal) decimal.Decimal {
+	return amount.RoundUp(CurrencyScale(currency))
+}
+
+// RoundProceeds rounds what a sale brings in in currency down to its scale, so a sale
+// never pays out more than the coins are worth
+func RoundProceeds(currency string, amount decimal.Decimal) decimal.Decimal {
+	return amount.RoundDown(CurrencyScale(currency))
+}
@@ -0,0 +1,100 @@
+package models
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestRoundAmount(t *testing.T) {
+	tests := []struct {
+		currency, amount, want string
+	}{
+		{CurrencyUSD, "1.005", "1"},
+		{CurrencyUSD, "1.015", "1.02"},
+		{CurrencyUSD, "1.0151", "1.02"},
+		{CurrencyUSD, "-1.005", "-1"},
+		{CurrencyUSD, "-1.015", "-1.02"},
+		{CurrencyEUR, "2.345", "2.34"},
+		{CurrencyGBP, "2.355", "2.36"},
+		{CurrencyBTC, "0.000000125", "0.00000012"},
+		{CurrencyBTC, "0.000000135", "0.00000014"},
+		{CurrencyBTC, "1.23456789", "1.23456789"},
+		{"XYZ", "1.005", "1"}, // unknown currencies round like fiat
+		{CurrencyUSD, "0", "0"},
+	}
+	for _, tt := range tests {
+		got := RoundAmount(tt.currency, decimal.RequireFromString(tt.amount))
+		if !got.Equal(decimal.RequireFromString(tt.want)) {
+			t.Errorf("RoundAmount(%s, %s) = %s, want %s", tt.currency, tt.amount, got, tt.want)
+		}
+	}
+}
+
+// Rounding is stable, odd in sign, and never moves an amount by more than half a unit
+// of the currency's last digit
+func TestRoundAmountProperties(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	for _, currency := range QuoteCurrencies() {
+		scale := CurrencyScale(currency)
+		half := decimal.New(5, -scale-1)
+		for i := 0; i < 2000; i++ {
+			amount := decimal.New(rng.Int63n(1e15), -int32(rng.Intn(12)))
+			rounded := RoundAmount(currency, amount)
+
+			if rounded.Exponent() < -scale {
+				t.Fatalf("RoundAmount(%s, %s) = %s has more than %d decimals", currency, amount, rounded, scale)
+			}
+			if again := RoundAmount(currency, rounded); !again.Equal(rounded) {
+				t.Fatalf("RoundAmount(%s) is not idempotent: %s then %s", currency, rounded, again)
+			}
+			if neg := RoundAmount(currency, amount.Neg()); !neg.Equal(rounded.Neg()) {
+				t.Fatalf("RoundAmount(%s, -%s) = %s, want %s", currency, amount, neg, rounded.Neg())
+			}
+			if amount.Sub(rounded).Abs().GreaterThan(half) {
+				t.Fatalf("RoundAmount(%s, %s) = %s is off by more than %s", currency, amount, rounded, half)
+			}
+		}
+	}
+}
+
+func TestRoundCostAndProceeds(t *testing.T) {
+	tests := []struct {
+		currency, amount, cost, proceeds string
+	}{
+		{CurrencyUSD, "1.001", "1.01", "1"},
+		{CurrencyUSD, "1.005", "1.01", "1"},
+		{CurrencyUSD, "1.01", "1.01", "1.01"},
+		{CurrencyUSD, "0.0000001", "0.01", "0"},
+		{CurrencyEUR, "2.349", "2.35", "2.34"},
+		{CurrencyBTC, "0.000000121", "0.00000013", "0.00000012"},
+		{CurrencyUSD, "0", "0", "0"},
+	}
+	for _, tt := range tests {
+		amount := decimal.RequireFromString(tt.amount)
+		if got := RoundCost(tt.currency, amount); !got.Equal(decimal.RequireFromString(tt.cost)) {
+			t.Errorf("RoundCost(%s, %s) = %s, want %s", tt.currency, tt.amount, got, tt.cost)
+		}
+		if got := RoundProceeds(tt.currency, amount); !got.Equal(decimal.RequireFromString(tt.proceeds)) {
+			t.Errorf("RoundProceeds(%s, %s) = %s, want %s", tt.currency, tt.amount, got, tt.proceeds)
+		}
+	}
+}
+
+func TestRoundQuantity(t *testing.T) {
+	tests := []struct {
+		coinID, quantity, want string
+	}{
+		{"bitcoin", "0.123456789", "0.12345678"},
+		{"bitcoin", "0.999999999", "0.99999999"},
+		{"tether", "10.1234567", "10.123456"},
+		{"ripple", "3.0000009", "3"},
+	}
+	for _, tt := range tests {
+		got := RoundQuantity(tt.coinID, decimal.RequireFromString(tt.quantity))
+		if !got.Equal(decimal.RequireFromString(tt.want)) {
+			t.Errorf("RoundQuantity(%s, %s) = %s, want %s", tt.coinID, tt.quantity, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Order is what a user asked to trade: a market order, a resting limit or a
+// conditional order. Its executions are recorded as Fills; FilledQuantity, Fee and
+// AveragePrice sum them up.
+type Order struct {
+	gorm.Model
+	UserID           uint            `gorm:"not null;index" json:"user_id"`      // user placing the order
+	PortfolioID      uint            `gorm:"not null;index" json:"portfolio_id"` // portfolio the order trades for
+	CoinID           string          `gorm:"size:100;not null;index" json:"coin_id"`
+	Symbol           string          `gorm:"size:20;not null;index" json:"symbol"` // trading pair
+	Side             string          `gorm:"size:10;not null" json:"side"`         // buy or sell
+	Quantity         decimal.Decimal `gorm:"type:numeric(36,18);not null" json:"quantity"`
+	Price            decimal.Decimal `gorm:"type:numeric(36,18);not null" json:"price"`                   // limit price; the execution price of market orders and the trigger price of triggered stops
+	AveragePrice     decimal.Decimal `gorm:"type:numeric(36,18);not null;default:0" json:"average_price"` // quantity-weighted price of the fills
+	Fee              decimal.Decimal `gorm:"type:numeric(36,18);not null;default:0" json:"fee"`           // fees of all fills, in QuoteCurrency
+	Type             string          `gorm:"size:20;not null" json:"type"`                                // see OrderType* constants
+	Status           string          `gorm:"size:20;not null" json:"status"`                              // see OrderStatus* constants
+	TimeInForce      string          `gorm:"size:3;not null;default:GTC" json:"time_in_force"`
+	ExpiresAt        *time.Time      `gorm:"index" json:"expires_at"` // only for GTD orders
+	FilledQuantity   decimal.Decimal `gorm:"type:numeric(36,18);not null;default:0" json:"filled_quantity"`
+	ReservedAmount   decimal.Decimal `gorm:"type:numeric(36,18);not null;default:0" json:"reserved_amount"`   // QuoteCurrency held for an open buy limit
+	ReservedQuantity decimal.Decimal `gorm:"type:numeric(36,18);not null;default:0" json:"reserved_quantity"` // coins held for an open sell limit
+	QuoteCurrency    string          `gorm:"size:10;not null;default:USD" json:"quote_currency"`              // currency the order is priced and settled in
+
+	// Conditional orders
+	StopPrice       decimal.Decimal `gorm:"type:numeric(36,18);not null;default:0" json:"stop_price"` // trigger price; recomputed for trailing stops
+	TrailingPercent decimal.Decimal `gorm:"type:numeric(36,18);not null;default:0" json:"trailing_percent"`
+	TrailingOffset  decimal.Decimal `gorm:"type:numeric(36,18);not null;default:0" json:"trailing_offset"`
+	WaterMark       decimal.Decimal `gorm:"type:numeric(36,18);not null;default:0" json:"water_mark"` // best price seen by a trailing stop: highest for sells, lowest for buys
+	TriggeredAt     *time.Time      `json:"triggered_at"`
+	OCOGroupID      string          `gorm:"size:36;index" json:"oco_group_id"` // legs of a one-cancels-other bracket share this
+}
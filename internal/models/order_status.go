@@ -0,0 +1,56 @@
+package models
+
+// Order lifecycle states
+const (
+	OrderStatusOpen            = "open"
+	OrderStatusPartiallyFilled = "partially_filled"
+	OrderStatusFilled          = "filled"
+	OrderStatusCancelled       = "cancelled"
+	OrderStatusExpired         = "expired"
+	OrderStatusRejected        = "rejected"
+)
+
+// Time-in-force policies for limit orders
+const (
+	TimeInForceGTC = "GTC" // good till cancelled
+	TimeInForceIOC = "IOC" // immediate or cancel: fill what is possible now, expire the rest
+	TimeInForceFOK = "FOK" // fill or kill: fill the whole quantity now or expire
+	TimeInForceGTD = "GTD" // good till date: expires at ExpiresAt
+)
+
+// orderTransitions lists the states reachable from each non-terminal state
+var orderTransitions = map[string][]string{
+	OrderStatusOpen: {
+		OrderStatusPartiallyFilled, OrderStatusFilled, OrderStatusCancelled,
+		OrderStatusExpired, OrderStatusRejected,
+	},
+	OrderStatusPartiallyFilled: {
+		OrderStatusPartiallyFilled, OrderStatusFilled, OrderStatusCancelled,
+		OrderStatusExpired, OrderStatusRejected,
+	},
+}
+
+// CanTransitionOrder reports whether an order may move from one status to another
+func CanTransitionOrder(from, to string) bool {
+	for _, s := range orderTransitions[from] {
+		if s == to {
+			return true
+		}
+	}
+	return false
+}
+
+// IsOrderActive reports whether an order can still fill
+func IsOrderActive(status string) bool {
+	return status == OrderStatusOpen || status == OrderStatusPartiallyFilled
+}
+
+// Order types
+const (
+	OrderTypeMarket       = "market"
+	OrderTypeLimit        = "limit"
+	OrderTypeStopMarket   = "stop_market"   // market order once price crosses StopPrice against the position
+	OrderTypeStopLimit    = "stop_limit"    // limit order at Price once StopPrice is crossed
+	OrderTypeTakeProfit   = "take_profit"   // market order once price crosses StopPrice in favour of the position
+	OrderTypeTrailingStop = "trailing_stop" // stop that follows WaterMark by TrailingPercent or TrailingOffset
+)
@@ -0,0 +1,21 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// DefaultPortfolioName names the portfolio every user starts with
+const DefaultPortfolioName = "Default"
+
+// Portfolio is a paper trading sub-account. It owns its cash balances, holdings,
+// orders, snapshots and performance, so one user can run several experiments side
+// by side. Archived portfolios stay readable but can no longer trade.
+type Portfolio struct {
+	gorm.Model
+	UserID     uint       `gorm:"not null;uniqueIndex:idx_portfolios_user_name" json:"user_id"`
+	Name       string     `gorm:"size:100;not null;uniqueIndex:idx_portfolios_user_name" json:"name"`
+	IsDefault  bool       `gorm:"not null;default:false" json:"is_default"` // used when a request doesn't pick a portfolio
+	ArchivedAt *time.Time `json:"archived_at"`
+}
@@ -0,0 +1,20 @@
+package models
+
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// PortfolioSnapshot is a portfolio's total value at a point in time; the series of
+// snapshots forms the equity curve
+type PortfolioSnapshot struct {
+	gorm.Model
+	UserID        uint            `gorm:"not null;index" json:"user_id"`
+	PortfolioID   uint            `gorm:"not null;index:idx_snapshots_portfolio_taken" json:"portfolio_id"`
+	TakenAt       time.Time       `gorm:"not null;index:idx_snapshots_portfolio_taken" json:"taken_at"`
+	Cash          decimal.Decimal `gorm:"type:numeric(36,18);not null" json:"cash"`
+	HoldingsValue decimal.Decimal `gorm:"type:numeric(36,18);not null" json:"holdings_value"`
+	TotalValue    decimal.Decimal `gorm:"type:numeric(36,18);not null" json:"total_value"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Risk limit modes
+const (
+	RiskModeReject = "reject" // orders that break a limit are refused
+	RiskModeWarn   = "warn"   // orders go through with the broken limits listed as warnings
+)
+
+// Risk rules, named after the limit they enforce
+const (
+	RiskRuleMaxPositionValue = "max_position_value"
+	RiskRuleMaxOrderNotional = "max_order_notional"
+	RiskRuleMaxDailyLoss     = "max_daily_loss"
+	RiskRuleMaxOpenOrders    = "max_open_orders"
+	RiskRuleMaxConcentration = "max_concentration"
+)
+
+// RiskLimit holds the pre-trade limits of one portfolio. Money limits are in USD and
+// a zero limit is not enforced, so a fresh row checks nothing.
+type RiskLimit struct {
+	gorm.Model
+	UserID      uint `gorm:"not null;index" json:"user_id"`
+	PortfolioID uint `gorm:"not null;uniqueIndex" json:"portfolio_id"`
+
+	MaxPositionValue decimal.Decimal `gorm:"type:numeric(36,18);not null;default:0" json:"max_position_value"` // value held in one coin after a buy
+	MaxOrderNotional decimal.Decimal `gorm:"type:numeric(36,18);not null;default:0" json:"max_order_notional"` // value of a single order
+	// MaxDailyLoss bounds the fall in portfolio value since the UTC day began. The day's
+	// start is read from equity snapshots: the first of the day, else the last one before
+	// midnight, which misses whatever moved between it and midnight. A portfolio with no
+	// snapshots yet is snapshotted at its first checked buy, so losses before that buy
+	// don't count.
+	MaxDailyLoss     decimal.Decimal `gorm:"type:numeric(36,18);not null;default:0" json:"max_daily_loss"`
+	MaxOpenOrders    int             `gorm:"not null;default:0" json:"max_open_orders"`                     // resting limit and conditional orders
+	MaxConcentration decimal.Decimal `gorm:"type:numeric(7,4);not null;default:0" json:"max_concentration"` // percent of portfolio value in one coin after a buy
+	Mode             string          `gorm:"size:10;not null;default:reject" json:"mode"`                   // see RiskMode* constants
+}
@@ -4,6 +4,9 @@ import "gorm.io/gorm"
 
 type Setting struct {
 	gorm.Model
-	UserID   uint   `gorm:"not null;uniqueIndex"` // each user has one setting row
-	APIKey   string `gorm:"size:255"`
+	UserID      uint   `gorm:"not null;uniqueIndex"` // each user has one setting row
+	APIKey      string `gorm:"size:255"`
+	FeeSchedule string `gorm:"size:20"` // execution cost preset; empty means the default
+	// DisplayCurrency is the currency portfolio totals are reported in; empty means USD
+	DisplayCurrency string `gorm:"size:10"`
 }
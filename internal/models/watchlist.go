@@ -0,0 +1,30 @@
+package models
+
+import (
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// Watchlist is a user's named, ordered list of coins. A watchlist with a default
+// alert gives every coin added to it a copy of that alert, which goes away again when
+// the coin is removed.
+type Watchlist struct {
+	gorm.Model
+	UserID         uint            `gorm:"not null;uniqueIndex:idx_watchlists_user_name" json:"user_id"`
+	Name           string          `gorm:"size:100;not null;uniqueIndex:idx_watchlists_user_name" json:"name"`
+	AlertCondition string          `gorm:"size:20" json:"alert_condition"` // empty when there is no default alert
+	AlertThreshold decimal.Decimal `gorm:"type:numeric(36,18);not null;default:0" json:"alert_threshold"`
+	AlertWindow    string          `gorm:"size:20" json:"alert_window"` // percent_move only
+	AlertMode      string          `gorm:"size:10" json:"alert_mode"`
+	AlertCooldown  string          `gorm:"size:20" json:"alert_cooldown"`
+	Items          []WatchlistItem `json:"items"`
+}
+
+// WatchlistItem is a coin on a watchlist. Position orders the coins, starting at 0.
+type WatchlistItem struct {
+	gorm.Model
+	WatchlistID uint   `gorm:"not null;uniqueIndex:idx_watchlist_items_list_coin" json:"watchlist_id"`
+	CoinID      string `gorm:"size:100;not null;uniqueIndex:idx_watchlist_items_list_coin" json:"coin_id"`
+	Position    int    `gorm:"not null" json:"position"`
+	AlertID     *uint  `json:"alert_id"` // the default alert created when the coin was added
+}
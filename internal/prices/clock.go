@@ -0,0 +1,57 @@
+package prices
+
+import (
+	"sync"
+	"time"
+)
+
+// Clock tells offline providers which instant to price
+type Clock interface {
+	Now() time.Time
+}
+
+// SimClock is a controllable clock. It starts at a fixed instant and runs at Speed
+// times wall-clock time; at speed 0 it only moves when Set or Advance is called.
+type SimClock struct {
+	mu       sync.Mutex
+	base     time.Time // simulated time at wallBase
+	wallBase time.Time
+	speed    float64
+}
+
+func NewSimClock(start time.Time, speed float64) *SimClock {
+	return &SimClock{base: start, wallBase: time.Now(), speed: speed}
+}
+
+func (c *SimClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now()
+}
+
+func (c *SimClock) now() time.Time {
+	elapsed := time.Since(c.wallBase)
+	return c.base.Add(time.Duration(float64(elapsed) * c.speed))
+}
+
+// Set jumps the clock to t; it keeps running from there at the current speed
+func (c *SimClock) Set(t time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.base, c.wallBase = t, time.Now()
+}
+
+// Advance moves the clock forward by d
+func (c *SimClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.base, c.wallBase = c.now().Add(d), time.Now()
+}
+
+// SetSpeed changes how fast the clock runs relative to wall-clock time
+func (c *SimClock) SetSpeed(speed float64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.base, c.wallBase = c.now(), time.Now()
+	c.speed = speed
+}
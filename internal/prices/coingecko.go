@@ -0,0 +1,194 @@
+package prices
+
+import (
+	"ares_api/internal/api/dto"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	repository "ares_api/internal/interfaces/repository"
+)
+
+var _ repository.PriceProvider = &CoinGeckoProvider{}
+
+// maxIDsPerRequest is the most ids /coins/markets returns in one page
+const maxIDsPerRequest = 250
+
+// CoinGeckoProvider reads live prices from the CoinGecko API
+type CoinGeckoProvider struct {
+	Client *CoinGeckoClient
+}
+
+func NewCoinGeckoProvider(client *CoinGeckoClient) *CoinGeckoProvider {
+	return &CoinGeckoProvider{Client: client}
+}
+
+// NewCoinGeckoProviderFromEnv initializes a CoinGecko provider from environment
+// variables. COINGECKO_PLAN picks the rate limit of the free (default) or pro plan, and
+// COINGECKO_RATE_LIMIT overrides it in requests per minute.
+func NewCoinGeckoProviderFromEnv() (*CoinGeckoProvider, error) {
+	baseURL := os.Getenv("COINGECKO_BASE_URL")
+	if baseURL == "" {
+		baseURL = "https://api.coingecko.com/api/v3"
+	}
+	plan := os.Getenv("COINGECKO_PLAN")
+	if plan == "" {
+		plan = CoinGeckoPlanFree
+	}
+	rate, ok := coinGeckoPlanRates[plan]
+	if !ok {
+		return nil, fmt.Errorf("unknown COINGECKO_PLAN %q: must be free or pro", plan)
+	}
+	if v := os.Getenv("COINGECKO_RATE_LIMIT"); v != "" {
+		parsed, err := strconv.Atoi(v)
+		if err != nil || parsed <= 0 {
+			return nil, fmt.Errorf("invalid COINGECKO_RATE_LIMIT %q", v)
+		}
+		rate = parsed
+	}
+	return NewCoinGeckoProvider(NewCoinGeckoClient(baseURL, os.Getenv("COINGECKO_API_KEY"), rate)), nil
+}
+
+func (p *CoinGeckoProvider) FetchAllCoins() ([]dto.CoinDTO, error) {
+	var coins []dto.CoinDTO
+	if err := p.Client.Get("/coins/list", nil, &coins); err != nil {
+		return nil, err
+	}
+	return coins, nil
+}
+
+// FetchCoinMarkets fetches every id through /coins/markets, batching up to
+// maxIDsPerRequest ids into each request
+func (p *CoinGeckoProvider) FetchCoinMarkets(ids []string, vsCurrency string) ([]dto.CoinMarketDTO, error) {
+	markets := make([]dto.CoinMarketDTO, 0, len(ids))
+	for start := 0; start < len(ids); start += maxIDsPerRequest {
+		end := start + maxIDsPerRequest
+		if end > len(ids) {
+			end = len(ids)
+		}
+		batch, err := p.fetchMarketsPage(ids[start:end], vsCurrency)
+		if err != nil {
+			return nil, err
+		}
+		markets = append(markets, batch...)
+	}
+	return markets, nil
+}
+
+func (p *CoinGeckoProvider) fetchMarketsPage(ids []string, vsCurrency string) ([]dto.CoinMarketDTO, error) {
+	return p.fetchMarkets(url.Values{
+		"vs_currency": {vsCurrency},
+		"ids":         {strings.Join(ids, ",")},
+		"per_page":    {strconv.Itoa(len(ids))},
+	})
+}
+
+// FetchTopMarkets pages through /coins/markets, largest market cap first
+func (p *CoinGeckoProvider) FetchTopMarkets(limit int) ([]dto.CoinMarketDTO, error) {
+	markets := make([]dto.CoinMarketDTO, 0, limit)
+	for page := 1; len(markets) < limit; page++ {
+		size := limit - len(markets)
+		if size > maxIDsPerRequest {
+			size = maxIDsPerRequest
+		}
+		batch, err := p.fetchMarkets(url.Values{
+			"vs_currency": {"usd"},
+			"per_page":    {strconv.Itoa(size)},
+			"page":        {strconv.Itoa(page)},
+		})
+		if err != nil {
+			return nil, err
+		}
+		markets = append(markets, batch...)
+		if len(batch) < size {
+			break // ran out of coins
+		}
+	}
+	return markets, nil
+}
+
+// fetchMarkets reads one page of /coins/markets, with price changes over 1h, 24h and 7d
+func (p *CoinGeckoProvider) fetchMarkets(query url.Values) ([]dto.CoinMarketDTO, error) {
+	query.Set("order", "market_cap_desc")
+	query.Set("sparkline", "false")
+	query.Set("price_change_percentage", "1h,24h,7d")
+
+	var data []struct {
+		ID          string  `json:"id"`
+		Symbol      string  `json:"symbol"`
+		Name        string  `json:"name"`
+		PriceUSD    float64 `json:"current_price"`
+		MarketCap   float64 `json:"market_cap"`
+		Volume24h   float64 `json:"total_volume"`
+		Change1h    float64 `json:"price_change_percentage_1h_in_currency"`
+		Change24h   float64 `json:"price_change_percentage_24h"`
+		Change7d    float64 `json:"price_change_percentage_7d_in_currency"`
+		LastUpdated string  `json:"last_updated"`
+	}
+	if err := p.Client.Get("/coins/markets", query, &data); err != nil {
+		return nil, err
+	}
+
+	markets := make([]dto.CoinMarketDTO, 0, len(data))
+	for _, d := range data {
+		t, _ := time.Parse(time.RFC3339, d.LastUpdated)
+		markets = append(markets, dto.CoinMarketDTO{
+			ID:          d.ID,
+			Symbol:      d.Symbol,
+			Name:        d.Name,
+			PriceUSD:    d.PriceUSD,
+			MarketCap:   d.MarketCap,
+			Volume24h:   d.Volume24h,
+			Change1h:    d.Change1h,
+			Change24h:   d.Change24h,
+			Change7d:    d.Change7d,
+			LastUpdated: t,
+		})
+	}
+	return markets, nil
+}
+
+// FetchMarketChart reads /coins/{id}/market_chart/range. CoinGecko picks the sample
+// spacing from the span: 5-minutely up to a day, hourly up to 90 days, daily beyond.
+func (p *CoinGeckoProvider) FetchMarketChart(id, vsCurrency string, from, to time.Time) ([]dto.PricePointDTO, error) {
+	query := url.Values{
+		"vs_currency": {vsCurrency},
+		"from":        {strconv.FormatInt(from.Unix(), 10)},
+		"to":          {strconv.FormatInt(to.Unix(), 10)},
+	}
+
+	// Each sample is a [unix milliseconds, value] pair
+	var data struct {
+		Prices       [][2]float64 `json:"prices"`
+		TotalVolumes [][2]float64 `json:"total_volumes"`
+	}
+	if err := p.Client.Get("/coins/"+url.PathEscape(id)+"/market_chart/range", query, &data); err != nil {
+		return nil, err
+	}
+
+	volumes := make(map[int64]float64, len(data.TotalVolumes))
+	for _, v := range data.TotalVolumes {
+		volumes[int64(v[0])] = v[1]
+	}
+	points := make([]dto.PricePointDTO, 0, len(data.Prices))
+	for _, sample := range data.Prices {
+		ms := int64(sample[0])
+		points = append(points, dto.PricePointDTO{
+			Time:   time.UnixMilli(ms).UTC(),
+			Price:  sample[1],
+			Volume: volumes[ms],
+		})
+	}
+	return points, nil
+}
+
+func (p *CoinGeckoProvider) FetchSupportedVSCurrencies() ([]string, error) {
+	var currencies []string
+	if err := p.Client.Get("/simple/supported_vs_currencies", nil, &currencies); err != nil {
+		return nil, err
+	}
+	return currencies, nil
+}
@@ -0,0 +1,367 @@
+package prices
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	service "ares_api/internal/interfaces/service"
+)
+
+// CoinGecko plans and the calls per minute each allows
+const (
+	CoinGeckoPlanFree = "free"
+	CoinGeckoPlanPro  = "pro"
+)
+
+var coinGeckoPlanRates = map[string]int{
+	CoinGeckoPlanFree: 30,
+	CoinGeckoPlanPro:  500,
+}
+
+const (
+	// maxResponseBytes bounds a response body; the full coin list is a few MB
+	maxResponseBytes = 32 << 20
+	// maxStaleResponses bounds how many last good responses are kept for fallback
+	maxStaleResponses = 512
+)
+
+// CoinGeckoClient is the HTTP client every CoinGecko request goes through. It keeps
+// under the plan's rate limit with a token bucket, retries 429s, 5xx and network
+// errors with jittered exponential backoff that honours Retry-After, and stops
+// calling for Cooldown after FailureThreshold requests in a row failed. While it
+// can't get an answer it serves the last good response to the same URL, if that is
+// no older than StaleFor.
+type CoinGeckoClient struct {
+	BaseURL string
+	APIKey  string
+	HTTP    *http.Client
+
+	MaxRetries    int
+	BaseBackoff   time.Duration // first retry waits up to this, doubling on each retry
+	MaxBackoff    time.Duration // longest wait between attempts, Retry-After included
+	MaxQueueDelay time.Duration // longest a request waits for a rate limit token
+	StaleFor      time.Duration
+
+	limiter *tokenBucket
+	breaker *circuitBreaker
+	stale   *staleResponses
+
+	now   func() time.Time
+	sleep func(time.Duration)
+	mu    sync.Mutex // guards rng
+	rng   *rand.Rand
+}
+
+func NewCoinGeckoClient(baseURL, apiKey string, requestsPerMinute int) *CoinGeckoClient {
+	return &CoinGeckoClient{
+		BaseURL:       baseURL,
+		APIKey:        apiKey,
+		HTTP:          &http.Client{Timeout: 30 * time.Second},
+		MaxRetries:    3,
+		BaseBackoff:   500 * time.Millisecond,
+		MaxBackoff:    30 * time.Second,
+		MaxQueueDelay: 30 * time.Second,
+		StaleFor:      15 * time.Minute,
+		limiter:       newTokenBucket(float64(requestsPerMinute)/60, requestsPerMinute/6+1, time.Now),
+		breaker:       &circuitBreaker{FailureThreshold: 5, Cooldown: 30 * time.Second, now: time.Now},
+		stale:         &staleResponses{entries: map[string]staleResponse{}},
+		now:           time.Now,
+		sleep:         time.Sleep,
+		rng:           rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+}
+
+// Get fetches path with query and decodes the JSON response into out
+func (c *CoinGeckoClient) Get(path string, query url.Values, out interface{}) error {
+	endpoint := c.BaseURL + path
+	if len(query) > 0 {
+		endpoint += "?" + query.Encode()
+	}
+
+	body, err := c.fetch(path, endpoint)
+	if err != nil {
+		if !retryable(err) {
+			return err
+		}
+		stale, ok := c.stale.get(endpoint, c.now().Add(-c.StaleFor))
+		if !ok {
+			return err
+		}
+		body = stale
+	}
+
+	if err := json.Unmarshal(body, out); err != nil {
+		return &service.MarketDataError{
+			Kind:       service.ErrMarketDataUnavailable,
+			StatusCode: http.StatusOK,
+			Err:        fmt.Errorf("coingecko %s: decode error: %w", path, err),
+		}
+	}
+	return nil
+}
+
+// fetch returns the body of a successful response, retrying what is worth retrying
+func (c *CoinGeckoClient) fetch(path, endpoint string) ([]byte, error) {
+	if err := c.breaker.allow(); err != nil {
+		return nil, &service.MarketDataError{Kind: service.ErrMarketDataUnavailable, Err: fmt.Errorf("coingecko %s: %w", path, err)}
+	}
+
+	for attempt := 0; ; attempt++ {
+		if wait, ok := c.limiter.reserve(c.MaxQueueDelay); !ok {
+			// Throttled locally; the upstream is fine, so the breaker isn't told
+			c.breaker.release()
+			return nil, &service.MarketDataError{
+				Kind:       service.ErrMarketDataRateLimited,
+				RetryAfter: wait,
+				Err:        fmt.Errorf("coingecko %s: request budget exhausted", path),
+			}
+		} else if wait > 0 {
+			c.sleep(wait)
+		}
+
+		body, err := c.do(path, endpoint)
+		if err == nil {
+			c.breaker.record(true)
+			c.stale.put(endpoint, body, c.now())
+			return body, nil
+		}
+		if !retryable(err) {
+			c.breaker.record(true) // the upstream answered; the request was wrong
+			return nil, err
+		}
+
+		var mdErr *service.MarketDataError
+		errors.As(err, &mdErr)
+		wait := c.backoff(attempt, mdErr.RetryAfter)
+		if attempt >= c.MaxRetries || wait > c.MaxBackoff {
+			c.breaker.record(false)
+			return nil, err
+		}
+		c.sleep(wait)
+	}
+}
+
+// do sends one request and turns anything but a 200 into a *MarketDataError
+func (c *CoinGeckoClient) do(path, endpoint string) ([]byte, error) {
+	req, err := http.NewRequest(http.MethodGet, endpoint, nil)
+	if err != nil {
+		return nil, &service.MarketDataError{Kind: service.ErrMarketDataRejected, Err: err}
+	}
+	req.Header.Set("Accept", "application/json")
+	if c.APIKey != "" {
+		req.Header.Add("X-CoinGecko-API-Key", c.APIKey)
+	}
+
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, &service.MarketDataError{Kind: service.ErrMarketDataUnavailable, Err: fmt.Errorf("coingecko %s: %w", path, err)}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxResponseBytes))
+		if err != nil {
+			return nil, &service.MarketDataError{Kind: service.ErrMarketDataUnavailable, StatusCode: resp.StatusCode, Err: fmt.Errorf("coingecko %s: %w", path, err)}
+		}
+		return body, nil
+	}
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseBytes)) // lets the connection be reused
+
+	mdErr := &service.MarketDataError{
+		StatusCode: resp.StatusCode,
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After"), c.now()),
+		Err:        fmt.Errorf("coingecko %s returned %s", path, resp.Status),
+	}
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests:
+		mdErr.Kind = service.ErrMarketDataRateLimited
+	case resp.StatusCode == http.StatusNotFound:
+		mdErr.Kind = service.ErrMarketDataNotFound
+	case resp.StatusCode >= 500:
+		mdErr.Kind = service.ErrMarketDataUnavailable
+	default:
+		mdErr.Kind = service.ErrMarketDataRejected
+	}
+	return nil, mdErr
+}
+
+// backoff is the wait before retry attempt+1: Retry-After when the upstream sent one,
+// otherwise a random duration up to BaseBackoff * 2^attempt ("full jitter")
+func (c *CoinGeckoClient) backoff(attempt int, retryAfter time.Duration) time.Duration {
+	if retryAfter > 0 {
+		return retryAfter
+	}
+	ceiling := float64(c.BaseBackoff) * math.Pow(2, float64(attempt))
+	if ceiling > float64(c.MaxBackoff) {
+		ceiling = float64(c.MaxBackoff)
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return time.Duration(c.rng.Int63n(int64(ceiling) + 1))
+}
+
+// retryable reports whether a request that failed with err may succeed if sent again
+func retryable(err error) bool {
+	return errors.Is(err, service.ErrMarketDataRateLimited) || errors.Is(err, service.ErrMarketDataUnavailable)
+}
+
+// parseRetryAfter reads a Retry-After header given in seconds or as an HTTP date
+func parseRetryAfter(v string, now time.Time) time.Duration {
+	if v == "" {
+		return 0
+	}
+	if secs, err := strconv.Atoi(v); err == nil && secs > 0 {
+		return time.Duration(secs) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil && t.After(now) {
+		return t.Sub(now)
+	}
+	return 0
+}
+
+// tokenBucket refills rate tokens per second up to burst. A request that can't take
+// a token right away reserves the next one and waits for it.
+type tokenBucket struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+	now    func() time.Time
+}
+
+func newTokenBucket(rate float64, burst int, now func() time.Time) *tokenBucket {
+	return &tokenBucket{rate: rate, burst: float64(burst), tokens: float64(burst), last: now(), now: now}
+}
+
+// reserve takes a token and returns how long to wait before using it. When that would
+// be longer than maxWait nothing is taken, ok is false and wait says when to come back.
+func (b *tokenBucket) reserve(maxWait time.Duration) (wait time.Duration, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := b.now()
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return 0, true
+	}
+	wait = time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+	if wait > maxWait {
+		return wait, false
+	}
+	b.tokens-- // goes negative: the callers queued behind wait their turn
+	return wait, true
+}
+
+// Circuit breaker states
+const (
+	circuitClosed   = "closed"
+	circuitOpen     = "open"
+	circuitHalfOpen = "half_open"
+)
+
+var errCircuitOpen = errors.New("circuit open after repeated failures")
+
+// circuitBreaker opens after FailureThreshold failed requests in a row and rejects
+// requests for Cooldown. Then a single probe is let through: it closes the circuit if
+// it succeeds and opens it again if it fails.
+type circuitBreaker struct {
+	FailureThreshold int
+	Cooldown         time.Duration
+
+	mu       sync.Mutex
+	state    string
+	failures int
+	openedAt time.Time
+	now      func() time.Time
+}
+
+func (b *circuitBreaker) allow() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state {
+	case circuitOpen:
+		if b.now().Sub(b.openedAt) < b.Cooldown {
+			return errCircuitOpen
+		}
+		b.state = circuitHalfOpen
+		return nil
+	case circuitHalfOpen:
+		return errCircuitOpen // the probe is still out
+	default:
+		return nil
+	}
+}
+
+func (b *circuitBreaker) record(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if success {
+		b.state, b.failures = circuitClosed, 0
+		return
+	}
+	b.failures++
+	if b.state == circuitHalfOpen || b.failures >= b.FailureThreshold {
+		b.state, b.openedAt = circuitOpen, b.now()
+	}
+}
+
+// release hands back a half-open probe that never reached the upstream, so the next
+// request probes instead
+func (b *circuitBreaker) release() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.state == circuitHalfOpen {
+		b.state = circuitOpen
+	}
+}
+
+// staleResponses keeps the last good body of each URL so it can stand in while the
+// upstream is failing. The oldest entry goes when it is full.
+type staleResponses struct {
+	mu      sync.Mutex
+	entries map[string]staleResponse
+}
+
+type staleResponse struct {
+	body      []byte
+	fetchedAt time.Time
+}
+
+func (s *staleResponses) put(endpoint string, body []byte, at time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.entries[endpoint]; !ok && len(s.entries) >= maxStaleResponses {
+		oldest := ""
+		for key, e := range s.entries {
+			if oldest == "" || e.fetchedAt.Before(s.entries[oldest].fetchedAt) {
+				oldest = key
+			}
+		}
+		delete(s.entries, oldest)
+	}
+	s.entries[endpoint] = staleResponse{body: body, fetchedAt: at}
+}
+
+// get returns the stored body of endpoint if it was fetched after notBefore
+func (s *staleResponses) get(endpoint string, notBefore time.Time) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e, ok := s.entries[endpoint]
+	if !ok || e.fetchedAt.Before(notBefore) {
+		return nil, false
+	}
+	return e.body, true
+}
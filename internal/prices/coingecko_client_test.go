@@ -0,0 +1,254 @@
+package prices
+
+import (
+	service "ares_api/internal/interfaces/service"
+	"errors"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// testUpstream is a CoinGecko stand-in answering every request with status and body
+type testUpstream struct {
+	*httptest.Server
+	requests   atomic.Int32
+	status     atomic.Int32
+	retryAfter atomic.Value // string
+}
+
+func newTestUpstream(t *testing.T) *testUpstream {
+	u := &testUpstream{}
+	u.status.Store(http.StatusOK)
+	u.retryAfter.Store("")
+	u.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		u.requests.Add(1)
+		if v := u.retryAfter.Load().(string); v != "" {
+			w.Header().Set("Retry-After", v)
+		}
+		w.WriteHeader(int(u.status.Load()))
+		w.Write([]byte(`{"price":42}`))
+	}))
+	t.Cleanup(u.Close)
+	return u
+}
+
+// testClient is a client of url on a simulated clock. Sleeping advances the clock and
+// is recorded instead of blocking.
+type testClient struct {
+	*CoinGeckoClient
+	clock  *SimClock
+	sleeps []time.Duration
+}
+
+func newTestClient(url string) *testClient {
+	clock := NewSimClock(time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC), 0)
+	c := &testClient{CoinGeckoClient: NewCoinGeckoClient(url, "", 600), clock: clock}
+	c.BaseBackoff = time.Second
+	c.now = clock.Now
+	c.sleep = func(d time.Duration) {
+		c.sleeps = append(c.sleeps, d)
+		clock.Advance(d)
+	}
+	c.rng = rand.New(rand.NewSource(1))
+	c.limiter = newTokenBucket(10, 100, clock.Now)
+	c.breaker.now = clock.Now
+	return c
+}
+
+func (c *testClient) get() (float64, error) {
+	var out struct {
+		Price float64 `json:"price"`
+	}
+	err := c.Get("/simple/price", nil, &out)
+	return out.Price, err
+}
+
+func TestCoinGeckoRetryAfter(t *testing.T) {
+	upstream := newTestUpstream(t)
+	c := newTestClient(upstream.URL)
+
+	// Rate limited once, then answered
+	upstream.status.Store(http.StatusTooManyRequests)
+	upstream.retryAfter.Store("7")
+	c.sleep = func(d time.Duration) {
+		c.sleeps = append(c.sleeps, d)
+		c.clock.Advance(d)
+		upstream.status.Store(http.StatusOK)
+	}
+
+	price, err := c.get()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if price != 42 || upstream.requests.Load() != 2 {
+		t.Errorf("price %v after %d requests, want 42 after 2", price, upstream.requests.Load())
+	}
+	if len(c.sleeps) != 1 || c.sleeps[0] != 7*time.Second {
+		t.Errorf("slept %v, want the 7s Retry-After", c.sleeps)
+	}
+}
+
+func TestCoinGeckoRetryAfterBeyondMaxBackoff(t *testing.T) {
+	upstream := newTestUpstream(t)
+	upstream.status.Store(http.StatusTooManyRequests)
+	upstream.retryAfter.Store("120")
+	c := newTestClient(upstream.URL)
+
+	_, err := c.get()
+	if !errors.Is(err, service.ErrMarketDataRateLimited) {
+		t.Fatalf("err = %v, want rate limited", err)
+	}
+	var mdErr *service.MarketDataError
+	if !errors.As(err, &mdErr) || mdErr.RetryAfter != 2*time.Minute {
+		t.Errorf("err = %#v, want it to carry the 2m Retry-After", err)
+	}
+	if upstream.requests.Load() != 1 || len(c.sleeps) != 0 {
+		t.Errorf("%d requests and sleeps %v, want one request and no waiting", upstream.requests.Load(), c.sleeps)
+	}
+}
+
+func TestCoinGeckoJitteredBackoff(t *testing.T) {
+	upstream := newTestUpstream(t)
+	upstream.status.Store(http.StatusServiceUnavailable)
+	c := newTestClient(upstream.URL)
+
+	_, err := c.get()
+	if !errors.Is(err, service.ErrMarketDataUnavailable) {
+		t.Fatalf("err = %v, want unavailable", err)
+	}
+	if got := upstream.requests.Load(); got != int32(c.MaxRetries+1) {
+		t.Errorf("%d requests, want %d", got, c.MaxRetries+1)
+	}
+	if len(c.sleeps) != c.MaxRetries {
+		t.Fatalf("slept %v, want %d waits", c.sleeps, c.MaxRetries)
+	}
+	ceiling := c.BaseBackoff
+	for i, d := range c.sleeps {
+		if d < 0 || d > ceiling {
+			t.Errorf("wait %d = %v, want within [0, %v]", i, d, ceiling)
+		}
+		ceiling *= 2
+	}
+
+	// Full jitter spreads the waits rather than always taking the ceiling
+	spread := map[time.Duration]bool{}
+	for i := 0; i < 20; i++ {
+		spread[c.backoff(2, 0)] = true
+	}
+	if len(spread) < 10 {
+		t.Errorf("20 backoffs took only %d distinct values", len(spread))
+	}
+	if d := c.backoff(20, 0); d > c.MaxBackoff {
+		t.Errorf("backoff %v is above MaxBackoff %v", d, c.MaxBackoff)
+	}
+}
+
+func TestCoinGeckoCircuitBreaker(t *testing.T) {
+	upstream := newTestUpstream(t)
+	upstream.status.Store(http.StatusBadGateway)
+	c := newTestClient(upstream.URL)
+	c.MaxRetries = 0
+	c.StaleFor = 0
+	c.breaker.FailureThreshold = 3
+
+	for i := 0; i < 3; i++ {
+		if _, err := c.get(); err == nil {
+			t.Fatal("a failing upstream answered")
+		}
+	}
+	if c.breaker.state != circuitOpen {
+		t.Fatalf("state = %q after 3 failures, want open", c.breaker.state)
+	}
+
+	// Open: nothing reaches the upstream until the cooldown is over
+	_, err := c.get()
+	if !errors.Is(err, errCircuitOpen) || !errors.Is(err, service.ErrMarketDataUnavailable) {
+		t.Errorf("err = %v, want an unavailable open circuit", err)
+	}
+	if upstream.requests.Load() != 3 {
+		t.Errorf("%d requests reached the upstream, want 3", upstream.requests.Load())
+	}
+
+	// Half-open: one probe; a failing probe opens the circuit again
+	c.clock.Advance(c.breaker.Cooldown)
+	if _, err := c.get(); err == nil || errors.Is(err, errCircuitOpen) {
+		t.Errorf("probe err = %v, want the upstream's failure", err)
+	}
+	if c.breaker.state != circuitOpen || upstream.requests.Load() != 4 {
+		t.Errorf("state %q after %d requests, want open after the 4th", c.breaker.state, upstream.requests.Load())
+	}
+
+	// Only one probe goes out at a time
+	c.clock.Advance(c.breaker.Cooldown)
+	if err := c.breaker.allow(); err != nil {
+		t.Fatalf("probe refused: %v", err)
+	}
+	if err := c.breaker.allow(); !errors.Is(err, errCircuitOpen) {
+		t.Errorf("second request while probing: err = %v, want an open circuit", err)
+	}
+	c.breaker.release()
+
+	// A successful probe closes the circuit
+	upstream.status.Store(http.StatusOK)
+	if _, err := c.get(); err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if c.breaker.state != circuitClosed || c.breaker.failures != 0 {
+		t.Errorf("state %q with %d failures, want closed with none", c.breaker.state, c.breaker.failures)
+	}
+}
+
+func TestCoinGeckoStaleFallback(t *testing.T) {
+	upstream := newTestUpstream(t)
+	c := newTestClient(upstream.URL)
+	c.MaxRetries = 0
+
+	if _, err := c.get(); err != nil {
+		t.Fatal(err)
+	}
+
+	// The last good answer stands in while the upstream fails
+	upstream.status.Store(http.StatusServiceUnavailable)
+	c.clock.Advance(c.StaleFor - time.Minute)
+	price, err := c.get()
+	if err != nil || price != 42 {
+		t.Errorf("got %v, %v; want the stale 42", price, err)
+	}
+
+	// Errors that aren't the upstream's fault aren't papered over
+	upstream.status.Store(http.StatusNotFound)
+	if _, err := c.get(); !errors.Is(err, service.ErrMarketDataNotFound) {
+		t.Errorf("err = %v, want not found", err)
+	}
+
+	// Too old to serve
+	upstream.status.Store(http.StatusServiceUnavailable)
+	c.clock.Advance(2 * time.Minute)
+	if _, err := c.get(); !errors.Is(err, service.ErrMarketDataUnavailable) {
+		t.Errorf("err = %v, want unavailable once the stale answer expired", err)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2025, 6, 1, 12, 0, 0, 0, time.UTC)
+	tests := []struct {
+		header string
+		want   time.Duration
+	}{
+		{"", 0},
+		{"30", 30 * time.Second},
+		{"0", 0},
+		{"-5", 0},
+		{now.Add(90 * time.Second).Format(http.TimeFormat), 90 * time.Second},
+		{now.Add(-time.Minute).Format(http.TimeFormat), 0},
+		{"soon", 0},
+	}
+	for _, tt := range tests {
+		if got := parseRetryAfter(tt.header, now); got != tt.want {
+			t.Errorf("parseRetryAfter(%q) = %v, want %v", tt.header, got, tt.want)
+		}
+	}
+}
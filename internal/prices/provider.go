@@ -0,0 +1,100 @@
+package prices
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"time"
+
+	repository "ares_api/internal/interfaces/repository"
+)
+
+// Price provider names accepted by PRICE_PROVIDER
+const (
+	ProviderCoinGecko  = "coingecko"
+	ProviderReplay     = "replay"
+	ProviderRandomWalk = "random_walk"
+)
+
+// defaultSimStart anchors the random walk so runs line up across restarts
+var defaultSimStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// NewProviderFromEnv builds the price provider selected by environment variables:
+//
+//	PRICE_PROVIDER        coingecko (default), replay or random_walk
+//	COINGECKO_PLAN        coingecko: free (default, 30 requests/min) or pro (500 requests/min)
+//	COINGECKO_RATE_LIMIT  coingecko: requests per minute, overriding the plan's
+//	PRICE_REPLAY_FILE     replay: path of the .csv or .json price history
+//	PRICE_CLOCK_START     replay/random_walk: RFC 3339 instant the clock starts at
+//	PRICE_CLOCK_SPEED     replay/random_walk: clock speed relative to wall time (default 1, 0 freezes it)
+//	PRICE_SIM_SEED        random_walk: seed of the price paths (default 1)
+//	PRICE_SIM_STEP        random_walk: clock time between price moves (default 1m)
+func NewProviderFromEnv() (repository.PriceProvider, error) {
+	switch name := os.Getenv("PRICE_PROVIDER"); name {
+	case "", ProviderCoinGecko:
+		return NewCoinGeckoProviderFromEnv()
+
+	case ProviderReplay:
+		path := os.Getenv("PRICE_REPLAY_FILE")
+		if path == "" {
+			return nil, fmt.Errorf("PRICE_REPLAY_FILE is required for the replay price provider")
+		}
+		p, err := LoadReplayFile(path)
+		if err != nil {
+			return nil, err
+		}
+		clock, err := clockFromEnv(p.Start())
+		if err != nil {
+			return nil, err
+		}
+		p.Clock = clock
+		return p, nil
+
+	case ProviderRandomWalk:
+		seed := int64(1)
+		if v := os.Getenv("PRICE_SIM_SEED"); v != "" {
+			parsed, err := strconv.ParseInt(v, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid PRICE_SIM_SEED: %w", err)
+			}
+			seed = parsed
+		}
+		step := time.Minute
+		if v := os.Getenv("PRICE_SIM_STEP"); v != "" {
+			parsed, err := time.ParseDuration(v)
+			if err != nil || parsed <= 0 {
+				return nil, fmt.Errorf("invalid PRICE_SIM_STEP %q", v)
+			}
+			step = parsed
+		}
+		clock, err := clockFromEnv(defaultSimStart)
+		if err != nil {
+			return nil, err
+		}
+		return NewRandomWalkProvider(DefaultSimulatedCoins, seed, clock.Now(), step, clock), nil
+
+	default:
+		return nil, fmt.Errorf("unknown PRICE_PROVIDER %q: must be coingecko, replay or random_walk", name)
+	}
+}
+
+// clockFromEnv builds the clock for offline providers, starting at start unless
+// PRICE_CLOCK_START overrides it
+func clockFromEnv(start time.Time) (*SimClock, error) {
+	if v := os.Getenv("PRICE_CLOCK_START"); v != "" {
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid PRICE_CLOCK_START: %w", err)
+		}
+		start = parsed
+	}
+	speed := 1.0
+	if v := os.Getenv("PRICE_CLOCK_SPEED"); v != "" {
+		parsed, err := strconv.ParseFloat(v, 64)
+		if err != nil || parsed < 0 {
+			return nil, fmt.Errorf("invalid PRICE_CLOCK_SPEED %q", v)
+		}
+		speed = parsed
+	}
+	return NewSimClock(start, speed), nil
+}
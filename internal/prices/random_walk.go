@@ -0,0 +1,208 @@
+package prices
+
+import (
+	"ares_api/internal/api/dto"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	repository "ares_api/internal/interfaces/repository"
+	service "ares_api/internal/interfaces/service"
+)
+
+var _ repository.PriceProvider = &RandomWalkProvider{}
+
+// maxChartPoints bounds the samples FetchMarketChart returns for one request
+const maxChartPoints = 5000
+
+// SimulatedCoin is a coin priced by the random walk simulator
+type SimulatedCoin struct {
+	ID         string
+	Symbol     string
+	Name       string
+	StartPrice float64
+	Supply     float64 // circulating supply; market cap is price * supply
+	Volatility float64 // annualised volatility of log returns (0.8 = 80%)
+}
+
+// DefaultSimulatedCoins are the coins the simulator prices unless told otherwise
+var DefaultSimulatedCoins = []SimulatedCoin{
+	{ID: "bitcoin", Symbol: "btc", Name: "Bitcoin", StartPrice: 60000, Supply: 19_700_000, Volatility: 0.6},
+	{ID: "ethereum", Symbol: "eth", Name: "Ethereum", StartPrice: 3000, Supply: 120_000_000, Volatility: 0.75},
+	{ID: "solana", Symbol: "sol", Name: "Solana", StartPrice: 150, Supply: 460_000_000, Volatility: 1.0},
+	{ID: "ripple", Symbol: "xrp", Name: "XRP", StartPrice: 0.6, Supply: 55_000_000_000, Volatility: 0.9},
+	{ID: "cardano", Symbol: "ada", Name: "Cardano", StartPrice: 0.45, Supply: 35_000_000_000, Volatility: 0.9},
+	{ID: "dogecoin", Symbol: "doge", Name: "Dogecoin", StartPrice: 0.15, Supply: 145_000_000_000, Volatility: 1.1},
+}
+
+// RandomWalkProvider prices coins along a geometric random walk that moves once per
+// Step of clock time. Each coin's path depends only on the seed and the coin id, so
+// the same seed and clock always produce the same prices.
+type RandomWalkProvider struct {
+	Clock Clock
+	Start time.Time // instant of the first step; the walk stays at StartPrice before it
+	Step  time.Duration
+	Seed  int64
+
+	coins map[string]SimulatedCoin
+	ids   []string
+
+	mu    sync.Mutex
+	paths map[string]*walkPath
+}
+
+type walkPath struct {
+	rng    *rand.Rand
+	prices []float64 // price after each step; prices[0] is the start price
+}
+
+func NewRandomWalkProvider(coins []SimulatedCoin, seed int64, start time.Time, step time.Duration, clock Clock) *RandomWalkProvider {
+	p := &RandomWalkProvider{
+		Clock: clock,
+		Start: start,
+		Step:  step,
+		Seed:  seed,
+		coins: map[string]SimulatedCoin{},
+		paths: map[string]*walkPath{},
+	}
+	for _, c := range coins {
+		p.coins[c.ID] = c
+		p.ids = append(p.ids, c.ID)
+	}
+	return p
+}
+
+// priceAt returns the coin's price after the step that covers t
+func (p *RandomWalkProvider) priceAt(coin SimulatedCoin, t time.Time) float64 {
+	n := 0
+	if t.After(p.Start) {
+		n = int(t.Sub(p.Start) / p.Step)
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	path, ok := p.paths[coin.ID]
+	if !ok {
+		h := fnv.New64a()
+		h.Write([]byte(coin.ID))
+		path = &walkPath{
+			rng:    rand.New(rand.NewSource(p.Seed ^ int64(h.Sum64()))),
+			prices: []float64{coin.StartPrice},
+		}
+		p.paths[coin.ID] = path
+	}
+
+	// Per-step volatility scaled from the annual figure, with the drift that keeps
+	// the expected price flat
+	sigma := coin.Volatility * math.Sqrt(float64(p.Step)/float64(365*24*time.Hour))
+	for len(path.prices) <= n {
+		last := path.prices[len(path.prices)-1]
+		shock := path.rng.NormFloat64()
+		path.prices = append(path.prices, last*math.Exp(sigma*shock-sigma*sigma/2))
+	}
+	return path.prices[n]
+}
+
+func (p *RandomWalkProvider) market(coin SimulatedCoin, t time.Time) dto.CoinMarketDTO {
+	price := p.priceAt(coin, t)
+	m := dto.CoinMarketDTO{
+		ID:          coin.ID,
+		Symbol:      coin.Symbol,
+		Name:        coin.Name,
+		PriceUSD:    price,
+		MarketCap:   price * coin.Supply,
+		LastUpdated: t,
+	}
+	m.Change1h = percentChange(price, p.priceAt(coin, t.Add(-time.Hour)))
+	m.Change24h = percentChange(price, p.priceAt(coin, t.Add(-24*time.Hour)))
+	m.Change7d = percentChange(price, p.priceAt(coin, t.Add(-7*24*time.Hour)))
+	return m
+}
+
+func (p *RandomWalkProvider) FetchAllCoins() ([]dto.CoinDTO, error) {
+	coins := make([]dto.CoinDTO, 0, len(p.ids))
+	for _, id := range p.ids {
+		c := p.coins[id]
+		coins = append(coins, dto.CoinDTO{ID: c.ID, Symbol: c.Symbol, Name: c.Name})
+	}
+	return coins, nil
+}
+
+func (p *RandomWalkProvider) FetchCoinMarkets(ids []string, vsCurrency string) ([]dto.CoinMarketDTO, error) {
+	now := p.Clock.Now()
+	rate, err := offlineRate(vsCurrency, now, p.btcUSD)
+	if err != nil {
+		return nil, err
+	}
+	markets := make([]dto.CoinMarketDTO, 0, len(ids))
+	for _, id := range ids {
+		if coin, ok := p.coins[id]; ok {
+			markets = append(markets, scaleMarket(p.market(coin, now), rate))
+		}
+	}
+	return markets, nil
+}
+
+func (p *RandomWalkProvider) FetchTopMarkets(limit int) ([]dto.CoinMarketDTO, error) {
+	now := p.Clock.Now()
+	markets := make([]dto.CoinMarketDTO, 0, len(p.ids))
+	for _, id := range p.ids {
+		markets = append(markets, p.market(p.coins[id], now))
+	}
+	return topByMarketCap(markets, limit), nil
+}
+
+// FetchMarketChart samples the walk between from and to, never past the clock. Long
+// spans are thinned to at most maxChartPoints samples.
+func (p *RandomWalkProvider) FetchMarketChart(id, vsCurrency string, from, to time.Time) ([]dto.PricePointDTO, error) {
+	if _, err := offlineRate(vsCurrency, from, p.btcUSD); err != nil {
+		return nil, err
+	}
+	coin, ok := p.coins[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: no coin with id=%s", service.ErrMarketDataNotFound, id)
+	}
+	if now := p.Clock.Now(); to.After(now) {
+		to = now
+	}
+	if from.Before(p.Start) {
+		from = p.Start
+	}
+	if !from.Before(to) {
+		return []dto.PricePointDTO{}, nil
+	}
+
+	spacing := p.Step
+	if steps := int(to.Sub(from) / p.Step); steps > maxChartPoints {
+		spacing = p.Step * time.Duration(steps/maxChartPoints+1)
+	}
+	first := p.Start.Add(from.Sub(p.Start).Truncate(p.Step))
+	if first.Before(from) {
+		first = first.Add(p.Step)
+	}
+
+	var points []dto.PricePointDTO
+	for t := first; !t.After(to); t = t.Add(spacing) {
+		rate, _ := offlineRate(vsCurrency, t, p.btcUSD)
+		points = append(points, dto.PricePointDTO{Time: t, Price: p.priceAt(coin, t) * rate})
+	}
+	return points, nil
+}
+
+func (p *RandomWalkProvider) FetchSupportedVSCurrencies() ([]string, error) {
+	_, hasBTC := p.coins[offlineBTCCoin]
+	return offlineCurrencies(hasBTC), nil
+}
+
+// btcUSD is the simulated USD price of offlineBTCCoin at t
+func (p *RandomWalkProvider) btcUSD(t time.Time) (float64, bool) {
+	coin, ok := p.coins[offlineBTCCoin]
+	if !ok {
+		return 0, false
+	}
+	return p.priceAt(coin, t), true
+}
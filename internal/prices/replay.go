@@ -0,0 +1,381 @@
+package prices
+
+import (
+	"ares_api/internal/api/dto"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	repository "ares_api/internal/interfaces/repository"
+	service "ares_api/internal/interfaces/service"
+)
+
+var _ repository.PriceProvider = &ReplayProvider{}
+
+// ReplayProvider replays historical prices from a CSV or JSON file against a clock.
+// A coin's price at the clock's current instant is the last row at or before it.
+//
+// CSV files need a header row naming the columns; JSON files hold an array of objects
+// with the same keys: timestamp, coin_id, symbol, name, price, market_cap and volume.
+// Only timestamp, coin_id and price are required. Timestamps are RFC 3339 or unix seconds.
+type ReplayProvider struct {
+	Clock  Clock
+	series map[string]*replaySeries
+	ids    []string // coin ids in the order they first appear
+}
+
+type replayTick struct {
+	at        time.Time
+	price     float64
+	marketCap float64
+	volume    float64
+}
+
+type replaySeries struct {
+	id, symbol, name string
+	ticks            []replayTick // oldest first
+}
+
+type replayRow struct {
+	Timestamp json.RawMessage `json:"timestamp"`
+	CoinID    string          `json:"coin_id"`
+	Symbol    string          `json:"symbol"`
+	Name      string          `json:"name"`
+	Price     float64         `json:"price"`
+	MarketCap float64         `json:"market_cap"`
+	Volume    float64         `json:"volume"`
+}
+
+// LoadReplayFile reads a replay file; the format follows the .csv or .json extension.
+// The provider's clock starts at the first timestamp and runs at wall-clock speed.
+func LoadReplayFile(path string) (*ReplayProvider, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var p *ReplayProvider
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".csv":
+		p, err = readReplayCSV(f)
+	case ".json":
+		p, err = readReplayJSON(f)
+	default:
+		return nil, fmt.Errorf("unsupported replay file %s: must be .csv or .json", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read replay file %s: %w", path, err)
+	}
+	if len(p.ids) == 0 {
+		return nil, fmt.Errorf("replay file %s has no prices", path)
+	}
+
+	p.Clock = NewSimClock(p.Start(), 1)
+	return p, nil
+}
+
+func readReplayCSV(r io.Reader) (*ReplayProvider, error) {
+	records, err := csv.NewReader(r).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return newReplayProvider(), nil
+	}
+
+	columns := map[string]int{}
+	for i, name := range records[0] {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"timestamp", "coin_id", "price"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("missing %s column", required)
+		}
+	}
+	field := func(record []string, name string) string {
+		if i, ok := columns[name]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+
+	p := newReplayProvider()
+	for line, record := range records[1:] {
+		at, err := parseReplayTime(field(record, "timestamp"))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", line+2, err)
+		}
+		price, err := strconv.ParseFloat(field(record, "price"), 64)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: invalid price: %w", line+2, err)
+		}
+		var marketCap, volume float64
+		if v := field(record, "market_cap"); v != "" {
+			if marketCap, err = strconv.ParseFloat(v, 64); err != nil {
+				return nil, fmt.Errorf("line %d: invalid market_cap: %w", line+2, err)
+			}
+		}
+		if v := field(record, "volume"); v != "" {
+			if volume, err = strconv.ParseFloat(v, 64); err != nil {
+				return nil, fmt.Errorf("line %d: invalid volume: %w", line+2, err)
+			}
+		}
+		p.add(field(record, "coin_id"), field(record, "symbol"), field(record, "name"),
+			replayTick{at: at, price: price, marketCap: marketCap, volume: volume})
+	}
+	p.sort()
+	return p, nil
+}
+
+func readReplayJSON(r io.Reader) (*ReplayProvider, error) {
+	var rows []replayRow
+	if err := json.NewDecoder(r).Decode(&rows); err != nil {
+		return nil, err
+	}
+
+	p := newReplayProvider()
+	for i, row := range rows {
+		raw := strings.Trim(string(row.Timestamp), `"`)
+		at, err := parseReplayTime(raw)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", i, err)
+		}
+		p.add(row.CoinID, row.Symbol, row.Name,
+			replayTick{at: at, price: row.Price, marketCap: row.MarketCap, volume: row.Volume})
+	}
+	p.sort()
+	return p, nil
+}
+
+func newReplayProvider() *ReplayProvider {
+	return &ReplayProvider{series: map[string]*replaySeries{}}
+}
+
+func (p *ReplayProvider) add(coinID, symbol, name string, tick replayTick) {
+	if coinID == "" {
+		return
+	}
+	s, ok := p.series[coinID]
+	if !ok {
+		s = &replaySeries{id: coinID, symbol: coinID, name: coinID}
+		p.series[coinID] = s
+		p.ids = append(p.ids, coinID)
+	}
+	if symbol != "" {
+		s.symbol = symbol
+	}
+	if name != "" {
+		s.name = name
+	}
+	s.ticks = append(s.ticks, tick)
+}
+
+func (p *ReplayProvider) sort() {
+	for _, s := range p.series {
+		sort.SliceStable(s.ticks, func(i, j int) bool { return s.ticks[i].at.Before(s.ticks[j].at) })
+	}
+}
+
+// Start is the earliest timestamp in the file
+func (p *ReplayProvider) Start() time.Time {
+	var start time.Time
+	for _, s := range p.series {
+		if first := s.ticks[0].at; start.IsZero() || first.Before(start) {
+			start = first
+		}
+	}
+	return start
+}
+
+// at returns the last tick at or before t
+func (s *replaySeries) at(t time.Time) (replayTick, bool) {
+	i := sort.Search(len(s.ticks), func(i int) bool { return s.ticks[i].at.After(t) })
+	if i == 0 {
+		return replayTick{}, false
+	}
+	return s.ticks[i-1], true
+}
+
+// market prices s at t; ok is false before the coin's first row
+func (s *replaySeries) market(t time.Time) (dto.CoinMarketDTO, bool) {
+	tick, ok := s.at(t)
+	if !ok {
+		return dto.CoinMarketDTO{}, false
+	}
+	m := dto.CoinMarketDTO{
+		ID:          s.id,
+		Symbol:      s.symbol,
+		Name:        s.name,
+		PriceUSD:    tick.price,
+		MarketCap:   tick.marketCap,
+		Volume24h:   tick.volume,
+		LastUpdated: tick.at,
+	}
+	if prev, ok := s.at(t.Add(-time.Hour)); ok {
+		m.Change1h = percentChange(tick.price, prev.price)
+	}
+	if prev, ok := s.at(t.Add(-24 * time.Hour)); ok {
+		m.Change24h = percentChange(tick.price, prev.price)
+	}
+	if prev, ok := s.at(t.Add(-7 * 24 * time.Hour)); ok {
+		m.Change7d = percentChange(tick.price, prev.price)
+	}
+	return m, true
+}
+
+func (p *ReplayProvider) FetchAllCoins() ([]dto.CoinDTO, error) {
+	coins := make([]dto.CoinDTO, 0, len(p.ids))
+	for _, id := range p.ids {
+		s := p.series[id]
+		coins = append(coins, dto.CoinDTO{ID: s.id, Symbol: s.symbol, Name: s.name})
+	}
+	return coins, nil
+}
+
+func (p *ReplayProvider) FetchCoinMarkets(ids []string, vsCurrency string) ([]dto.CoinMarketDTO, error) {
+	now := p.Clock.Now()
+	rate, err := offlineRate(vsCurrency, now, p.btcUSD)
+	if err != nil {
+		return nil, err
+	}
+	markets := make([]dto.CoinMarketDTO, 0, len(ids))
+	for _, id := range ids {
+		if s, ok := p.series[id]; ok {
+			if m, ok := s.market(now); ok {
+				markets = append(markets, scaleMarket(m, rate))
+			}
+		}
+	}
+	return markets, nil
+}
+
+func (p *ReplayProvider) FetchTopMarkets(limit int) ([]dto.CoinMarketDTO, error) {
+	now := p.Clock.Now()
+	var markets []dto.CoinMarketDTO
+	for _, id := range p.ids {
+		if m, ok := p.series[id].market(now); ok {
+			markets = append(markets, m)
+		}
+	}
+	return topByMarketCap(markets, limit), nil
+}
+
+// FetchMarketChart returns the file's rows for the coin between from and to. Rows later
+// than the replay clock are withheld so the history never leaks the future.
+func (p *ReplayProvider) FetchMarketChart(id, vsCurrency string, from, to time.Time) ([]dto.PricePointDTO, error) {
+	if _, err := offlineRate(vsCurrency, p.Clock.Now(), p.btcUSD); err != nil {
+		return nil, err
+	}
+	s, ok := p.series[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: no coin with id=%s", service.ErrMarketDataNotFound, id)
+	}
+	if now := p.Clock.Now(); to.After(now) {
+		to = now
+	}
+
+	var points []dto.PricePointDTO
+	for _, tick := range s.ticks {
+		if tick.at.Before(from) || tick.at.After(to) {
+			continue
+		}
+		rate, err := offlineRate(vsCurrency, tick.at, p.btcUSD)
+		if err != nil {
+			continue // no bitcoin price yet to convert into btc
+		}
+		points = append(points, dto.PricePointDTO{Time: tick.at, Price: tick.price * rate, Volume: tick.volume * rate})
+	}
+	return points, nil
+}
+
+func (p *ReplayProvider) FetchSupportedVSCurrencies() ([]string, error) {
+	_, hasBTC := p.series[offlineBTCCoin]
+	return offlineCurrencies(hasBTC), nil
+}
+
+// btcUSD is the replayed USD price of offlineBTCCoin at t
+func (p *ReplayProvider) btcUSD(t time.Time) (float64, bool) {
+	s, ok := p.series[offlineBTCCoin]
+	if !ok {
+		return 0, false
+	}
+	tick, ok := s.at(t)
+	return tick.price, ok
+}
+
+func parseReplayTime(v string) (time.Time, error) {
+	if secs, err := strconv.ParseInt(v, 10, 64); err == nil {
+		return time.Unix(secs, 0).UTC(), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02"} {
+		if t, err := time.Parse(layout, v); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid timestamp %q", v)
+}
+
+// offlineFiatRates are the fixed units of each fiat currency one USD buys. Offline
+// sources only record USD prices, so other quote currencies are derived from them.
+var offlineFiatRates = map[string]float64{"usd": 1, "eur": 0.92, "gbp": 0.79}
+
+// offlineBTCCoin is the coin whose USD price converts offline prices into btc
+const offlineBTCCoin = "bitcoin"
+
+// offlineCurrencies lists the quote currencies offline sources can price in. btc is
+// only offered when the source prices offlineBTCCoin.
+func offlineCurrencies(hasBTC bool) []string {
+	currencies := []string{"usd", "eur", "gbp"}
+	if hasBTC {
+		currencies = append(currencies, "btc")
+	}
+	return currencies
+}
+
+// offlineRate returns the units of vsCurrency one USD buys at t. btcUSD prices
+// offlineBTCCoin at an instant and reports false when the source doesn't know it.
+func offlineRate(vsCurrency string, t time.Time, btcUSD func(time.Time) (float64, bool)) (float64, error) {
+	vs := strings.ToLower(vsCurrency)
+	if rate, ok := offlineFiatRates[vs]; ok {
+		return rate, nil
+	}
+	if vs == "btc" {
+		if price, ok := btcUSD(t); ok && price > 0 {
+			return 1 / price, nil
+		}
+	}
+	return 0, fmt.Errorf("offline prices are not available in %s", vsCurrency)
+}
+
+// scaleMarket converts a USD market quote at rate units of another currency per USD
+func scaleMarket(m dto.CoinMarketDTO, rate float64) dto.CoinMarketDTO {
+	m.PriceUSD *= rate
+	m.MarketCap *= rate
+	m.Volume24h *= rate
+	return m
+}
+
+// percentChange is the move from prev to price in percent; zero without a prev price
+func percentChange(price, prev float64) float64 {
+	if prev <= 0 {
+		return 0
+	}
+	return (price/prev - 1) * 100
+}
+
+// topByMarketCap orders markets the way CoinGecko's /coins/markets does and keeps the first limit
+func topByMarketCap(markets []dto.CoinMarketDTO, limit int) []dto.CoinMarketDTO {
+	sort.SliceStable(markets, func(i, j int) bool { return markets[i].MarketCap > markets[j].MarketCap })
+	if limit > 0 && limit < len(markets) {
+		markets = markets[:limit]
+	}
+	return markets
+}
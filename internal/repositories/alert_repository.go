@@ -0,0 +1,43 @@
+package repositories
+
+import (
+	repository "ares_api/internal/interfaces/repository"
+	"ares_api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type AlertRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewAlertRepository(db *gorm.DB) repository.AlertRepository {
+	return &AlertRepositoryImpl{DB: db}
+}
+
+func (r *AlertRepositoryImpl) Create(alert *models.Alert) error {
+	return r.DB.Create(alert).Error
+}
+
+func (r *AlertRepositoryImpl) Update(alert *models.Alert) error {
+	return r.DB.Save(alert).Error
+}
+
+// Delete removes one of the user's alerts and reports whether it existed
+func (r *AlertRepositoryImpl) Delete(userID, alertID uint) (bool, error) {
+	res := r.DB.Where("id = ? AND user_id = ?", alertID, userID).Delete(&models.Alert{})
+	return res.RowsAffected > 0, res.Error
+}
+
+func (r *AlertRepositoryImpl) GetByUser(userID uint) ([]models.Alert, error) {
+	var alerts []models.Alert
+	err := r.DB.Where("user_id = ?", userID).Order("id asc").Find(&alerts).Error
+	return alerts, err
+}
+
+// GetActive returns every user's alerts that can still fire
+func (r *AlertRepositoryImpl) GetActive() ([]models.Alert, error) {
+	var alerts []models.Alert
+	err := r.DB.Where("status = ?", models.AlertStatusActive).Order("id asc").Find(&alerts).Error
+	return alerts, err
+}
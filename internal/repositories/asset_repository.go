@@ -2,170 +2,83 @@ package repositories
 
 import (
 	"ares_api/internal/api/dto"
-	"encoding/json"
 	"fmt"
-	"io"
-	"net/http"
-	"os"
+	"sync"
 	"time"
 
 	repository "ares_api/internal/interfaces/repository"
+	service "ares_api/internal/interfaces/service"
 )
 
+// Size and lifetime of the cached market universe
+const (
+	marketUniverseSize = 250
+	marketUniverseTTL  = time.Minute
+)
+
+// AssetRepositoryImpl serves coin and market data from whichever price provider is
+// configured. Quotes go through a shared cache that lives for quoteTTL.
 type AssetRepositoryImpl struct {
-	BaseURL string
-	APIKey  string
+	Provider repository.PriceProvider
+	Quotes   *QuoteCache
+
+	universeMu        sync.Mutex
+	universe          []dto.CoinMarketDTO
+	universeFetchedAt time.Time
 }
 
-func NewAssetRepository() repository.AssetRepository {
-	baseURL := os.Getenv("COINGECKO_BASE_URL")
-	if baseURL == "" {
-		baseURL = "https://api.coingecko.com/api/v3"
-	}
-	return &AssetRepositoryImpl{
-		BaseURL: baseURL,
-		APIKey:  os.Getenv("COINGECKO_API_KEY"),
-	}
+func NewAssetRepository(p repository.PriceProvider, quoteTTL time.Duration) repository.AssetRepository {
+	return &AssetRepositoryImpl{Provider: p, Quotes: NewQuoteCache(p, quoteTTL)}
 }
 
 func (r *AssetRepositoryImpl) FetchAllCoins() ([]dto.CoinDTO, error) {
-	url := fmt.Sprintf("%s/coins/list", r.BaseURL)
-	req, _ := http.NewRequest("GET", url, nil)
-	if r.APIKey != "" {
-		req.Header.Add("X-CoinGecko-API-Key", r.APIKey)
-	}
-	client := &http.Client{}
-	resp, err := client.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var coins []dto.CoinDTO
-	if err := json.NewDecoder(resp.Body).Decode(&coins); err != nil {
-		return nil, err
-	}
-	return coins, nil
+	return r.Provider.FetchAllCoins()
 }
 
 func (r *AssetRepositoryImpl) FetchCoinMarket(id, vsCurrency string) (*dto.CoinMarketDTO, error) {
-	url := fmt.Sprintf("%s/coins/markets?vs_currency=%s&ids=%s&order=market_cap_desc&sparkline=false",
-		r.BaseURL, vsCurrency, id)
-
-	req, _ := http.NewRequest("GET", url, nil)
-	if r.APIKey != "" {
-		req.Header.Add("X-CoinGecko-API-Key", r.APIKey)
-	}
-
-	client := &http.Client{Timeout: 10 * time.Second}
-	resp, err := client.Do(req)
+	markets, err := r.Quotes.Get([]string{id}, vsCurrency)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	body, _ := io.ReadAll(resp.Body)
-	fmt.Println("DEBUG CoinGecko response:", string(body)) // 👈 log raw response
-
-	var data []struct {
-		ID          string  `json:"id"`
-		Symbol      string  `json:"symbol"`
-		Name        string  `json:"name"`
-		PriceUSD    float64 `json:"current_price"`
-		MarketCap   float64 `json:"market_cap"`
-		Change24h   float64 `json:"price_change_percentage_24h"`
-		LastUpdated string  `json:"last_updated"`
-	}
-
-	if err := json.Unmarshal(body, &data); err != nil {
-		return nil, fmt.Errorf("decode error: %w", err)
+	market, ok := markets[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: no coin with id=%s", service.ErrMarketDataNotFound, id)
 	}
-	if len(data) == 0 {
-		return nil, fmt.Errorf("coin not found for id=%s", id)
-	}
-
-	t, _ := time.Parse(time.RFC3339, data[0].LastUpdated)
-	return &dto.CoinMarketDTO{
-		ID:          data[0].ID,
-		Symbol:      data[0].Symbol,
-		Name:        data[0].Name,
-		PriceUSD:    data[0].PriceUSD,
-		MarketCap:   data[0].MarketCap,
-		Change24h:   data[0].Change24h,
-		LastUpdated: t,
-	}, nil
+	return &market, nil
 }
 
+// FetchCoinMarkets returns cached quotes for ids, fetching the stale ones in one batch
+func (r *AssetRepositoryImpl) FetchCoinMarkets(ids []string, vsCurrency string) (map[string]dto.CoinMarketDTO, error) {
+	return r.Quotes.Get(ids, vsCurrency)
+}
 
-func (r *AssetRepositoryImpl) FetchTopMovers(limit int) ([]dto.TopMoverDTO, error) {
-	url := fmt.Sprintf("%s/coins/markets?vs_currency=usd&order=market_cap_desc&per_page=%d&page=1&sparkline=false", r.BaseURL, limit)
+// FetchMarketUniverse refetches the universe once it is older than marketUniverseTTL.
+// Its quotes also refresh the USD quote cache.
+func (r *AssetRepositoryImpl) FetchMarketUniverse() ([]dto.CoinMarketDTO, error) {
+	r.universeMu.Lock()
+	defer r.universeMu.Unlock()
 
-	req, _ := http.NewRequest("GET", url, nil)
-	if r.APIKey != "" {
-		req.Header.Add("X-CoinGecko-API-Key", r.APIKey)
+	if r.universe != nil && time.Since(r.universeFetchedAt) < marketUniverseTTL {
+		return r.universe, nil
 	}
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	markets, err := r.Provider.FetchTopMarkets(marketUniverseSize)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	var data []struct {
-		ID        string  `json:"id"`
-		Symbol    string  `json:"symbol"`
-		Name      string  `json:"name"`
-		Price     float64 `json:"current_price"`
-		MarketCap float64 `json:"market_cap"`
-		Change24h float64 `json:"price_change_percentage_24h"`
-		LastUpd   string  `json:"last_updated"`
-	}
-	if err := json.NewDecoder(resp.Body).Decode(&data); err != nil {
-		return nil, err
-	}
-
-	var movers []dto.TopMoverDTO
-	for _, d := range data {
-		lastUpd, _ := parseTime(d.LastUpd)
-		movers = append(movers, dto.TopMoverDTO{
-			ID:          d.ID,
-			Symbol:      d.Symbol,
-			Name:        d.Name,
-			PriceUSD:    d.Price,
-			MarketCap:   d.MarketCap,
-			Change24h:   d.Change24h,
-			LastUpdated: lastUpd,
-		})
-	}
-	return movers, nil
+	r.universe, r.universeFetchedAt = markets, time.Now()
+	r.Quotes.Put(markets, "usd")
+	return markets, nil
 }
 
-func (r *AssetRepositoryImpl) FetchSupportedVSCurrencies() ([]string, error) {
-    url := fmt.Sprintf("%s/simple/supported_vs_currencies", r.BaseURL)
-
-    req, _ := http.NewRequest("GET", url, nil)
-    if r.APIKey != "" {
-        req.Header.Add("X-CoinGecko-API-Key", r.APIKey)
-    }
-
-    client := &http.Client{Timeout: 30 * time.Second}
-    resp, err := client.Do(req)
-    if err != nil {
-        return nil, err
-    }
-    defer resp.Body.Close()
-
-    var currencies []string
-    if err := json.NewDecoder(resp.Body).Decode(&currencies); err != nil {
-        return nil, err
-    }
-
-    return currencies, nil
+func (r *AssetRepositoryImpl) FetchMarketChart(id, vsCurrency string, from, to time.Time) ([]dto.PricePointDTO, error) {
+	return r.Provider.FetchMarketChart(id, vsCurrency, from, to)
 }
 
+// CachedCoinMarkets returns the quotes currently fresh in the cache without fetching
+func (r *AssetRepositoryImpl) CachedCoinMarkets(vsCurrency string) []dto.CoinMarketDTO {
+	return r.Quotes.Fresh(vsCurrency)
+}
 
-
-
-func parseTime(t string) (time.Time, error) {
-	return time.Parse(time.RFC3339, t)
+func (r *AssetRepositoryImpl) FetchSupportedVSCurrencies() ([]string, error) {
+	return r.Provider.FetchSupportedVSCurrencies()
 }
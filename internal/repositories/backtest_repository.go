@@ -0,0 +1,94 @@
+package repositories
+
+import (
+	repository "ares_api/internal/interfaces/repository"
+	"ares_api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+// resultBatchSize bounds the rows of one insert when saving backtest results
+const resultBatchSize = 500
+
+type BacktestRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewBacktestRepository(db *gorm.DB) repository.BacktestRepository {
+	return &BacktestRepositoryImpl{DB: db}
+}
+
+func (r *BacktestRepositoryImpl) Create(run *models.BacktestRun) error {
+	return r.DB.Create(run).Error
+}
+
+func (r *BacktestRepositoryImpl) Update(run *models.BacktestRun) error {
+	return r.DB.Save(run).Error
+}
+
+// GetRun loads a run regardless of its owner, for the workers
+func (r *BacktestRepositoryImpl) GetRun(runID uint) (*models.BacktestRun, error) {
+	var run models.BacktestRun
+	if err := r.DB.First(&run, runID).Error; err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (r *BacktestRepositoryImpl) GetByID(userID, runID uint) (*models.BacktestRun, error) {
+	var run models.BacktestRun
+	if err := r.DB.Where("id = ? AND user_id = ?", runID, userID).First(&run).Error; err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// GetByUser returns the user's runs, newest first
+func (r *BacktestRepositoryImpl) GetByUser(userID uint, limit int) ([]models.BacktestRun, error) {
+	var runs []models.BacktestRun
+	err := r.DB.Where("user_id = ?", userID).Order("created_at desc").Limit(limit).Find(&runs).Error
+	return runs, err
+}
+
+// GetByStatus returns every user's runs in any of statuses, oldest first
+func (r *BacktestRepositoryImpl) GetByStatus(statuses ...string) ([]models.BacktestRun, error) {
+	var runs []models.BacktestRun
+	err := r.DB.Where("status IN ?", statuses).Order("id asc").Find(&runs).Error
+	return runs, err
+}
+
+// SaveResults stores a finished run's trades and equity curve together with its summary
+func (r *BacktestRepositoryImpl) SaveResults(run *models.BacktestRun, trades []models.BacktestTrade, equity []models.BacktestEquityPoint) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		// A run resumed after a restart may have saved part of an earlier attempt
+		if err := tx.Where("run_id = ?", run.ID).Delete(&models.BacktestTrade{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("run_id = ?", run.ID).Delete(&models.BacktestEquityPoint{}).Error; err != nil {
+			return err
+		}
+		if len(trades) > 0 {
+			if err := tx.CreateInBatches(trades, resultBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		if len(equity) > 0 {
+			if err := tx.CreateInBatches(equity, resultBatchSize).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Save(run).Error
+	})
+}
+
+func (r *BacktestRepositoryImpl) GetTrades(runID uint) ([]models.BacktestTrade, error) {
+	var trades []models.BacktestTrade
+	err := r.DB.Where("run_id = ?", runID).Order("executed_at asc, id asc").Find(&trades).Error
+	return trades, err
+}
+
+func (r *BacktestRepositoryImpl) GetEquity(runID uint) ([]models.BacktestEquityPoint, error) {
+	var points []models.BacktestEquityPoint
+	err := r.DB.Where("run_id = ?", runID).Order("at asc").Find(&points).Error
+	return points, err
+}
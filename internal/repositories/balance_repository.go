@@ -1,14 +1,17 @@
 package repositories
 
 import (
+	"errors"
+
 	repository "ares_api/internal/interfaces/repository"
 	"ares_api/internal/models"
 
+	"github.com/shopspring/decimal"
+
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-
-
 type BalanceRepositoryImpl struct {
 	DB *gorm.DB
 }
@@ -17,46 +20,127 @@ func NewBalanceRepository(db *gorm.DB) repository.BalanceRepository {
 	return &BalanceRepositoryImpl{DB: db}
 }
 
-func (r *BalanceRepositoryImpl) GetUSDBalance(userID uint) (*models.Balance, error) {
+// WithTx returns a copy of the repository bound to tx
+func (r *BalanceRepositoryImpl) WithTx(tx *gorm.DB) repository.BalanceRepository {
+	return &BalanceRepositoryImpl{DB: tx}
+}
+
+func (r *BalanceRepositoryImpl) GetBalance(portfolioID uint, asset string) (*models.Balance, error) {
 	var balance models.Balance
-	err := r.DB.Where("user_id = ? AND asset = ?", userID, "USD").First(&balance).Error
+	err := r.DB.Where("portfolio_id = ? AND asset = ?", portfolioID, asset).First(&balance).Error
 	if err != nil {
 		return nil, err
 	}
 	return &balance, nil
 }
 
-func (r *BalanceRepositoryImpl) UpdateUSDBalance(userID uint, delta float64) (*models.Balance, error) {
+// GetBalanceForUpdate reads the balance with SELECT ... FOR UPDATE.
+// The row stays locked until the surrounding transaction ends.
+func (r *BalanceRepositoryImpl) GetBalanceForUpdate(portfolioID uint, asset string) (*models.Balance, error) {
 	var balance models.Balance
-	if err := r.DB.Where("user_id = ? AND asset = ?", userID, "USD").First(&balance).Error; err != nil {
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("portfolio_id = ? AND asset = ?", portfolioID, asset).First(&balance).Error
+	if err != nil {
 		return nil, err
 	}
+	return &balance, nil
+}
+
+// GetBalances returns every cash balance the portfolio holds, ordered by asset
+func (r *BalanceRepositoryImpl) GetBalances(portfolioID uint) ([]models.Balance, error) {
+	var balances []models.Balance
+	err := r.DB.Where("portfolio_id = ?", portfolioID).Order("asset").Find(&balances).Error
+	return balances, err
+}
+
+// UpdateBalance locks the balance row, applies delta and saves it. A credit to a
+// currency the portfolio doesn't hold yet creates the row, inserted with ON CONFLICT DO
+// NOTHING first so concurrent first credits don't collide.
+// When called on a WithTx repository it runs as a savepoint of that transaction.
+func (r *BalanceRepositoryImpl) UpdateBalance(userID, portfolioID uint, asset string, delta decimal.Decimal) (*models.Balance, error) {
+	var balance models.Balance
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if delta.IsPositive() {
+			seed := models.Balance{UserID: userID, PortfolioID: portfolioID, Asset: asset}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&seed).Error; err != nil {
+				return err
+			}
+		}
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("portfolio_id = ? AND asset = ?", portfolioID, asset).First(&balance).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return gorm.ErrInvalidData // nothing to debit
+		} else if err != nil {
+			return err
+		}
+
+		balance.Amount = balance.Amount.Add(delta)
+		if balance.Amount.LessThan(balance.Reserved) {
+			return gorm.ErrInvalidData // insufficient funds
+		}
 
-	balance.Amount += delta
-	if balance.Amount < 0 {
-		return nil, gorm.ErrInvalidData // insufficient funds
+		return tx.Save(&balance).Error
+	})
+	if err != nil {
+		return nil, err
 	}
+	return &balance, nil
+}
+
+// Reserve moves delta into (or, when negative, out of) the reserved part of the balance.
+// The reservation can never exceed the balance nor drop below zero.
+func (r *BalanceRepositoryImpl) Reserve(portfolioID uint, asset string, delta decimal.Decimal) (*models.Balance, error) {
+	var balance models.Balance
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("portfolio_id = ? AND asset = ?", portfolioID, asset).First(&balance).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return gorm.ErrInvalidData // nothing to reserve
+		} else if err != nil {
+			return err
+		}
 
-	if err := r.DB.Save(&balance).Error; err != nil {
+		balance.Reserved = balance.Reserved.Add(delta)
+		if balance.Reserved.IsNegative() {
+			balance.Reserved = decimal.Zero // never release more than is held
+		}
+		if balance.Reserved.GreaterThan(balance.Amount) {
+			return gorm.ErrInvalidData // insufficient available funds
+		}
+
+		return tx.Save(&balance).Error
+	})
+	if err != nil {
 		return nil, err
 	}
 	return &balance, nil
 }
 
-func (r *BalanceRepositoryImpl) ResetUSDBalance(userID uint, defaultBalance float64) error {
-	return r.DB.Model(&models.Balance{}).
-		Where("user_id = ? AND asset = ?", userID, "USD").
-		Update("amount", defaultBalance).Error
+// ResetBalances sets the USD balance back to defaultBalance and empties every other
+// currency, never going below what open orders still hold
+func (r *BalanceRepositoryImpl) ResetBalances(portfolioID uint, defaultBalance decimal.Decimal) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Model(&models.Balance{}).
+			Where("portfolio_id = ? AND asset = ?", portfolioID, models.CurrencyUSD).
+			Update("amount", gorm.Expr("GREATEST(?, reserved)", defaultBalance)).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Balance{}).
+			Where("portfolio_id = ? AND asset <> ?", portfolioID, models.CurrencyUSD).
+			Update("amount", gorm.Expr("reserved")).Error
+	})
 }
 
-func (r *BalanceRepositoryImpl) CreateUSDBalance(userID uint, defaultBalance float64) (*models.Balance, error) {
+func (r *BalanceRepositoryImpl) CreateUSDBalance(userID, portfolioID uint, defaultBalance decimal.Decimal) (*models.Balance, error) {
 	balance := models.Balance{
-		UserID: userID,
-		Asset:  "USD",
-		Amount: defaultBalance,
+		UserID:      userID,
+		PortfolioID: portfolioID,
+		Asset:       models.CurrencyUSD,
+		Amount:      defaultBalance,
 	}
 	if err := r.DB.Create(&balance).Error; err != nil {
 		return nil, err
 	}
 	return &balance, nil
-}
\ No newline at end of file
+}
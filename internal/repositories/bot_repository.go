@@ -0,0 +1,62 @@
+package repositories
+
+import (
+	repository "ares_api/internal/interfaces/repository"
+	"ares_api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type BotRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewBotRepository(db *gorm.DB) repository.BotRepository {
+	return &BotRepositoryImpl{DB: db}
+}
+
+func (r *BotRepositoryImpl) Create(bot *models.Bot) error {
+	return r.DB.Create(bot).Error
+}
+
+func (r *BotRepositoryImpl) Update(bot *models.Bot) error {
+	return r.DB.Save(bot).Error
+}
+
+func (r *BotRepositoryImpl) GetByID(userID, botID uint) (*models.Bot, error) {
+	var bot models.Bot
+	if err := r.DB.Where("id = ? AND user_id = ?", botID, userID).First(&bot).Error; err != nil {
+		return nil, err
+	}
+	return &bot, nil
+}
+
+func (r *BotRepositoryImpl) GetByUser(userID uint) ([]models.Bot, error) {
+	var bots []models.Bot
+	err := r.DB.Where("user_id = ?", userID).Order("id asc").Find(&bots).Error
+	return bots, err
+}
+
+// GetRunning returns every user's running bots
+func (r *BotRepositoryImpl) GetRunning() ([]models.Bot, error) {
+	var bots []models.Bot
+	err := r.DB.Where("status = ?", models.BotStatusRunning).Order("id asc").Find(&bots).Error
+	return bots, err
+}
+
+// RecordTrade saves the bot's state together with the trade log entry for an order it placed
+func (r *BotRepositoryImpl) RecordTrade(bot *models.Bot, trade *models.BotTrade) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(trade).Error; err != nil {
+			return err
+		}
+		return tx.Save(bot).Error
+	})
+}
+
+// GetTrades returns the bot's most recent trades, newest first
+func (r *BotRepositoryImpl) GetTrades(botID uint, limit int) ([]models.BotTrade, error) {
+	var trades []models.BotTrade
+	err := r.DB.Where("bot_id = ?", botID).Order("id desc").Limit(limit).Find(&trades).Error
+	return trades, err
+}
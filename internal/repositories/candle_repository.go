@@ -0,0 +1,56 @@
+package repositories
+
+import (
+	repository "ares_api/internal/interfaces/repository"
+	"ares_api/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CandleRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewCandleRepository(db *gorm.DB) repository.CandleRepository {
+	return &CandleRepositoryImpl{DB: db}
+}
+
+var candleKey = []clause.Column{{Name: "coin_id"}, {Name: "interval"}, {Name: "open_time"}}
+
+// Upsert stores candles, replacing any existing bar with the same coin, interval and open time
+func (r *CandleRepositoryImpl) Upsert(candles []models.Candle) error {
+	if len(candles) == 0 {
+		return nil
+	}
+	return r.DB.Clauses(clause.OnConflict{
+		Columns:   candleKey,
+		DoUpdates: clause.AssignmentColumns([]string{"open", "high", "low", "close", "volume", "updated_at"}),
+	}).Create(&candles).Error
+}
+
+// Merge folds candle into the stored bar for the same bucket: the high and low widen,
+// the close is replaced, and the open of an existing bar is kept. A zero volume keeps
+// the stored one, since live quotes carry none.
+func (r *CandleRepositoryImpl) Merge(candle *models.Candle) error {
+	return r.DB.Clauses(clause.OnConflict{
+		Columns: candleKey,
+		DoUpdates: clause.Assignments(map[string]interface{}{
+			"high":       gorm.Expr("GREATEST(candles.high, excluded.high)"),
+			"low":        gorm.Expr("LEAST(candles.low, excluded.low)"),
+			"close":      gorm.Expr("excluded.close"),
+			"volume":     gorm.Expr("CASE WHEN excluded.volume > 0 THEN excluded.volume ELSE candles.volume END"),
+			"updated_at": gorm.Expr("excluded.updated_at"),
+		}),
+	}).Create(candle).Error
+}
+
+// GetRange returns the bars opening between from and to inclusive, oldest first
+func (r *CandleRepositoryImpl) GetRange(coinID, interval string, from, to time.Time) ([]models.Candle, error) {
+	var candles []models.Candle
+	err := r.DB.Where(&models.Candle{CoinID: coinID, Interval: interval}).
+		Where("open_time BETWEEN ? AND ?", from, to).
+		Order("open_time asc").Find(&candles).Error
+	return candles, err
+}
@@ -0,0 +1,90 @@
+package repositories
+
+import (
+	repository "ares_api/internal/interfaces/repository"
+	"ares_api/internal/models"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type CoinRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewCoinRepository(db *gorm.DB) repository.CoinRepository {
+	return &CoinRepositoryImpl{DB: db}
+}
+
+// coinUpsertBatch keeps each insert of a full catalog refresh well under the
+// Postgres limit on bind parameters
+const coinUpsertBatch = 1000
+
+// Upsert stores coins, replacing the symbol, name and rank of coins already in the catalog
+func (r *CoinRepositoryImpl) Upsert(coins []models.Coin) error {
+	if len(coins) == 0 {
+		return nil
+	}
+	return r.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"symbol", "name", "market_cap_rank", "updated_at"}),
+	}).CreateInBatches(&coins, coinUpsertBatch).Error
+}
+
+func (r *CoinRepositoryImpl) DeleteStale(before time.Time) (int64, error) {
+	res := r.DB.Where("updated_at < ?", before).Delete(&models.Coin{})
+	return res.RowsAffected, res.Error
+}
+
+func (r *CoinRepositoryImpl) Count() (int64, error) {
+	var count int64
+	err := r.DB.Model(&models.Coin{}).Count(&count).Error
+	return count, err
+}
+
+// List returns ranked coins by market cap first, then the rest by id
+func (r *CoinRepositoryImpl) List(limit int) ([]models.Coin, error) {
+	var coins []models.Coin
+	query := r.DB.Order("market_cap_rank = 0, market_cap_rank, id")
+	if limit > 0 {
+		query = query.Limit(limit)
+	}
+	err := query.Find(&coins).Error
+	return coins, err
+}
+
+func (r *CoinRepositoryImpl) GetByID(id string) (*models.Coin, error) {
+	var coin models.Coin
+	if err := r.DB.Where("id = ?", id).First(&coin).Error; err != nil {
+		return nil, err
+	}
+	return &coin, nil
+}
+
+func (r *CoinRepositoryImpl) GetBySymbol(symbol string) ([]models.Coin, error) {
+	var coins []models.Coin
+	err := r.DB.Where("symbol = ?", strings.ToLower(symbol)).
+		Order("market_cap_rank = 0, market_cap_rank, id").Find(&coins).Error
+	return coins, err
+}
+
+// likeEscaper escapes the LIKE wildcards in a search query
+var likeEscaper = strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`)
+
+func (r *CoinRepositoryImpl) SearchCandidates(query string) ([]models.Coin, error) {
+	query = strings.ToLower(query)
+	if query == "" {
+		return nil, nil
+	}
+	first, _ := utf8.DecodeRuneInString(query)
+	initial := string(first)
+	contains := "%" + likeEscaper.Replace(query) + "%"
+
+	var coins []models.Coin
+	err := r.DB.Where("symbol LIKE ? OR LOWER(name) LIKE ? OR LEFT(symbol, 1) = ? OR LOWER(LEFT(name, 1)) = ?",
+		contains, contains, initial, initial).Find(&coins).Error
+	return coins, err
+}
@@ -0,0 +1,116 @@
+package repositories
+
+import (
+	"errors"
+
+	repository "ares_api/internal/interfaces/repository"
+	"ares_api/internal/models"
+
+	"github.com/shopspring/decimal"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type HoldingRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewHoldingRepository(db *gorm.DB) repository.HoldingRepository {
+	return &HoldingRepositoryImpl{DB: db}
+}
+
+// WithTx returns a copy of the repository bound to tx
+func (r *HoldingRepositoryImpl) WithTx(tx *gorm.DB) repository.HoldingRepository {
+	return &HoldingRepositoryImpl{DB: tx}
+}
+
+func (r *HoldingRepositoryImpl) GetHolding(portfolioID uint, coinID string) (*models.Holding, error) {
+	var holding models.Holding
+	err := r.DB.Where("portfolio_id = ? AND coin_id = ?", portfolioID, coinID).First(&holding).Error
+	if err != nil {
+		return nil, err
+	}
+	return &holding, nil
+}
+
+// GetHoldingForUpdate reads the holding with SELECT ... FOR UPDATE
+func (r *HoldingRepositoryImpl) GetHoldingForUpdate(portfolioID uint, coinID string) (*models.Holding, error) {
+	var holding models.Holding
+	err := r.DB.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("portfolio_id = ? AND coin_id = ?", portfolioID, coinID).First(&holding).Error
+	if err != nil {
+		return nil, err
+	}
+	return &holding, nil
+}
+
+// GetHoldings returns every non-empty position in the portfolio
+func (r *HoldingRepositoryImpl) GetHoldings(portfolioID uint) ([]models.Holding, error) {
+	var holdings []models.Holding
+	err := r.DB.Where("portfolio_id = ? AND quantity > 0", portfolioID).Order("coin_id").Find(&holdings).Error
+	return holdings, err
+}
+
+// UpdateHolding applies delta to the portfolio's position in coinID, creating it on first buy
+// The row is locked for the rest of the transaction; a missing row is inserted with
+// ON CONFLICT DO NOTHING first so concurrent first buys of the same coin don't collide.
+func (r *HoldingRepositoryImpl) UpdateHolding(userID, portfolioID uint, coinID string, symbol string, delta decimal.Decimal) (*models.Holding, error) {
+	var holding models.Holding
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		if delta.IsPositive() {
+			seed := models.Holding{UserID: userID, PortfolioID: portfolioID, CoinID: coinID, Symbol: symbol}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&seed).Error; err != nil {
+				return err
+			}
+		}
+
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("portfolio_id = ? AND coin_id = ?", portfolioID, coinID).First(&holding).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return gorm.ErrInvalidData // nothing to sell
+		} else if err != nil {
+			return err
+		}
+
+		holding.Quantity = holding.Quantity.Add(delta)
+		if holding.Quantity.LessThan(holding.Reserved) {
+			return gorm.ErrInvalidData // insufficient holding
+		}
+
+		return tx.Save(&holding).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &holding, nil
+}
+
+// Reserve moves delta into (or, when negative, out of) the reserved part of the holding.
+// The reservation can never exceed the holding nor drop below zero.
+func (r *HoldingRepositoryImpl) Reserve(portfolioID uint, coinID string, delta decimal.Decimal) (*models.Holding, error) {
+	var holding models.Holding
+	err := r.DB.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("portfolio_id = ? AND coin_id = ?", portfolioID, coinID).First(&holding).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return gorm.ErrInvalidData // nothing to reserve
+		} else if err != nil {
+			return err
+		}
+
+		holding.Reserved = holding.Reserved.Add(delta)
+		if holding.Reserved.IsNegative() {
+			holding.Reserved = decimal.Zero // never release more than is held
+		}
+		if holding.Reserved.GreaterThan(holding.Quantity) {
+			return gorm.ErrInvalidData // insufficient available holding
+		}
+
+		return tx.Save(&holding).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &holding, nil
+}
@@ -0,0 +1,47 @@
+package repositories
+
+import (
+	repository "ares_api/internal/interfaces/repository"
+	"ares_api/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type IdempotencyRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewIdempotencyRepository(db *gorm.DB) repository.IdempotencyRepository {
+	return &IdempotencyRepositoryImpl{DB: db}
+}
+
+// Claim relies on the unique (user_id, key) index, so of two concurrent requests with
+// the same key exactly one wins
+func (r *IdempotencyRepositoryImpl) Claim(record *models.IdempotencyKey) (bool, error) {
+	res := r.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(record)
+	return res.RowsAffected == 1, res.Error
+}
+
+func (r *IdempotencyRepositoryImpl) Get(userID uint, key string) (*models.IdempotencyKey, error) {
+	var record models.IdempotencyKey
+	if err := r.DB.Where("user_id = ? AND key = ?", userID, key).First(&record).Error; err != nil {
+		return nil, err
+	}
+	return &record, nil
+}
+
+func (r *IdempotencyRepositoryImpl) Save(record *models.IdempotencyKey) error {
+	return r.DB.Save(record).Error
+}
+
+// Delete removes the row for good so its key can be claimed again
+func (r *IdempotencyRepositoryImpl) Delete(record *models.IdempotencyKey) error {
+	return r.DB.Unscoped().Delete(record).Error
+}
+
+func (r *IdempotencyRepositoryImpl) DeleteExpired(now time.Time) (int64, error) {
+	res := r.DB.Unscoped().Where("expires_at <= ?", now).Delete(&models.IdempotencyKey{})
+	return res.RowsAffected, res.Error
+}
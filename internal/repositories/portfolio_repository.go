@@ -0,0 +1,60 @@
+package repositories
+
+import (
+	repository "ares_api/internal/interfaces/repository"
+	"ares_api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type PortfolioRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewPortfolioRepository(db *gorm.DB) repository.PortfolioRepository {
+	return &PortfolioRepositoryImpl{DB: db}
+}
+
+// WithTx returns a copy of the repository bound to tx
+func (r *PortfolioRepositoryImpl) WithTx(tx *gorm.DB) repository.PortfolioRepository {
+	return &PortfolioRepositoryImpl{DB: tx}
+}
+
+func (r *PortfolioRepositoryImpl) Create(portfolio *models.Portfolio) error {
+	return r.DB.Create(portfolio).Error
+}
+
+func (r *PortfolioRepositoryImpl) Update(portfolio *models.Portfolio) error {
+	return r.DB.Save(portfolio).Error
+}
+
+func (r *PortfolioRepositoryImpl) GetByID(userID, portfolioID uint) (*models.Portfolio, error) {
+	var portfolio models.Portfolio
+	if err := r.DB.Where("id = ? AND user_id = ?", portfolioID, userID).First(&portfolio).Error; err != nil {
+		return nil, err
+	}
+	return &portfolio, nil
+}
+
+// GetByUser returns every portfolio of the user, archived ones included, oldest first
+func (r *PortfolioRepositoryImpl) GetByUser(userID uint) ([]models.Portfolio, error) {
+	var portfolios []models.Portfolio
+	err := r.DB.Where("user_id = ?", userID).Order("id asc").Find(&portfolios).Error
+	return portfolios, err
+}
+
+// GetDefault returns the portfolio used when a request doesn't pick one
+func (r *PortfolioRepositoryImpl) GetDefault(userID uint) (*models.Portfolio, error) {
+	var portfolio models.Portfolio
+	if err := r.DB.Where("user_id = ? AND is_default", userID).First(&portfolio).Error; err != nil {
+		return nil, err
+	}
+	return &portfolio, nil
+}
+
+// GetActive returns every user's portfolios that are not archived
+func (r *PortfolioRepositoryImpl) GetActive() ([]models.Portfolio, error) {
+	var portfolios []models.Portfolio
+	err := r.DB.Where("archived_at IS NULL").Order("id asc").Find(&portfolios).Error
+	return portfolios, err
+}
@@ -0,0 +1,118 @@
+package repositories
+
+import (
+	"ares_api/internal/api/dto"
+	"strings"
+	"sync"
+	"time"
+
+	repository "ares_api/internal/interfaces/repository"
+)
+
+// QuoteCache keeps recent market quotes so every caller shares them. Missing or
+// stale coins are refreshed together in one provider call per lookup, and only one
+// refresh runs at a time so concurrent misses don't stampede the upstream API.
+type QuoteCache struct {
+	Provider repository.PriceProvider
+	TTL      time.Duration
+
+	mu      sync.RWMutex
+	quotes  map[string]cachedQuote // keyed by vs currency and coin id
+	fetchMu sync.Mutex
+}
+
+type cachedQuote struct {
+	market    dto.CoinMarketDTO
+	fetchedAt time.Time
+}
+
+func NewQuoteCache(p repository.PriceProvider, ttl time.Duration) *QuoteCache {
+	return &QuoteCache{Provider: p, TTL: ttl, quotes: map[string]cachedQuote{}}
+}
+
+// Get returns the quotes for ids in vsCurrency. Coins the provider doesn't know are
+// left out of the result.
+func (c *QuoteCache) Get(ids []string, vsCurrency string) (map[string]dto.CoinMarketDTO, error) {
+	vsCurrency = strings.ToLower(vsCurrency)
+	result := make(map[string]dto.CoinMarketDTO, len(ids))
+
+	stale := c.lookup(ids, vsCurrency, result)
+	if len(stale) == 0 {
+		return result, nil
+	}
+
+	c.fetchMu.Lock()
+	defer c.fetchMu.Unlock()
+
+	// Another caller may have refreshed these while we waited
+	stale = c.lookup(stale, vsCurrency, result)
+	if len(stale) == 0 {
+		return result, nil
+	}
+
+	markets, err := c.Provider.FetchCoinMarkets(stale, vsCurrency)
+	if err != nil {
+		return nil, err
+	}
+
+	c.Put(markets, vsCurrency)
+	for _, m := range markets {
+		result[m.ID] = m
+	}
+	return result, nil
+}
+
+// Put stores quotes fetched elsewhere, so callers that just fetched them spare the
+// next lookup a provider call
+func (c *QuoteCache) Put(markets []dto.CoinMarketDTO, vsCurrency string) {
+	vsCurrency = strings.ToLower(vsCurrency)
+	now := time.Now()
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, m := range markets {
+		c.quotes[quoteKey(vsCurrency, m.ID)] = cachedQuote{market: m, fetchedAt: now}
+	}
+}
+
+// lookup copies fresh quotes for ids into result and returns the distinct ids that
+// are missing or older than the TTL
+func (c *QuoteCache) lookup(ids []string, vsCurrency string, result map[string]dto.CoinMarketDTO) []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var stale []string
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		if q, ok := c.quotes[quoteKey(vsCurrency, id)]; ok && time.Since(q.fetchedAt) < c.TTL {
+			result[id] = q.market
+			continue
+		}
+		stale = append(stale, id)
+	}
+	return stale
+}
+
+// Fresh returns every cached quote in vsCurrency that is still within the TTL
+func (c *QuoteCache) Fresh(vsCurrency string) []dto.CoinMarketDTO {
+	vsCurrency = strings.ToLower(vsCurrency)
+	prefix := quoteKey(vsCurrency, "")
+
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var markets []dto.CoinMarketDTO
+	for key, q := range c.quotes {
+		if strings.HasPrefix(key, prefix) && time.Since(q.fetchedAt) < c.TTL {
+			markets = append(markets, q.market)
+		}
+	}
+	return markets
+}
+
+func quoteKey(vsCurrency, id string) string {
+	return vsCurrency + ":" + id
+}
@@ -0,0 +1,28 @@
+package repositories
+
+import (
+	repository "ares_api/internal/interfaces/repository"
+	"ares_api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type RiskLimitRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewRiskLimitRepository(db *gorm.DB) repository.RiskLimitRepository {
+	return &RiskLimitRepositoryImpl{DB: db}
+}
+
+func (r *RiskLimitRepositoryImpl) GetByPortfolio(portfolioID uint) (*models.RiskLimit, error) {
+	var limit models.RiskLimit
+	if err := r.DB.Where("portfolio_id = ?", portfolioID).First(&limit).Error; err != nil {
+		return nil, err
+	}
+	return &limit, nil
+}
+
+func (r *RiskLimitRepositoryImpl) Save(limit *models.RiskLimit) error {
+	return r.DB.Save(limit).Error
+}
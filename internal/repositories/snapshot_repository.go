@@ -0,0 +1,38 @@
+package repositories
+
+import (
+	repository "ares_api/internal/interfaces/repository"
+	"ares_api/internal/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type SnapshotRepository struct {
+	db *gorm.DB
+}
+
+func NewSnapshotRepository(db *gorm.DB) repository.SnapshotRepository {
+	return &SnapshotRepository{db: db}
+}
+
+func (r *SnapshotRepository) Create(snapshot *models.PortfolioSnapshot) error {
+	return r.db.Create(snapshot).Error
+}
+
+// GetByPortfolioSince returns the portfolio's snapshots taken at or after from, oldest first
+func (r *SnapshotRepository) GetByPortfolioSince(portfolioID uint, from time.Time) ([]models.PortfolioSnapshot, error) {
+	var snapshots []models.PortfolioSnapshot
+	err := r.db.Where("portfolio_id = ? AND taken_at >= ?", portfolioID, from).Order("taken_at asc").Find(&snapshots).Error
+	return snapshots, err
+}
+
+// GetLatestBefore returns the portfolio's last snapshot taken before before
+func (r *SnapshotRepository) GetLatestBefore(portfolioID uint, before time.Time) (*models.PortfolioSnapshot, error) {
+	var snapshot models.PortfolioSnapshot
+	err := r.db.Where("portfolio_id = ? AND taken_at < ?", portfolioID, before).Order("taken_at desc").First(&snapshot).Error
+	if err != nil {
+		return nil, err
+	}
+	return &snapshot, nil
+}
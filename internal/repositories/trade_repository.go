@@ -3,9 +3,9 @@ package repositories
 import (
 	repo "ares_api/internal/interfaces/repository"
 	"ares_api/internal/models"
-	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type TradeRepository struct {
@@ -16,31 +16,117 @@ func NewTradeRepository(db *gorm.DB) repo.TradeRepository {
 	return &TradeRepository{db: db}
 }
 
-func (r *TradeRepository) Create(trade *models.Trade) error {
-	return r.db.Create(trade).Error
+// WithTx returns a copy of the repository bound to tx
+func (r *TradeRepository) WithTx(tx *gorm.DB) repo.TradeRepository {
+	return &TradeRepository{db: tx}
 }
 
-func (r *TradeRepository) GetByUserID(userID uint, limit int) ([]models.Trade, error) {
-	var trades []models.Trade
-	err := r.db.Where("user_id = ?", userID).Order("created_at desc").Limit(limit).Find(&trades).Error
-	return trades, err
+func (r *TradeRepository) Create(order *models.Order) error {
+	return r.db.Create(order).Error
 }
 
-func (r *TradeRepository) GetOpenLimitOrders() ([]models.Trade, error) {
-	var trades []models.Trade
-	err := r.db.Where("type = ? AND status = ?", "limit", "open").Find(&trades).Error
-	return trades, err
+func (r *TradeRepository) GetByPortfolio(portfolioID uint, limit int) ([]models.Order, error) {
+	var orders []models.Order
+	err := r.db.Where("portfolio_id = ?", portfolioID).Order("created_at desc").Limit(limit).Find(&orders).Error
+	return orders, err
 }
 
-func (r *TradeRepository) MarkOrderFilled(tradeID uint) error {
-	return r.db.Model(&models.Trade{}).Where("id = ?", tradeID).Updates(map[string]interface{}{
-		"status":     "filled",
-		"updated_at": time.Now(),
-	}).Error
+// Search returns every order matching filter, oldest first, without a row cap
+func (r *TradeRepository) Search(filter repo.TradeFilter) ([]models.Order, error) {
+	var orders []models.Order
+	err := applyTradeFilter(r.db.Model(&models.Order{}), filter).Order("created_at asc, id asc").Find(&orders).Error
+	return orders, err
 }
 
-func (r *TradeRepository) GetOpenLimitOrdersByUser(userID uint) ([]models.Trade, error) {
-	var trades []models.Trade
-	err := r.db.Where("user_id = ? AND type = ? AND status = ?", userID, "limit", "open").Find(&trades).Error
-	return trades, err
+// GetOpenOrders returns every resting (non-market) order that can still fill
+func (r *TradeRepository) GetOpenOrders() ([]models.Order, error) {
+	var orders []models.Order
+	err := r.db.Where("type <> ? AND status IN ?", models.OrderTypeMarket, activeOrderStatuses).Order("id").Find(&orders).Error
+	return orders, err
+}
+
+func (r *TradeRepository) GetOrderByID(orderID uint) (*models.Order, error) {
+	var order models.Order
+	if err := r.db.First(&order, orderID).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// GetOrderForUpdate reads an order with SELECT ... FOR UPDATE so status changes are serialised
+func (r *TradeRepository) GetOrderForUpdate(orderID uint) (*models.Order, error) {
+	var order models.Order
+	if err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).First(&order, orderID).Error; err != nil {
+		return nil, err
+	}
+	return &order, nil
+}
+
+// GetOCOGroupForUpdate locks every leg of a one-cancels-other group in id order,
+// so concurrent callers touching different legs can't deadlock
+func (r *TradeRepository) GetOCOGroupForUpdate(groupID string) ([]models.Order, error) {
+	var orders []models.Order
+	err := r.db.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("oco_group_id = ?", groupID).Order("id").Find(&orders).Error
+	return orders, err
+}
+
+func (r *TradeRepository) UpdateOrder(order *models.Order) error {
+	return r.db.Save(order).Error
+}
+
+func (r *TradeRepository) GetOpenOrdersByPortfolio(portfolioID uint) ([]models.Order, error) {
+	var orders []models.Order
+	err := r.db.Where("portfolio_id = ? AND type <> ? AND status IN ?", portfolioID, models.OrderTypeMarket, activeOrderStatuses).Find(&orders).Error
+	return orders, err
+}
+
+var activeOrderStatuses = []string{models.OrderStatusOpen, models.OrderStatusPartiallyFilled}
+
+func (r *TradeRepository) CreateFill(fill *models.Fill) error {
+	return r.db.Create(fill).Error
+}
+
+// GetFills returns the fills of the given orders, oldest first
+func (r *TradeRepository) GetFills(orderIDs []uint) ([]models.Fill, error) {
+	var fills []models.Fill
+	if len(orderIDs) == 0 {
+		return fills, nil
+	}
+	err := r.db.Where("order_id IN ?", orderIDs).Order("created_at asc, id asc").Find(&fills).Error
+	return fills, err
+}
+
+// GetFillsByPortfolio returns every fill in a portfolio, oldest first
+func (r *TradeRepository) GetFillsByPortfolio(portfolioID uint) ([]models.Fill, error) {
+	var fills []models.Fill
+	err := r.db.Where("portfolio_id = ?", portfolioID).Order("created_at asc, id asc").Find(&fills).Error
+	return fills, err
+}
+
+// SearchFills returns every fill matching filter, oldest first, without a row cap
+func (r *TradeRepository) SearchFills(filter repo.TradeFilter) ([]models.Fill, error) {
+	var fills []models.Fill
+	err := applyTradeFilter(r.db.Model(&models.Fill{}), filter).Order("created_at asc, id asc").Find(&fills).Error
+	return fills, err
+}
+
+// applyTradeFilter adds the non-zero fields of filter to query; orders and fills share the columns
+func applyTradeFilter(query *gorm.DB, filter repo.TradeFilter) *gorm.DB {
+	if filter.PortfolioID != 0 {
+		query = query.Where("portfolio_id = ?", filter.PortfolioID)
+	}
+	if filter.CoinID != "" {
+		query = query.Where("coin_id = ?", filter.CoinID)
+	}
+	if filter.Side != "" {
+		query = query.Where("side = ?", filter.Side)
+	}
+	if !filter.From.IsZero() {
+		query = query.Where("created_at >= ?", filter.From)
+	}
+	if !filter.To.IsZero() {
+		query = query.Where("created_at < ?", filter.To)
+	}
+	return query
 }
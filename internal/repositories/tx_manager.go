@@ -0,0 +1,20 @@
+package repositories
+
+import (
+	repository "ares_api/internal/interfaces/repository"
+
+	"gorm.io/gorm"
+)
+
+type TxManagerImpl struct {
+	DB *gorm.DB
+}
+
+func NewTxManager(db *gorm.DB) repository.TxManager {
+	return &TxManagerImpl{DB: db}
+}
+
+// Transaction commits when fn returns nil and rolls back on error or panic
+func (m *TxManagerImpl) Transaction(fn func(tx *gorm.DB) error) error {
+	return m.DB.Transaction(fn)
+}
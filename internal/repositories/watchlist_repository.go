@@ -0,0 +1,73 @@
+package repositories
+
+import (
+	repository "ares_api/internal/interfaces/repository"
+	"ares_api/internal/models"
+
+	"gorm.io/gorm"
+)
+
+type WatchlistRepositoryImpl struct {
+	DB *gorm.DB
+}
+
+func NewWatchlistRepository(db *gorm.DB) repository.WatchlistRepository {
+	return &WatchlistRepositoryImpl{DB: db}
+}
+
+// WithTx returns a copy of the repository bound to tx
+func (r *WatchlistRepositoryImpl) WithTx(tx *gorm.DB) repository.WatchlistRepository {
+	return &WatchlistRepositoryImpl{DB: tx}
+}
+
+func (r *WatchlistRepositoryImpl) Create(watchlist *models.Watchlist) error {
+	return r.DB.Create(watchlist).Error
+}
+
+func (r *WatchlistRepositoryImpl) withItems() *gorm.DB {
+	return r.DB.Preload("Items", func(db *gorm.DB) *gorm.DB {
+		return db.Order("position asc, id asc")
+	})
+}
+
+func (r *WatchlistRepositoryImpl) GetByID(userID, watchlistID uint) (*models.Watchlist, error) {
+	var watchlist models.Watchlist
+	if err := r.withItems().Where("id = ? AND user_id = ?", watchlistID, userID).First(&watchlist).Error; err != nil {
+		return nil, err
+	}
+	return &watchlist, nil
+}
+
+// GetByUser returns every watchlist of the user, oldest first
+func (r *WatchlistRepositoryImpl) GetByUser(userID uint) ([]models.Watchlist, error) {
+	var watchlists []models.Watchlist
+	err := r.withItems().Where("user_id = ?", userID).Order("id asc").Find(&watchlists).Error
+	return watchlists, err
+}
+
+// Delete removes the rows for good, so the name and coins can be used again
+func (r *WatchlistRepositoryImpl) Delete(watchlist *models.Watchlist) error {
+	return r.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Where("watchlist_id = ?", watchlist.ID).Delete(&models.WatchlistItem{}).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(watchlist).Error
+	})
+}
+
+func (r *WatchlistRepositoryImpl) AddItem(item *models.WatchlistItem) error {
+	return r.DB.Create(item).Error
+}
+
+func (r *WatchlistRepositoryImpl) DeleteItem(item *models.WatchlistItem) error {
+	return r.DB.Unscoped().Delete(item).Error
+}
+
+func (r *WatchlistRepositoryImpl) UpdatePositions(items []models.WatchlistItem) error {
+	for _, item := range items {
+		if err := r.DB.Model(&models.WatchlistItem{}).Where("id = ?", item.ID).Update("position", item.Position).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
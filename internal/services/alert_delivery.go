@@ -0,0 +1,125 @@
+package services
+
+import (
+	"ares_api/internal/api/dto"
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// alertStreamBuffer is how many undelivered events a subscriber may fall behind by
+// before further events to it are dropped
+const alertStreamBuffer = 16
+
+// webhookAttempts is how many times a webhook is tried before it is given up on
+const webhookAttempts = 3
+
+// alertHub fans fired alerts out to each user's open event streams
+type alertHub struct {
+	mu   sync.Mutex
+	subs map[uint]map[chan dto.AlertEventDTO]struct{}
+}
+
+func newAlertHub() *alertHub {
+	return &alertHub{subs: map[uint]map[chan dto.AlertEventDTO]struct{}{}}
+}
+
+func (h *alertHub) subscribe(userID uint) (<-chan dto.AlertEventDTO, func()) {
+	ch := make(chan dto.AlertEventDTO, alertStreamBuffer)
+
+	h.mu.Lock()
+	if h.subs[userID] == nil {
+		h.subs[userID] = map[chan dto.AlertEventDTO]struct{}{}
+	}
+	h.subs[userID][ch] = struct{}{}
+	h.mu.Unlock()
+
+	var once sync.Once
+	return ch, func() {
+		once.Do(func() {
+			h.mu.Lock()
+			defer h.mu.Unlock()
+			delete(h.subs[userID], ch)
+			if len(h.subs[userID]) == 0 {
+				delete(h.subs, userID)
+			}
+			close(ch)
+		})
+	}
+}
+
+// publish hands the event to every stream of the user without blocking; a stream that
+// isn't keeping up misses it
+func (h *alertHub) publish(userID uint, event dto.AlertEventDTO) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ch := range h.subs[userID] {
+		select {
+		case ch <- event:
+		default:
+		}
+	}
+}
+
+// newWebhookSecret returns a random HMAC key for signing an alert's webhooks
+func newWebhookSecret() (string, error) {
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// signWebhook is the X-Ares-Signature value for body sent at timestamp
+func signWebhook(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	fmt.Fprintf(mac, "%d.", timestamp)
+	mac.Write(body)
+	return "t=" + strconv.FormatInt(timestamp, 10) + ",v1=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// deliverWebhook POSTs the event to url, retrying with backoff on network errors and
+// non-2xx responses. Each attempt is signed afresh so receivers can reject replays by
+// the timestamp.
+func deliverWebhook(client *http.Client, url, secret string, event dto.AlertEventDTO) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	var lastErr error
+	backoff := time.Second
+	for attempt := 0; attempt < webhookAttempts; attempt++ {
+		if attempt > 0 {
+			time.Sleep(backoff)
+			backoff *= 2
+		}
+		req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Ares-Event", "alert.triggered")
+		req.Header.Set("X-Ares-Signature", signWebhook(secret, time.Now().Unix(), body))
+
+		resp, err := client.Do(req)
+		if err != nil {
+			lastErr = err
+			continue
+		}
+		resp.Body.Close()
+		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			return nil
+		}
+		lastErr = fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return lastErr
+}
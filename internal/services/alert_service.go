@@ -0,0 +1,300 @@
+package services
+
+import (
+	"ares_api/internal/api/dto"
+	repository "ares_api/internal/interfaces/repository"
+	service "ares_api/internal/interfaces/service"
+	"ares_api/internal/models"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var _ service.AlertService = &AlertService{}
+
+const (
+	maxAlertWindow = 24 * time.Hour
+	webhookTimeout = 5 * time.Second
+)
+
+// AlertService stores price alerts and evaluates them against the quote feed. Fired
+// alerts go to the user's open event streams, the alert's webhook if it has one, and
+// the ledger.
+type AlertService struct {
+	Repo          repository.AlertRepository
+	AssetRepo     repository.AssetRepository
+	CandleRepo    repository.CandleRepository
+	LedgerService service.LedgerService
+
+	hub    *alertHub
+	client *http.Client
+}
+
+func NewAlertService(r repository.AlertRepository, a repository.AssetRepository, c repository.CandleRepository, l service.LedgerService) *AlertService {
+	return &AlertService{
+		Repo:          r,
+		AssetRepo:     a,
+		CandleRepo:    c,
+		LedgerService: l,
+		hub:           newAlertHub(),
+		client:        &http.Client{Timeout: webhookTimeout},
+	}
+}
+
+func (s *AlertService) Create(userID uint, req dto.CreateAlertRequest) (*dto.AlertDTO, error) {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", service.ErrInvalidAlert, fmt.Sprintf(format, args...))
+	}
+
+	alert := &models.Alert{
+		UserID:    userID,
+		CoinID:    strings.ToLower(strings.TrimSpace(req.CoinID)),
+		Condition: req.Condition,
+		Threshold: req.Threshold,
+		Mode:      req.Mode,
+		Status:    models.AlertStatusActive,
+		Note:      strings.TrimSpace(req.Note),
+	}
+	if alert.CoinID == "" {
+		return nil, invalid("coin_id is required")
+	}
+
+	switch alert.Condition {
+	case models.AlertPriceAbove, models.AlertPriceBelow:
+		alert.Threshold = models.RoundPrice(alert.Threshold)
+		if !alert.Threshold.IsPositive() {
+			return nil, invalid("threshold must be a positive price")
+		}
+	case models.AlertPercentMove:
+		window, err := time.ParseDuration(req.Window)
+		if err != nil || window < time.Minute || window > maxAlertWindow {
+			return nil, invalid("percent_move needs a window between 1m and 24h")
+		}
+		alert.WindowMinutes = int(window / time.Minute)
+		fallthrough
+	case models.AlertChange24h:
+		if alert.Threshold.IsZero() {
+			return nil, invalid("threshold must be a non-zero percentage")
+		}
+	default:
+		return nil, invalid("unknown condition %q: must be price_above, price_below, percent_move or change_24h", req.Condition)
+	}
+
+	switch alert.Mode {
+	case "":
+		alert.Mode = models.AlertModeOnce
+	case models.AlertModeOnce:
+	case models.AlertModeRecurring:
+		if req.Cooldown != "" {
+			cooldown, err := time.ParseDuration(req.Cooldown)
+			if err != nil || cooldown < 0 {
+				return nil, invalid("cooldown must be a duration")
+			}
+			alert.CooldownMinutes = int(cooldown / time.Minute)
+		}
+	default:
+		return nil, invalid("unknown mode %q: must be once or recurring", req.Mode)
+	}
+
+	if req.WebhookURL != "" {
+		u, err := url.Parse(req.WebhookURL)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return nil, invalid("webhook_url must be an absolute http or https URL")
+		}
+		secret, err := newWebhookSecret()
+		if err != nil {
+			return nil, err
+		}
+		alert.WebhookURL, alert.WebhookSecret = req.WebhookURL, secret
+	}
+
+	if err := s.Repo.Create(alert); err != nil {
+		return nil, err
+	}
+	res := toAlertDTO(alert)
+	res.WebhookSecret = alert.WebhookSecret
+	return &res, nil
+}
+
+func (s *AlertService) List(userID uint) ([]dto.AlertDTO, error) {
+	alerts, err := s.Repo.GetByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]dto.AlertDTO, 0, len(alerts))
+	for i := range alerts {
+		res = append(res, toAlertDTO(&alerts[i]))
+	}
+	return res, nil
+}
+
+func (s *AlertService) Delete(userID, alertID uint) error {
+	deleted, err := s.Repo.Delete(userID, alertID)
+	if err != nil {
+		return err
+	}
+	if !deleted {
+		return service.ErrAlertNotFound
+	}
+	return nil
+}
+
+func (s *AlertService) Subscribe(userID uint) (<-chan dto.AlertEventDTO, func()) {
+	return s.hub.subscribe(userID)
+}
+
+// Evaluate checks every active alert against one batch of quotes and returns how many fired
+func (s *AlertService) Evaluate() (int, error) {
+	alerts, err := s.Repo.GetActive()
+	if err != nil || len(alerts) == 0 {
+		return 0, err
+	}
+
+	seen := map[string]bool{}
+	var coinIDs []string
+	for _, a := range alerts {
+		if !seen[a.CoinID] {
+			seen[a.CoinID] = true
+			coinIDs = append(coinIDs, a.CoinID)
+		}
+	}
+	markets, err := s.AssetRepo.FetchCoinMarkets(coinIDs, "usd")
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch market prices: %w", err)
+	}
+
+	now := time.Now()
+	fired := 0
+	for i := range alerts {
+		alert := &alerts[i]
+		market, ok := markets[alert.CoinID]
+		if !ok {
+			continue
+		}
+		value, ok := s.observe(alert, &market, now)
+		if !ok {
+			continue
+		}
+
+		holds := conditionHolds(alert, value)
+		if !holds {
+			if !alert.Armed {
+				alert.Armed = true
+				if err := s.Repo.Update(alert); err != nil {
+					return fired, err
+				}
+			}
+			continue
+		}
+		if !alert.Armed || s.coolingDown(alert, now) {
+			continue
+		}
+		if err := s.fire(alert, value, marketPrice(&market), now); err != nil {
+			return fired, err
+		}
+		fired++
+	}
+	return fired, nil
+}
+
+// observe returns the value the alert's condition compares against its threshold.
+// percent_move reads the price WindowMinutes ago from the 1m candles; until they
+// reach back that far it has nothing to compare against.
+func (s *AlertService) observe(alert *models.Alert, market *dto.CoinMarketDTO, now time.Time) (decimal.Decimal, bool) {
+	price := marketPrice(market)
+	switch alert.Condition {
+	case models.AlertChange24h:
+		return decimal.NewFromFloat(market.Change24h), true
+	case models.AlertPercentMove:
+		at := now.Add(-time.Duration(alert.WindowMinutes) * time.Minute)
+		candles, err := s.CandleRepo.GetRange(alert.CoinID, models.CandleInterval1m, at.Add(-2*time.Minute), at)
+		if err != nil || len(candles) == 0 || candles[len(candles)-1].Close <= 0 {
+			return decimal.Zero, false
+		}
+		past := decimal.NewFromFloat(candles[len(candles)-1].Close)
+		return price.Div(past).Sub(decimal.NewFromInt(1)).Mul(decimal.NewFromInt(100)), true
+	default:
+		return price, true
+	}
+}
+
+func conditionHolds(alert *models.Alert, value decimal.Decimal) bool {
+	switch alert.Condition {
+	case models.AlertPriceAbove:
+		return value.GreaterThanOrEqual(alert.Threshold)
+	case models.AlertPriceBelow:
+		return value.LessThanOrEqual(alert.Threshold)
+	default:
+		// Percentages: negative thresholds watch for falls
+		if alert.Threshold.IsNegative() {
+			return value.LessThanOrEqual(alert.Threshold)
+		}
+		return value.GreaterThanOrEqual(alert.Threshold)
+	}
+}
+
+func (s *AlertService) coolingDown(alert *models.Alert, now time.Time) bool {
+	cooldown := time.Duration(alert.CooldownMinutes) * time.Minute
+	return alert.LastTriggeredAt != nil && now.Sub(*alert.LastTriggeredAt) < cooldown
+}
+
+// fire records the firing and delivers it. Webhooks are sent in the background so a
+// slow receiver can't hold up the other alerts.
+func (s *AlertService) fire(alert *models.Alert, value, price decimal.Decimal, now time.Time) error {
+	alert.Armed = false
+	alert.TriggerCount++
+	alert.LastTriggeredAt = &now
+	if alert.Mode == models.AlertModeOnce {
+		alert.Status = models.AlertStatusTriggered
+	}
+	if err := s.Repo.Update(alert); err != nil {
+		return err
+	}
+
+	event := dto.AlertEventDTO{
+		AlertID:     alert.ID,
+		CoinID:      alert.CoinID,
+		Condition:   alert.Condition,
+		Threshold:   alert.Threshold,
+		Value:       value.Round(8),
+		Price:       price,
+		Note:        alert.Note,
+		TriggeredAt: now,
+	}
+	s.hub.publish(alert.UserID, event)
+	_ = s.LedgerService.Append(alert.UserID, "AlertTriggered", event)
+
+	if alert.WebhookURL != "" {
+		webhookURL, secret, userID := alert.WebhookURL, alert.WebhookSecret, alert.UserID
+		go func() {
+			if err := deliverWebhook(s.client, webhookURL, secret, event); err != nil {
+				fmt.Printf("⚠️ Alert %d webhook failed: %v\n", event.AlertID, err)
+				_ = s.LedgerService.Append(userID, "AlertWebhookFailed", fmt.Sprintf("Alert %d webhook to %s failed: %v", event.AlertID, webhookURL, err))
+			}
+		}()
+	}
+	return nil
+}
+
+func toAlertDTO(alert *models.Alert) dto.AlertDTO {
+	return dto.AlertDTO{
+		ID:              alert.ID,
+		CoinID:          alert.CoinID,
+		Condition:       alert.Condition,
+		Threshold:       alert.Threshold,
+		WindowMinutes:   alert.WindowMinutes,
+		Mode:            alert.Mode,
+		CooldownMinutes: alert.CooldownMinutes,
+		Status:          alert.Status,
+		Armed:           alert.Armed,
+		TriggerCount:    alert.TriggerCount,
+		LastTriggeredAt: alert.LastTriggeredAt,
+		Note:            alert.Note,
+		WebhookURL:      alert.WebhookURL,
+		CreatedAt:       alert.CreatedAt,
+	}
+}
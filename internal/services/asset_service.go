@@ -4,65 +4,188 @@ import (
 	"ares_api/internal/api/dto"
 	repo "ares_api/internal/interfaces/repository"
 	service "ares_api/internal/interfaces/service"
+	"ares_api/internal/models"
+	"errors"
+	"fmt"
+	"strings"
 	"sync"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type AssetServiceImpl struct {
-	Repo       repo.AssetRepository
-	coinCache  []dto.CoinDTO
-	cacheMutex sync.RWMutex
-	lastFetch  time.Time
-	cacheTTL   time.Duration
+	Repo     repo.AssetRepository
+	CoinRepo repo.CoinRepository
+
+	refreshMu sync.Mutex // one catalog refresh at a time
 }
 
-func NewAssetService(r repo.AssetRepository) service.AssetService {
+func NewAssetService(r repo.AssetRepository, c repo.CoinRepository) service.AssetService {
 	return &AssetServiceImpl{
 		Repo:     r,
-		cacheTTL: 1 * time.Hour, 
+		CoinRepo: c,
 	}
 }
 
-// GetAllCoins with caching
+// GetAllCoins lists the coin catalog, ranked coins first
 func (s *AssetServiceImpl) GetAllCoins(limit int) ([]dto.CoinDTO, error) {
-	s.cacheMutex.RLock()
-	if time.Since(s.lastFetch) < s.cacheTTL && len(s.coinCache) > 0 {
-		cached := s.coinCache
-		s.cacheMutex.RUnlock()
-		if limit > 0 && limit < len(cached) {
-			return cached[:limit], nil
+	if err := s.ensureCatalog(); err != nil {
+		return nil, err
+	}
+	coins, err := s.CoinRepo.List(limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]dto.CoinDTO, 0, len(coins))
+	for _, c := range coins {
+		res = append(res, toCoinDTO(c))
+	}
+	return res, nil
+}
+
+// RefreshCatalog replaces the stored catalog with the provider's coin list and returns
+// the number of coins listed. Coins the provider no longer lists are removed.
+func (s *AssetServiceImpl) RefreshCatalog() (int, error) {
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+	return s.refreshCatalog()
+}
+
+func (s *AssetServiceImpl) refreshCatalog() (int, error) {
+	refreshedAt := time.Now()
+	listed, err := s.Repo.FetchAllCoins()
+	if err != nil {
+		return 0, err
+	}
+	if len(listed) == 0 {
+		return 0, fmt.Errorf("price provider listed no coins") // keep the catalog we have
+	}
+
+	// Coins of the market universe are ranked by market cap. Ranks are a nicety for
+	// ordering; a failed fetch leaves every coin unranked.
+	ranks := map[string]int{}
+	if top, err := s.Repo.FetchMarketUniverse(); err == nil {
+		for i, m := range top {
+			ranks[m.ID] = i + 1
 		}
-		return cached, nil
 	}
-	s.cacheMutex.RUnlock()
 
-	coins, err := s.Repo.FetchAllCoins()
+	coins := make([]models.Coin, 0, len(listed))
+	seen := map[string]bool{}
+	for _, c := range listed {
+		if c.ID == "" || seen[c.ID] {
+			continue
+		}
+		seen[c.ID] = true
+		coins = append(coins, models.Coin{
+			ID:            c.ID,
+			Symbol:        strings.ToLower(c.Symbol),
+			Name:          c.Name,
+			MarketCapRank: ranks[c.ID],
+			UpdatedAt:     refreshedAt,
+		})
+	}
+	if err := s.CoinRepo.Upsert(coins); err != nil {
+		return 0, err
+	}
+	if _, err := s.CoinRepo.DeleteStale(refreshedAt); err != nil {
+		return 0, err
+	}
+	return len(coins), nil
+}
+
+// ensureCatalog fills an empty catalog, so a fresh database serves coins before the
+// first scheduled refresh
+func (s *AssetServiceImpl) ensureCatalog() error {
+	count, err := s.CoinRepo.Count()
+	if err != nil || count > 0 {
+		return err
+	}
+	s.refreshMu.Lock()
+	defer s.refreshMu.Unlock()
+	if count, err = s.CoinRepo.Count(); err != nil || count > 0 {
+		return err // filled while we waited
+	}
+	_, err = s.refreshCatalog()
+	return err
+}
+
+// SearchCoins ranks catalog coins against query, best match first
+func (s *AssetServiceImpl) SearchCoins(query string, limit int) ([]dto.CoinSearchResultDTO, error) {
+	query = strings.ToLower(strings.TrimSpace(query))
+	if query == "" {
+		return nil, fmt.Errorf("%w: q is required", service.ErrInvalidCoinQuery)
+	}
+	if err := s.ensureCatalog(); err != nil {
+		return nil, err
+	}
+	candidates, err := s.CoinRepo.SearchCandidates(query)
 	if err != nil {
 		return nil, err
 	}
+	return rankCoins(query, candidates, limit), nil
+}
+
+// ResolveCoin looks an order's coin up by id when it has one, otherwise by symbol. A
+// symbol shared by several coins has to be disambiguated with the coin id.
+func (s *AssetServiceImpl) ResolveCoin(coinID, symbol string) (*dto.CoinDTO, error) {
+	coinID = strings.ToLower(strings.TrimSpace(coinID))
+	symbol = strings.TrimSpace(symbol)
+	if coinID == "" && symbol == "" {
+		return nil, fmt.Errorf("%w: coin_id or symbol is required", service.ErrInvalidCoinQuery)
+	}
+	if err := s.ensureCatalog(); err != nil {
+		return nil, err
+	}
 
-	s.cacheMutex.Lock()
-	s.coinCache = coins
-	s.lastFetch = time.Now()
-	s.cacheMutex.Unlock()
+	if coinID != "" {
+		coin, err := s.CoinRepo.GetByID(coinID)
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, fmt.Errorf("%w: %s", service.ErrUnknownCoin, coinID)
+		}
+		if err != nil {
+			return nil, err
+		}
+		if symbol != "" && !strings.EqualFold(symbol, coin.Symbol) {
+			return nil, fmt.Errorf("%w: %s is %s, not %s", service.ErrSymbolMismatch, coin.ID, strings.ToUpper(coin.Symbol), strings.ToUpper(symbol))
+		}
+		res := toCoinDTO(*coin)
+		return &res, nil
+	}
 
-	if limit > 0 && limit < len(coins) {
-		return coins[:limit], nil
+	coins, err := s.CoinRepo.GetBySymbol(symbol)
+	if err != nil {
+		return nil, err
 	}
-	return coins, nil
+	switch len(coins) {
+	case 0:
+		return nil, fmt.Errorf("%w: symbol %s", service.ErrUnknownCoin, strings.ToUpper(symbol))
+	case 1:
+		res := toCoinDTO(coins[0])
+		return &res, nil
+	}
+	ids := make([]string, 0, len(coins))
+	for i, c := range coins {
+		if i == 5 {
+			ids = append(ids, "...")
+			break
+		}
+		ids = append(ids, c.ID)
+	}
+	return nil, fmt.Errorf("%w: %s is %s; pass a coin_id", service.ErrAmbiguousSymbol, strings.ToUpper(symbol), strings.Join(ids, ", "))
 }
 
-// GetCoinMarket returns market data for a single coin
-func (s *AssetServiceImpl) GetCoinMarket(id string , vsCurrency string) (*dto.CoinMarketDTO, error) {
-	return s.Repo.FetchCoinMarket(id , vsCurrency)
+func toCoinDTO(c models.Coin) dto.CoinDTO {
+	return dto.CoinDTO{ID: c.ID, Symbol: c.Symbol, Name: c.Name}
 }
 
-// GetTopMovers returns top movers with optional limit
-func (s *AssetServiceImpl) GetTopMovers(limit int) ([]dto.TopMoverDTO, error) {
-	return s.Repo.FetchTopMovers(limit)
+// GetCoinMarket returns market data for a single coin
+func (s *AssetServiceImpl) GetCoinMarket(id string, vsCurrency string) (*dto.CoinMarketDTO, error) {
+	return s.Repo.FetchCoinMarket(id, vsCurrency)
 }
 
 // GetSupportedVSCurrencies returns a list of supported virtual currencies
 func (s *AssetServiceImpl) GetSupportedVSCurrencies() ([]string, error) {
-    return s.Repo.FetchSupportedVSCurrencies()
+	return s.Repo.FetchSupportedVSCurrencies()
 }
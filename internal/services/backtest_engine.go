@@ -0,0 +1,264 @@
+package services
+
+import (
+	"ares_api/internal/api/dto"
+	service "ares_api/internal/interfaces/service"
+	"ares_api/internal/models"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// backtestBroker is the simulated account a backtest trades against. Fills are priced
+// by quoteExecution and settled with fillCashFlow, the same as live orders in
+// TradeService; only the balances live in memory instead of the database.
+type backtestBroker struct {
+	runID     uint
+	schedule  FeeSchedule
+	cash      decimal.Decimal
+	positions map[string]*position
+	prices    map[string]decimal.Decimal // latest close of each coin
+	tripPnL   map[string]decimal.Decimal // realized minus fees since the coin was last flat
+
+	trades     []models.BacktestTrade
+	fees       decimal.Decimal
+	realized   decimal.Decimal
+	roundTrips int
+	wins       int
+}
+
+func newBacktestBroker(runID uint, schedule FeeSchedule, cash decimal.Decimal) *backtestBroker {
+	return &backtestBroker{
+		runID:     runID,
+		schedule:  schedule,
+		cash:      cash,
+		positions: map[string]*position{},
+		prices:    map[string]decimal.Decimal{},
+		tripPnL:   map[string]decimal.Decimal{},
+	}
+}
+
+func (b *backtestBroker) position(coinID string) *position {
+	p, ok := b.positions[coinID]
+	if !ok {
+		p = newPosition(CostBasisFIFO)
+		b.positions[coinID] = p
+	}
+	return p
+}
+
+// market fills a market order at the coin's latest close as a taker. Candles carry no
+// market cap, so price impact only applies under schedules with a configured depth.
+// It reports false when the account can't cover the order.
+func (b *backtestBroker) market(at time.Time, coinID, side string, quantity decimal.Decimal) bool {
+	quantity = models.RoundQuantity(coinID, quantity)
+	price := b.prices[coinID]
+	if !quantity.IsPositive() || !price.IsPositive() {
+		return false
+	}
+	quote := quoteExecution(b.schedule, models.CurrencyUSD, side, quantity, price, decimal.Zero, service.LiquidityTaker, decimal.Zero)
+	cashFlow := fillCashFlow(models.CurrencyUSD, side, quantity, quote.Price, quote.Fee)
+
+	pos := b.position(coinID)
+	trade := models.BacktestTrade{
+		RunID:      b.runID,
+		ExecutedAt: at,
+		CoinID:     coinID,
+		Side:       side,
+		Quantity:   quantity,
+		Price:      quote.Price,
+		Fee:        quote.Fee,
+	}
+	switch side {
+	case "buy":
+		if b.cash.Add(cashFlow).IsNegative() {
+			return false
+		}
+		pos.buy(quantity, quote.Price)
+	case "sell":
+		if pos.quantity.LessThan(quantity) {
+			return false
+		}
+		trade.RealizedPnL = models.RoundCash(pos.sell(quantity, quote.Price))
+		b.realized = b.realized.Add(trade.RealizedPnL)
+		b.tripPnL[coinID] = b.tripPnL[coinID].Add(trade.RealizedPnL)
+	}
+
+	b.cash = b.cash.Add(cashFlow)
+	b.fees = b.fees.Add(quote.Fee)
+	b.tripPnL[coinID] = b.tripPnL[coinID].Sub(quote.Fee)
+	b.trades = append(b.trades, trade)
+
+	// Position back to flat closes the round trip
+	if side == "sell" && pos.quantity.IsZero() {
+		b.roundTrips++
+		if b.tripPnL[coinID].IsPositive() {
+			b.wins++
+		}
+		b.tripPnL[coinID] = decimal.Zero
+	}
+	return true
+}
+
+// buyUSD buys as much of the coin as usd covers, fee included, capped by the cash on hand
+func (b *backtestBroker) buyUSD(at time.Time, coinID string, usd decimal.Decimal) bool {
+	quantity := quantityForBudget(b.schedule, models.CurrencyUSD, coinID, b.prices[coinID], decimal.Zero, decimal.Min(usd, b.cash))
+	return b.market(at, coinID, "buy", quantity)
+}
+
+// sellAll closes the whole position in the coin
+func (b *backtestBroker) sellAll(at time.Time, coinID string) bool {
+	return b.market(at, coinID, "sell", b.position(coinID).quantity)
+}
+
+// equity marks every position to its latest close
+func (b *backtestBroker) equity(at time.Time) models.BacktestEquityPoint {
+	var holdings decimal.Decimal
+	for coinID, pos := range b.positions {
+		holdings = holdings.Add(models.RoundCash(pos.quantity.Mul(b.prices[coinID])))
+	}
+	return models.BacktestEquityPoint{
+		RunID:         b.runID,
+		At:            at,
+		Cash:          b.cash,
+		HoldingsValue: holdings,
+		TotalValue:    b.cash.Add(holdings),
+	}
+}
+
+// backtestStrategy decides what to trade at each candle close
+type backtestStrategy interface {
+	// onCandle is called once per coin and candle, after the broker's price for the
+	// coin is set to the close; closes holds every close of the coin so far
+	onCandle(b *backtestBroker, at time.Time, coinID string, closes []float64)
+}
+
+type buyAndHoldStrategy struct {
+	allocation decimal.Decimal // USD per coin
+}
+
+func (s *buyAndHoldStrategy) onCandle(b *backtestBroker, at time.Time, coinID string, closes []float64) {
+	if len(closes) == 1 {
+		b.buyUSD(at, coinID, s.allocation)
+	}
+}
+
+type dcaStrategy struct {
+	amount decimal.Decimal
+	every  int
+}
+
+func (s *dcaStrategy) onCandle(b *backtestBroker, at time.Time, coinID string, closes []float64) {
+	if (len(closes)-1)%s.every == 0 {
+		b.buyUSD(at, coinID, s.amount)
+	}
+}
+
+type smaCrossStrategy struct {
+	fast, slow int
+	allocation decimal.Decimal // USD per coin per entry
+}
+
+func (s *smaCrossStrategy) onCandle(b *backtestBroker, at time.Time, coinID string, closes []float64) {
+	n := len(closes)
+	if n <= s.slow {
+		return
+	}
+	// Signals only need the direction of the cross, so they run on floats
+	wasAbove := sma(closes[:n-1], s.fast) > sma(closes[:n-1], s.slow)
+	isAbove := sma(closes, s.fast) > sma(closes, s.slow)
+	holding := b.position(coinID).quantity.IsPositive()
+
+	switch {
+	case isAbove && !wasAbove && !holding:
+		b.buyUSD(at, coinID, s.allocation)
+	case !isAbove && wasAbove && holding:
+		b.sellAll(at, coinID)
+	}
+}
+
+// sma is the simple moving average of the last period values
+func sma(values []float64, period int) float64 {
+	var sum float64
+	for _, v := range values[len(values)-period:] {
+		sum += v
+	}
+	return sum / float64(period)
+}
+
+func newBacktestStrategy(run *models.BacktestRun, coins int) backtestStrategy {
+	allocation := models.RoundCash(run.StartingBalance.Div(decimal.NewFromInt(int64(coins))))
+	switch run.Strategy {
+	case models.StrategyDCA:
+		return &dcaStrategy{amount: run.Amount, every: run.Every}
+	case models.StrategySMACross:
+		return &smaCrossStrategy{fast: run.FastPeriod, slow: run.SlowPeriod, allocation: allocation}
+	default:
+		return &buyAndHoldStrategy{allocation: allocation}
+	}
+}
+
+// runBacktest replays candles through the run's strategy in open-time order, filling
+// at each candle's close, and fills in the run's summary. Coins without a candle at
+// some instant keep their previous close.
+func runBacktest(run *models.BacktestRun, candles map[string][]dto.CandleDTO) ([]models.BacktestTrade, []models.BacktestEquityPoint) {
+	coinIDs := strings.Split(run.CoinIDs, ",")
+	strategy := newBacktestStrategy(run, len(coinIDs))
+	broker := newBacktestBroker(run.ID, FeeSchedules[run.FeeSchedule], run.StartingBalance)
+
+	byTime := map[int64]map[string]dto.CandleDTO{}
+	var times []int64
+	for coinID, series := range candles {
+		for _, c := range series {
+			key := c.OpenTime.Unix()
+			if _, ok := byTime[key]; !ok {
+				byTime[key] = map[string]dto.CandleDTO{}
+				times = append(times, key)
+			}
+			byTime[key][coinID] = c
+		}
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+
+	length := models.CandleIntervals[run.Interval]
+	closes := map[string][]float64{}
+	equity := make([]models.BacktestEquityPoint, 0, len(times))
+	for _, key := range times {
+		var closedAt time.Time
+		for _, coinID := range coinIDs {
+			c, ok := byTime[key][coinID]
+			if !ok {
+				continue
+			}
+			closedAt = c.OpenTime.Add(length)
+			broker.prices[coinID] = models.RoundPrice(decimal.NewFromFloat(c.Close))
+			closes[coinID] = append(closes[coinID], c.Close)
+			strategy.onCandle(broker, closedAt, coinID, closes[coinID])
+		}
+		equity = append(equity, broker.equity(closedAt))
+	}
+
+	run.EndValue = run.StartingBalance
+	if len(equity) > 0 {
+		run.EndValue = equity[len(equity)-1].TotalValue
+	}
+	run.RealizedPnL = broker.realized
+	run.FeesPaid = broker.fees
+	run.TradeCount = len(broker.trades)
+	if broker.roundTrips > 0 {
+		run.WinRate = float64(broker.wins) / float64(broker.roundTrips) * 100
+	}
+	if run.StartingBalance.IsPositive() {
+		run.TotalReturn = run.EndValue.Div(run.StartingBalance).Sub(decimal.NewFromInt(1)).InexactFloat64()
+	}
+	values := make([]float64, 0, len(equity))
+	for _, p := range equity {
+		values = append(values, p.TotalValue.InexactFloat64())
+	}
+	run.MaxDrawdown = maxDrawdown(values)
+	run.Volatility, run.Sharpe, run.Sortino = riskMetrics(periodReturns(values), float64(year)/float64(length))
+
+	return broker.trades, equity
+}
@@ -0,0 +1,344 @@
+package services
+
+import (
+	"ares_api/internal/api/dto"
+	repository "ares_api/internal/interfaces/repository"
+	service "ares_api/internal/interfaces/service"
+	"ares_api/internal/models"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var _ service.BacktestService = &BacktestService{}
+
+const (
+	maxBacktestCoins   = 10
+	maxBacktestCandles = 10000 // per coin; a year of hourly candles fits
+	backtestQueueSize  = 100
+)
+
+// BacktestService queues backtest runs and executes them on a fixed pool of workers.
+// Runs only read stored candles and never touch live balances or orders.
+type BacktestService struct {
+	Repo          repository.BacktestRepository
+	CandleService service.CandleService
+	SettingsRepo  repository.SettingsRepository
+
+	queue chan uint
+}
+
+func NewBacktestService(r repository.BacktestRepository, c service.CandleService, st repository.SettingsRepository) *BacktestService {
+	return &BacktestService{
+		Repo:          r,
+		CandleService: c,
+		SettingsRepo:  st,
+		queue:         make(chan uint, backtestQueueSize),
+	}
+}
+
+// Start launches the workers and requeues runs a previous process left queued or
+// running. Runs are deterministic, so an interrupted run simply starts over.
+func (s *BacktestService) Start(workers int) {
+	for i := 0; i < workers; i++ {
+		go func() {
+			for runID := range s.queue {
+				s.execute(runID)
+			}
+		}()
+	}
+
+	pending, err := s.Repo.GetByStatus(models.BacktestStatusQueued, models.BacktestStatusRunning)
+	if err != nil {
+		fmt.Printf("⚠️ Failed to load pending backtests: %v\n", err)
+		return
+	}
+	go func() {
+		for _, run := range pending {
+			s.queue <- run.ID
+		}
+	}()
+}
+
+// Submit validates the request, stores it as a queued run and hands it to the workers
+func (s *BacktestService) Submit(userID uint, req dto.BacktestRequest) (*dto.BacktestRunDTO, error) {
+	run, err := s.newRun(userID, req)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.Repo.Create(run); err != nil {
+		return nil, err
+	}
+
+	select {
+	case s.queue <- run.ID:
+	default:
+		run.Status = models.BacktestStatusFailed
+		run.Error = "backtest queue is full, try again later"
+		_ = s.Repo.Update(run)
+	}
+
+	res := toBacktestRunDTO(run)
+	return &res, nil
+}
+
+// newRun checks a request and fills in its defaults
+func (s *BacktestService) newRun(userID uint, req dto.BacktestRequest) (*models.BacktestRun, error) {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", service.ErrInvalidBacktest, fmt.Sprintf(format, args...))
+	}
+
+	run := &models.BacktestRun{
+		UserID:          userID,
+		Status:          models.BacktestStatusQueued,
+		Strategy:        req.Strategy.Type,
+		Interval:        req.Interval,
+		From:            req.From.UTC(),
+		To:              req.To.UTC(),
+		StartingBalance: models.RoundCash(req.StartingBalance),
+		FeeSchedule:     req.FeeSchedule,
+	}
+
+	switch run.Strategy {
+	case models.StrategyBuyAndHold:
+	case models.StrategyDCA:
+		run.Amount = models.RoundCash(req.Strategy.Amount)
+		run.Every = req.Strategy.Every
+		if run.Every == 0 {
+			run.Every = 1
+		}
+		if !run.Amount.IsPositive() || run.Every < 0 {
+			return nil, invalid("dca needs a positive amount and every")
+		}
+	case models.StrategySMACross:
+		run.FastPeriod, run.SlowPeriod = req.Strategy.FastPeriod, req.Strategy.SlowPeriod
+		if run.FastPeriod < 1 || run.SlowPeriod <= run.FastPeriod {
+			return nil, invalid("sma_cross needs 1 <= fast_period < slow_period")
+		}
+	default:
+		return nil, invalid("unknown strategy %q: must be buy_and_hold, dca or sma_cross", req.Strategy.Type)
+	}
+
+	seen := map[string]bool{}
+	var coinIDs []string
+	for _, id := range req.CoinIDs {
+		id = strings.ToLower(strings.TrimSpace(id))
+		if id != "" && !seen[id] {
+			seen[id] = true
+			coinIDs = append(coinIDs, id)
+		}
+	}
+	if len(coinIDs) == 0 || len(coinIDs) > maxBacktestCoins {
+		return nil, invalid("coin_ids must name between 1 and %d coins", maxBacktestCoins)
+	}
+	run.CoinIDs = strings.Join(coinIDs, ",")
+
+	if run.Interval == "" {
+		run.Interval = models.CandleInterval1d
+	}
+	length, ok := models.CandleIntervals[run.Interval]
+	if !ok {
+		return nil, service.ErrInvalidInterval
+	}
+	if now := time.Now().UTC(); run.To.After(now) {
+		run.To = now
+	}
+	run.From = run.From.Truncate(length)
+	if !run.From.Before(run.To) {
+		return nil, invalid("from must be before to and in the past")
+	}
+	if int(run.To.Sub(run.From)/length)+1 > maxBacktestCandles {
+		return nil, invalid("range spans more than %d %s candles", maxBacktestCandles, run.Interval)
+	}
+
+	if run.StartingBalance.IsZero() {
+		run.StartingBalance = DefaultBalance
+	}
+	if !run.StartingBalance.IsPositive() {
+		return nil, invalid("starting_balance must be positive")
+	}
+
+	if run.FeeSchedule == "" {
+		run.FeeSchedule = userFeeSchedule(s.SettingsRepo, userID).Name
+	}
+	if _, ok := FeeSchedules[run.FeeSchedule]; !ok {
+		return nil, invalid("unknown fee_schedule %q: must be one of %s", run.FeeSchedule, strings.Join(FeeScheduleNames(), ", "))
+	}
+	return run, nil
+}
+
+// execute runs one queued backtest to completion and records the outcome
+func (s *BacktestService) execute(runID uint) {
+	run, err := s.Repo.GetRun(runID)
+	if err != nil {
+		fmt.Printf("⚠️ Backtest %d could not be loaded: %v\n", runID, err)
+		return
+	}
+	if run.Status != models.BacktestStatusQueued && run.Status != models.BacktestStatusRunning {
+		return
+	}
+
+	started := time.Now()
+	run.Status = models.BacktestStatusRunning
+	run.StartedAt = &started
+	if err := s.Repo.Update(run); err != nil {
+		fmt.Printf("⚠️ Backtest %d could not be started: %v\n", runID, err)
+		return
+	}
+
+	candles, err := s.loadCandles(run)
+	if err != nil {
+		s.fail(run, err)
+		return
+	}
+	trades, equity := runBacktest(run, candles)
+
+	finished := time.Now()
+	run.Status = models.BacktestStatusCompleted
+	run.FinishedAt = &finished
+	if err := s.Repo.SaveResults(run, trades, equity); err != nil {
+		s.fail(run, err)
+	}
+}
+
+func (s *BacktestService) fail(run *models.BacktestRun, err error) {
+	finished := time.Now()
+	run.Status = models.BacktestStatusFailed
+	run.Error = err.Error()
+	run.FinishedAt = &finished
+	if err := s.Repo.Update(run); err != nil {
+		fmt.Printf("⚠️ Backtest %d failure could not be recorded: %v\n", run.ID, err)
+	}
+}
+
+// loadCandles reads each coin's candles for the run through the candle service, a
+// chunk at a time, so closed candles missing from the store are backfilled first
+func (s *BacktestService) loadCandles(run *models.BacktestRun) (map[string][]dto.CandleDTO, error) {
+	length := models.CandleIntervals[run.Interval]
+	chunk := time.Duration(maxCandles-1) * length
+
+	candles := map[string][]dto.CandleDTO{}
+	for _, coinID := range strings.Split(run.CoinIDs, ",") {
+		for from := run.From; !from.After(run.To); from = from.Add(chunk + length) {
+			to := from.Add(chunk)
+			if to.After(run.To) {
+				to = run.To
+			}
+			res, err := s.CandleService.GetCandles(coinID, run.Interval, from, to)
+			if err != nil {
+				return nil, fmt.Errorf("failed to load %s candles for %s: %w", run.Interval, coinID, err)
+			}
+			candles[coinID] = append(candles[coinID], res.Candles...)
+		}
+		if len(candles[coinID]) == 0 {
+			return nil, fmt.Errorf("no %s candles for %s between %s and %s", run.Interval, coinID,
+				run.From.Format(time.RFC3339), run.To.Format(time.RFC3339))
+		}
+	}
+	return candles, nil
+}
+
+// Get returns one of the user's runs with its trades and equity curve once complete
+func (s *BacktestService) Get(userID, runID uint) (*dto.BacktestRunDTO, error) {
+	run, err := s.Repo.GetByID(userID, runID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, service.ErrBacktestNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res := toBacktestRunDTO(run)
+	if run.Status != models.BacktestStatusCompleted {
+		return &res, nil
+	}
+
+	trades, err := s.Repo.GetTrades(run.ID)
+	if err != nil {
+		return nil, err
+	}
+	equity, err := s.Repo.GetEquity(run.ID)
+	if err != nil {
+		return nil, err
+	}
+	res.Trades = make([]dto.BacktestTradeDTO, 0, len(trades))
+	for _, t := range trades {
+		res.Trades = append(res.Trades, dto.BacktestTradeDTO{
+			ExecutedAt:  t.ExecutedAt,
+			CoinID:      t.CoinID,
+			Side:        t.Side,
+			Quantity:    t.Quantity,
+			Price:       t.Price,
+			Fee:         t.Fee,
+			RealizedPnL: t.RealizedPnL,
+		})
+	}
+	res.Equity = make([]dto.EquityPointDTO, 0, len(equity))
+	for _, p := range equity {
+		res.Equity = append(res.Equity, dto.EquityPointDTO{
+			TakenAt:       p.At,
+			Cash:          p.Cash,
+			HoldingsValue: p.HoldingsValue,
+			TotalValue:    p.TotalValue,
+		})
+	}
+	return &res, nil
+}
+
+// List returns the user's most recent runs without their trades or equity
+func (s *BacktestService) List(userID uint, limit int) ([]dto.BacktestRunDTO, error) {
+	runs, err := s.Repo.GetByUser(userID, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]dto.BacktestRunDTO, 0, len(runs))
+	for i := range runs {
+		res = append(res, toBacktestRunDTO(&runs[i]))
+	}
+	return res, nil
+}
+
+func toBacktestRunDTO(run *models.BacktestRun) dto.BacktestRunDTO {
+	res := dto.BacktestRunDTO{
+		ID:     run.ID,
+		Status: run.Status,
+		Error:  run.Error,
+		Strategy: dto.BacktestStrategyDTO{
+			Type:       run.Strategy,
+			FastPeriod: run.FastPeriod,
+			SlowPeriod: run.SlowPeriod,
+			Every:      run.Every,
+		},
+		CoinIDs:         strings.Split(run.CoinIDs, ","),
+		Interval:        run.Interval,
+		From:            run.From,
+		To:              run.To,
+		StartingBalance: run.StartingBalance,
+		FeeSchedule:     run.FeeSchedule,
+		CreatedAt:       run.CreatedAt,
+		StartedAt:       run.StartedAt,
+		FinishedAt:      run.FinishedAt,
+	}
+	if run.Strategy == models.StrategyDCA {
+		res.Strategy.Amount = run.Amount
+	}
+	if run.Status == models.BacktestStatusCompleted {
+		res.Summary = &dto.BacktestSummaryDTO{
+			StartingBalance: run.StartingBalance,
+			EndValue:        run.EndValue,
+			RealizedPnL:     run.RealizedPnL,
+			FeesPaid:        run.FeesPaid,
+			TradeCount:      run.TradeCount,
+			WinRate:         run.WinRate,
+			TotalReturn:     run.TotalReturn,
+			MaxDrawdown:     run.MaxDrawdown,
+			Volatility:      run.Volatility,
+			Sharpe:          run.Sharpe,
+			Sortino:         run.Sortino,
+		}
+	}
+	return res
+}
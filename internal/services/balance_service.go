@@ -4,45 +4,256 @@ import (
 	"ares_api/internal/api/dto"
 	 repository "ares_api/internal/interfaces/repository"
 	"ares_api/internal/interfaces/service"
+	"ares_api/internal/models"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
 )
 
-const DefaultBalance = 10000.0 // Every user starts with 10k USD
+var DefaultBalance = decimal.NewFromInt(10000) // Every user starts with 10k USD
 
 type BalanceServiceImpl struct {
-	Repo repository.BalanceRepository
+	Repo          repository.BalanceRepository
+	HoldingRepo   repository.HoldingRepository
+	PortfolioRepo repository.PortfolioRepository
+	AssetRepo     repository.AssetRepository
+	SettingsRepo  repository.SettingsRepository
+	TxManager     repository.TxManager
+}
+
+func NewBalanceService(r repository.BalanceRepository, h repository.HoldingRepository, p repository.PortfolioRepository, a repository.AssetRepository, st repository.SettingsRepository, tx repository.TxManager) service.BalanceService {
+	return &BalanceServiceImpl{Repo: r, HoldingRepo: h, PortfolioRepo: p, AssetRepo: a, SettingsRepo: st, TxManager: tx}
 }
 
-func NewBalanceService(r repository.BalanceRepository) service.BalanceService {
-	return &BalanceServiceImpl{Repo: r}
+func toBalanceDTO(b *models.Balance) *dto.BalanceDTO {
+	return &dto.BalanceDTO{UserID: b.UserID, PortfolioID: b.PortfolioID, Asset: b.Asset, Amount: b.Amount, Reserved: b.Reserved}
 }
 
-func (s *BalanceServiceImpl) GetUSDBalance(userID uint) (*dto.BalanceDTO, error) {
-	b, err := s.Repo.GetUSDBalance(userID)
+// GetBalance returns the portfolio's cash in currency; a currency never held reads as zero
+func (s *BalanceServiceImpl) GetBalance(userID, portfolioID uint, currency string) (*dto.BalanceDTO, error) {
+	currency, err := parseCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+	portfolio, err := resolvePortfolio(s.PortfolioRepo, userID, portfolioID, false)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.Repo.GetBalance(portfolio.ID, currency)
+	if errors.Is(err, gorm.ErrRecordNotFound) && currency != models.CurrencyUSD {
+		return &dto.BalanceDTO{UserID: userID, PortfolioID: portfolio.ID, Asset: currency}, nil
+	}
 	if err != nil {
 		return nil, err
 	}
-	return &dto.BalanceDTO{UserID: b.UserID, Asset: b.Asset, Amount: b.Amount}, nil
+	return toBalanceDTO(b), nil
 }
 
-func (s *BalanceServiceImpl) UpdateUSDBalance(userID uint, delta float64) (*dto.BalanceDTO, error) {
-	b, err := s.Repo.UpdateUSDBalance(userID, delta)
+func (s *BalanceServiceImpl) UpdateBalance(userID, portfolioID uint, currency string, delta decimal.Decimal) (*dto.BalanceDTO, error) {
+	currency, err := parseCurrency(currency)
 	if err != nil {
 		return nil, err
 	}
-	return &dto.BalanceDTO{UserID: b.UserID, Asset: b.Asset, Amount: b.Amount}, nil
+	portfolio, err := resolvePortfolio(s.PortfolioRepo, userID, portfolioID, true)
+	if err != nil {
+		return nil, err
+	}
+	b, err := s.Repo.UpdateBalance(userID, portfolio.ID, currency, models.RoundAmount(currency, delta))
+	if errors.Is(err, gorm.ErrInvalidData) {
+		return nil, fmt.Errorf("%w in %s", service.ErrInsufficientFunds, currency)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toBalanceDTO(b), nil
 }
 
-func (s *BalanceServiceImpl) ResetUSDBalance(userID uint) (*dto.BalanceDTO, error) {
-	if err := s.Repo.ResetUSDBalance(userID, DefaultBalance); err != nil {
+// ResetBalance puts the portfolio back on the default USD balance and empties the other
+// currencies. Cash that open buy orders hold stays put, so USD can end above the default.
+func (s *BalanceServiceImpl) ResetBalance(userID, portfolioID uint) (*dto.BalanceDTO, error) {
+	portfolio, err := resolvePortfolio(s.PortfolioRepo, userID, portfolioID, true)
+	if err != nil {
 		return nil, err
 	}
-	return &dto.BalanceDTO{UserID: userID, Asset: "USD", Amount: DefaultBalance}, nil
+	if err := s.Repo.ResetBalances(portfolio.ID, DefaultBalance); err != nil {
+		return nil, err
+	}
+	b, err := s.Repo.GetBalance(portfolio.ID, models.CurrencyUSD)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &dto.BalanceDTO{UserID: userID, PortfolioID: portfolio.ID, Asset: models.CurrencyUSD, Amount: DefaultBalance}, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return toBalanceDTO(b), nil
 }
 
+// InitializeBalance funds the user's default portfolio, creating the portfolio on first use
 func (s *BalanceServiceImpl) InitializeBalance(userID uint) (*dto.BalanceDTO, error) {
-	b, err := s.Repo.CreateUSDBalance(userID, DefaultBalance)
+	var b *models.Balance
+	err := s.TxManager.Transaction(func(tx *gorm.DB) error {
+		portfolio, err := defaultPortfolio(s.PortfolioRepo.WithTx(tx), userID)
+		if err != nil {
+			return err
+		}
+		b, err = s.Repo.WithTx(tx).CreateUSDBalance(userID, portfolio.ID, DefaultBalance)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return toBalanceDTO(b), nil
+}
+
+// Convert exchanges an amount of one currency for another at the current rate. Only the
+// part of the source balance not reserved by open orders can be converted.
+func (s *BalanceServiceImpl) Convert(userID, portfolioID uint, req dto.ConvertRequest) (*dto.ConversionDTO, error) {
+	from, err := parseCurrency(req.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseCurrency(req.To)
+	if err != nil {
+		return nil, err
+	}
+	if from == to {
+		return nil, fmt.Errorf("%w: from and to are both %s", service.ErrInvalidConversion, from)
+	}
+	amount := models.RoundAmount(from, req.Amount)
+	if !amount.IsPositive() {
+		return nil, fmt.Errorf("%w: amount must be positive", service.ErrInvalidConversion)
+	}
+
+	portfolio, err := resolvePortfolio(s.PortfolioRepo, userID, portfolioID, true)
+	if err != nil {
+		return nil, err
+	}
+	rate, err := exchangeRate(s.AssetRepo, from, to)
+	if err != nil {
+		return nil, err
+	}
+	received := models.RoundAmount(to, amount.Mul(rate))
+	if !received.IsPositive() {
+		return nil, fmt.Errorf("%w: %s %s is worth less than the smallest unit of %s", service.ErrInvalidConversion, amount, from, to)
+	}
+
+	res := &dto.ConversionDTO{From: from, To: to, Amount: amount, Received: received, Rate: rate}
+	err = s.TxManager.Transaction(func(tx *gorm.DB) error {
+		legs := []struct {
+			asset string
+			delta decimal.Decimal
+		}{{from, amount.Neg()}, {to, received}}
+		// Lock the two rows in asset order so opposite conversions can't deadlock
+		sort.Slice(legs, func(i, j int) bool { return legs[i].asset < legs[j].asset })
+
+		balances := map[string]*models.Balance{}
+		for _, leg := range legs {
+			b, err := s.Repo.WithTx(tx).UpdateBalance(userID, portfolio.ID, leg.asset, leg.delta)
+			if errors.Is(err, gorm.ErrInvalidData) {
+				return fmt.Errorf("%w in %s", service.ErrInsufficientFunds, from)
+			}
+			if err != nil {
+				return err
+			}
+			balances[leg.asset] = b
+		}
+		res.Balances = []dto.BalanceDTO{*toBalanceDTO(balances[from]), *toBalanceDTO(balances[to])}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// displayCurrency resolves the currency a portfolio is reported in: the requested one,
+// else the one picked in the user's settings, else USD
+func (s *BalanceServiceImpl) displayCurrency(userID uint, requested string) (string, error) {
+	if requested == "" {
+		if setting, err := s.SettingsRepo.GetByUserID(userID); err == nil {
+			requested = setting.DisplayCurrency
+		}
+	}
+	return parseCurrency(requested)
+}
+
+// GetPortfolio returns every cash balance plus every coin holding of the portfolio
+// valued at the current market price, all reported in the display currency
+func (s *BalanceServiceImpl) GetPortfolio(userID, portfolioID uint, displayCurrency string) (*dto.PortfolioDTO, error) {
+	currency, err := s.displayCurrency(userID, displayCurrency)
+	if err != nil {
+		return nil, err
+	}
+	p, err := resolvePortfolio(s.PortfolioRepo, userID, portfolioID, false)
 	if err != nil {
 		return nil, err
 	}
-	return &dto.BalanceDTO{UserID: b.UserID, Asset: b.Asset, Amount: b.Amount}, nil
+
+	balances, err := s.Repo.GetBalances(p.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(balances) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	holdings, err := s.HoldingRepo.GetHoldings(p.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	portfolio := &dto.PortfolioDTO{
+		UserID:      userID,
+		PortfolioID: p.ID,
+		Name:        p.Name,
+		Currency:    currency,
+		Balances:    []dto.CashDTO{},
+		Holdings:    []dto.HoldingDTO{},
+	}
+	for _, b := range balances {
+		rate, err := exchangeRate(s.AssetRepo, b.Asset, currency)
+		if err != nil {
+			return nil, err
+		}
+		value := models.RoundAmount(currency, b.Amount.Mul(rate))
+		portfolio.Balances = append(portfolio.Balances, dto.CashDTO{
+			Asset:    b.Asset,
+			Amount:   b.Amount,
+			Reserved: b.Reserved,
+			Value:    value,
+		})
+		portfolio.Cash = portfolio.Cash.Add(value)
+	}
+
+	coinIDs := make([]string, 0, len(holdings))
+	for _, h := range holdings {
+		coinIDs = append(coinIDs, h.CoinID)
+	}
+	markets, err := s.AssetRepo.FetchCoinMarkets(coinIDs, strings.ToLower(currency))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch market prices: %w", err)
+	}
+	for _, h := range holdings {
+		coinMarket, ok := markets[h.CoinID]
+		if !ok {
+			return nil, fmt.Errorf("failed to fetch market price for %s", h.CoinID)
+		}
+		price := marketPrice(&coinMarket)
+		value := models.RoundAmount(currency, h.Quantity.Mul(price))
+		portfolio.Holdings = append(portfolio.Holdings, dto.HoldingDTO{
+			CoinID:   h.CoinID,
+			Symbol:   h.Symbol,
+			Quantity: h.Quantity,
+			Reserved: h.Reserved,
+			Price:    price,
+			Value:    value,
+		})
+		portfolio.HoldingsValue = portfolio.HoldingsValue.Add(value)
+	}
+	portfolio.TotalValue = portfolio.Cash.Add(portfolio.HoldingsValue)
+
+	return portfolio, nil
 }
@@ -0,0 +1,462 @@
+package services
+
+import (
+	"ares_api/internal/api/dto"
+	repository "ares_api/internal/interfaces/repository"
+	service "ares_api/internal/interfaces/service"
+	"ares_api/internal/models"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+var _ service.BotService = &BotService{}
+
+const (
+	maxGridLevels      = 100
+	defaultDipCooldown = 24 * time.Hour
+)
+
+// BotService stores users' bots and runs them. RunDue evaluates every running bot
+// against the current quotes and places its orders through the trade service, so
+// bot orders pay the same fees and pass the same balance checks as manual ones.
+type BotService struct {
+	Repo          repository.BotRepository
+	TradeService  service.TradeService
+	PortfolioRepo repository.PortfolioRepository
+	AssetRepo     repository.AssetRepository
+	SettingsRepo  repository.SettingsRepository
+
+	// mu serialises the runner with status changes so a bot paused or stopped
+	// mid-tick never places another order
+	mu sync.Mutex
+}
+
+func NewBotService(r repository.BotRepository, t service.TradeService, p repository.PortfolioRepository, a repository.AssetRepository, st repository.SettingsRepository) *BotService {
+	return &BotService{Repo: r, TradeService: t, PortfolioRepo: p, AssetRepo: a, SettingsRepo: st}
+}
+
+// Create validates and stores a bot; it stays paused until started. The bot trades in
+// the requested portfolio, or the user's default one.
+func (s *BotService) Create(userID uint, req dto.CreateBotRequest) (*dto.BotDTO, error) {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", service.ErrInvalidBot, fmt.Sprintf(format, args...))
+	}
+
+	portfolio, err := resolvePortfolio(s.PortfolioRepo, userID, req.PortfolioID, true)
+	if err != nil {
+		return nil, err
+	}
+
+	bot := &models.Bot{
+		UserID:      userID,
+		PortfolioID: portfolio.ID,
+		Name:        strings.TrimSpace(req.Name),
+		Type:        req.Type,
+		Status:      models.BotStatusPaused,
+		CoinID:      strings.ToLower(strings.TrimSpace(req.CoinID)),
+		Symbol:      req.Symbol,
+		Amount:      models.RoundCash(req.Amount),
+		Budget:      models.RoundCash(req.Budget),
+		GridBand:    -1,
+	}
+	if bot.CoinID == "" || bot.Symbol == "" {
+		return nil, invalid("coin_id and symbol are required")
+	}
+	if !bot.Amount.IsPositive() || bot.Budget.LessThan(bot.Amount) {
+		return nil, invalid("amount must be positive and no more than budget")
+	}
+
+	switch bot.Type {
+	case models.BotTypeDCA:
+		if _, err := cron.ParseStandard(req.Schedule); err != nil {
+			return nil, invalid("schedule: %v", err)
+		}
+		bot.Schedule = req.Schedule
+	case models.BotTypeGrid:
+		bot.GridLower = models.RoundPrice(req.GridLower)
+		bot.GridUpper = models.RoundPrice(req.GridUpper)
+		bot.GridLevels = req.GridLevels
+		if !bot.GridLower.IsPositive() || !bot.GridUpper.GreaterThan(bot.GridLower) {
+			return nil, invalid("grid needs 0 < grid_lower < grid_upper")
+		}
+		if bot.GridLevels < 2 || bot.GridLevels > maxGridLevels {
+			return nil, invalid("grid_levels must be between 2 and %d", maxGridLevels)
+		}
+	case models.BotTypeDip:
+		bot.DipPercent = req.DipPercent
+		if !bot.DipPercent.IsPositive() || bot.DipPercent.GreaterThan(decimal.NewFromInt(100)) {
+			return nil, invalid("dip_percent must be between 0 and 100")
+		}
+		cooldown := defaultDipCooldown
+		if req.Cooldown != "" {
+			parsed, err := time.ParseDuration(req.Cooldown)
+			if err != nil || parsed < time.Minute {
+				return nil, invalid("cooldown must be a duration of at least 1m")
+			}
+			cooldown = parsed
+		}
+		bot.CooldownMinutes = int(cooldown / time.Minute)
+	default:
+		return nil, invalid("unknown type %q: must be dca, grid or dip", req.Type)
+	}
+	if bot.Name == "" {
+		bot.Name = bot.Type + " " + bot.Symbol
+	}
+
+	if err := s.Repo.Create(bot); err != nil {
+		return nil, err
+	}
+	res := toBotDTO(bot)
+	return &res, nil
+}
+
+func (s *BotService) List(userID uint) ([]dto.BotDTO, error) {
+	bots, err := s.Repo.GetByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]dto.BotDTO, 0, len(bots))
+	for i := range bots {
+		res = append(res, toBotDTO(&bots[i]))
+	}
+	return res, nil
+}
+
+func (s *BotService) Get(userID, botID uint) (*dto.BotDTO, error) {
+	bot, err := s.getBot(userID, botID)
+	if err != nil {
+		return nil, err
+	}
+	res := toBotDTO(bot)
+	return &res, nil
+}
+
+// Start runs a paused bot. Grids re-anchor to the price at the next tick, so moves
+// made while paused aren't traded all at once; DCA schedules resume from now.
+func (s *BotService) Start(userID, botID uint) (*dto.BotDTO, error) {
+	return s.transition(userID, botID, func(bot *models.Bot) error {
+		if bot.Status == models.BotStatusStopped {
+			return service.ErrBotStopped
+		}
+		if bot.Status == models.BotStatusRunning {
+			return nil
+		}
+		bot.Status = models.BotStatusRunning
+		bot.GridBand = -1
+		bot.NextRunAt = nil
+		bot.LastError = ""
+		return nil
+	})
+}
+
+func (s *BotService) Pause(userID, botID uint) (*dto.BotDTO, error) {
+	return s.transition(userID, botID, func(bot *models.Bot) error {
+		if bot.Status == models.BotStatusStopped {
+			return service.ErrBotStopped
+		}
+		bot.Status = models.BotStatusPaused
+		return nil
+	})
+}
+
+// Stop retires a bot for good. Coins it bought stay in the account.
+func (s *BotService) Stop(userID, botID uint) (*dto.BotDTO, error) {
+	return s.transition(userID, botID, func(bot *models.Bot) error {
+		bot.Status = models.BotStatusStopped
+		bot.NextRunAt = nil
+		return nil
+	})
+}
+
+func (s *BotService) transition(userID, botID uint, change func(bot *models.Bot) error) (*dto.BotDTO, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bot, err := s.getBot(userID, botID)
+	if err != nil {
+		return nil, err
+	}
+	if err := change(bot); err != nil {
+		return nil, err
+	}
+	if err := s.Repo.Update(bot); err != nil {
+		return nil, err
+	}
+	res := toBotDTO(bot)
+	return &res, nil
+}
+
+func (s *BotService) GetTrades(userID, botID uint, limit int) ([]dto.BotTradeDTO, error) {
+	if _, err := s.getBot(userID, botID); err != nil {
+		return nil, err
+	}
+	trades, err := s.Repo.GetTrades(botID, limit)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]dto.BotTradeDTO, 0, len(trades))
+	for _, t := range trades {
+		res = append(res, dto.BotTradeDTO{
+			ID:        t.ID,
+			TradeID:   t.TradeID,
+			Side:      t.Side,
+			Quantity:  t.Quantity,
+			Price:     t.Price,
+			Fee:       t.Fee,
+			Reason:    t.Reason,
+			CreatedAt: t.CreatedAt,
+		})
+	}
+	return res, nil
+}
+
+func (s *BotService) getBot(userID, botID uint) (*models.Bot, error) {
+	bot, err := s.Repo.GetByID(userID, botID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, service.ErrBotNotFound
+	}
+	return bot, err
+}
+
+// RunDue evaluates every running bot once against one batch of quotes and returns
+// how many orders were placed. A bot's failed order is kept in its LastError.
+func (s *BotService) RunDue() (int, error) {
+	bots, err := s.Repo.GetRunning()
+	if err != nil || len(bots) == 0 {
+		return 0, err
+	}
+
+	seen := map[string]bool{}
+	var coinIDs []string
+	for _, bot := range bots {
+		if !seen[bot.CoinID] {
+			seen[bot.CoinID] = true
+			coinIDs = append(coinIDs, bot.CoinID)
+		}
+	}
+	markets, err := s.AssetRepo.FetchCoinMarkets(coinIDs, "usd")
+	if err != nil {
+		return 0, fmt.Errorf("failed to fetch market prices: %w", err)
+	}
+
+	placed := 0
+	for _, b := range bots {
+		n, err := s.runBot(b.UserID, b.ID, markets)
+		if err != nil {
+			fmt.Printf("⚠️ Bot %d error: %v\n", b.ID, err)
+		}
+		placed += n
+	}
+	return placed, nil
+}
+
+// runBot reloads the bot under the lock, so a status change since the tick began
+// wins, and applies its rule
+func (s *BotService) runBot(userID, botID uint, markets map[string]dto.CoinMarketDTO) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	bot, err := s.getBot(userID, botID)
+	if err != nil || bot.Status != models.BotStatusRunning {
+		return 0, err
+	}
+	market, ok := markets[bot.CoinID]
+	if !ok {
+		bot.LastError = "no market price for " + bot.CoinID
+		return 0, s.Repo.Update(bot)
+	}
+
+	now := time.Now()
+	var placed int
+	switch bot.Type {
+	case models.BotTypeDCA:
+		placed = s.runDCA(bot, &market, now)
+	case models.BotTypeGrid:
+		placed = s.runGrid(bot, &market, now)
+	case models.BotTypeDip:
+		placed = s.runDip(bot, &market, now)
+	}
+	return placed, s.Repo.Update(bot)
+}
+
+func (s *BotService) runDCA(bot *models.Bot, market *dto.CoinMarketDTO, now time.Time) int {
+	schedule, err := cron.ParseStandard(bot.Schedule)
+	if err != nil {
+		bot.LastError = "invalid schedule: " + err.Error()
+		return 0
+	}
+	due := bot.NextRunAt != nil && !now.Before(*bot.NextRunAt)
+	next := schedule.Next(now.UTC())
+	bot.NextRunAt = &next
+	if !due {
+		return 0
+	}
+	return s.buy(bot, market, bot.Amount, "scheduled buy", now)
+}
+
+func (s *BotService) runDip(bot *models.Bot, market *dto.CoinMarketDTO, now time.Time) int {
+	change := decimal.NewFromFloat(market.Change24h)
+	if change.GreaterThan(bot.DipPercent.Neg()) {
+		return 0
+	}
+	cooldown := time.Duration(bot.CooldownMinutes) * time.Minute
+	if bot.LastTradeAt != nil && now.Sub(*bot.LastTradeAt) < cooldown {
+		return 0
+	}
+	return s.buy(bot, market, bot.Amount, fmt.Sprintf("24h change %s%%", change.StringFixed(2)), now)
+}
+
+// runGrid trades the grid lines the price crossed since the last tick: one buy of
+// Amount per line crossed downwards and one open lot sold per line crossed upwards
+func (s *BotService) runGrid(bot *models.Bot, market *dto.CoinMarketDTO, now time.Time) int {
+	band := gridBand(bot, marketPrice(market))
+	previous := bot.GridBand
+	bot.GridBand = band
+	switch {
+	case previous < 0 || band == previous:
+		return 0
+	case band < previous:
+		lines := previous - band
+		amount := bot.Amount.Mul(decimal.NewFromInt(int64(lines)))
+		bot.GridLots += lines
+		placed := s.buy(bot, market, amount, fmt.Sprintf("grid: crossed %d line(s) down", lines), now)
+		if placed == 0 {
+			bot.GridLots -= lines
+		}
+		return placed
+	default:
+		lots := band - previous
+		if lots > bot.GridLots {
+			lots = bot.GridLots
+		}
+		if lots == 0 {
+			return 0
+		}
+		quantity := bot.Position
+		if lots < bot.GridLots {
+			quantity = models.RoundQuantity(bot.CoinID, bot.Position.Mul(decimal.NewFromInt(int64(lots))).Div(decimal.NewFromInt(int64(bot.GridLots))))
+		}
+		bot.GridLots -= lots
+		placed := s.sell(bot, quantity, fmt.Sprintf("grid: crossed %d line(s) up", band-previous), now)
+		if placed == 0 {
+			bot.GridLots += lots
+		}
+		return placed
+	}
+}
+
+// gridBand is the number of grid lines at or below price
+func gridBand(bot *models.Bot, price decimal.Decimal) int {
+	step := bot.GridUpper.Sub(bot.GridLower).Div(decimal.NewFromInt(int64(bot.GridLevels - 1)))
+	band := 0
+	for i := 0; i < bot.GridLevels; i++ {
+		if bot.GridLower.Add(step.Mul(decimal.NewFromInt(int64(i)))).GreaterThan(price) {
+			break
+		}
+		band++
+	}
+	return band
+}
+
+// buy spends up to usd on the coin, fee included, unless that would take the bot past
+// its budget. Bots that only buy are stopped once the budget can't cover a full buy.
+func (s *BotService) buy(bot *models.Bot, market *dto.CoinMarketDTO, usd decimal.Decimal, reason string, now time.Time) int {
+	remaining := bot.Budget.Sub(bot.Deployed)
+	if remaining.LessThan(usd) {
+		bot.LastError = fmt.Sprintf("budget exhausted: %s of %s deployed", bot.Deployed.StringFixed(models.CashScale), bot.Budget.StringFixed(models.CashScale))
+		if bot.Type != models.BotTypeGrid {
+			bot.Status = models.BotStatusStopped
+			bot.NextRunAt = nil
+		}
+		return 0
+	}
+
+	cost := userFeeSchedule(s.SettingsRepo, bot.UserID)
+	quantity := quantityForBudget(cost, models.CurrencyUSD, bot.CoinID, marketPrice(market), marketCap(market), usd)
+	if !quantity.IsPositive() {
+		bot.LastError = "amount is too small to buy any " + bot.Symbol
+		return 0
+	}
+	return s.place(bot, "buy", quantity, reason, now)
+}
+
+func (s *BotService) sell(bot *models.Bot, quantity decimal.Decimal, reason string, now time.Time) int {
+	if !quantity.IsPositive() {
+		return 0
+	}
+	return s.place(bot, "sell", quantity, reason, now)
+}
+
+// place sends a market order for the bot and records it in the bot's trade log together
+// with the bot's new state
+func (s *BotService) place(bot *models.Bot, side string, quantity decimal.Decimal, reason string, now time.Time) int {
+	res, err := s.TradeService.MarketOrder(bot.UserID, bot.PortfolioID, dto.MarketOrderRequest{
+		CoinID:   bot.CoinID,
+		Currency: "usd",
+		Symbol:   bot.Symbol,
+		Side:     side,
+		Quantity: quantity,
+	})
+	if err != nil {
+		bot.LastError = fmt.Sprintf("%s order failed: %v", side, err)
+		return 0
+	}
+
+	cashFlow := fillCashFlow(models.CurrencyUSD, side, res.FilledQuantity, res.AveragePrice, res.Fee)
+	bot.Deployed = decimal.Max(bot.Deployed.Sub(cashFlow), decimal.Zero)
+	if side == "buy" {
+		bot.Position = bot.Position.Add(res.FilledQuantity)
+	} else {
+		bot.Position = decimal.Max(bot.Position.Sub(res.FilledQuantity), decimal.Zero)
+	}
+	bot.LastTradeAt = &now
+	bot.LastError = ""
+
+	trade := &models.BotTrade{
+		BotID:    bot.ID,
+		TradeID:  res.ID,
+		Side:     side,
+		Quantity: res.FilledQuantity,
+		Price:    res.AveragePrice,
+		Fee:      res.Fee,
+		Reason:   reason,
+	}
+	if err := s.Repo.RecordTrade(bot, trade); err != nil {
+		fmt.Printf("⚠️ Bot %d trade %d could not be logged: %v\n", bot.ID, res.ID, err)
+	}
+	return 1
+}
+
+func toBotDTO(bot *models.Bot) dto.BotDTO {
+	return dto.BotDTO{
+		ID:              bot.ID,
+		PortfolioID:     bot.PortfolioID,
+		Name:            bot.Name,
+		Type:            bot.Type,
+		Status:          bot.Status,
+		CoinID:          bot.CoinID,
+		Symbol:          bot.Symbol,
+		Amount:          bot.Amount,
+		Budget:          bot.Budget,
+		Deployed:        bot.Deployed,
+		Position:        bot.Position,
+		Schedule:        bot.Schedule,
+		NextRunAt:       bot.NextRunAt,
+		GridLower:       bot.GridLower,
+		GridUpper:       bot.GridUpper,
+		GridLevels:      bot.GridLevels,
+		GridLots:        bot.GridLots,
+		DipPercent:      bot.DipPercent,
+		CooldownMinutes: bot.CooldownMinutes,
+		LastTradeAt:     bot.LastTradeAt,
+		LastError:       bot.LastError,
+		CreatedAt:       bot.CreatedAt,
+	}
+}
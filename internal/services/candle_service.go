@@ -0,0 +1,209 @@
+package services
+
+import (
+	"ares_api/internal/api/dto"
+	repository "ares_api/internal/interfaces/repository"
+	service "ares_api/internal/interfaces/service"
+	"ares_api/internal/models"
+	"fmt"
+	"sync"
+	"time"
+)
+
+var _ service.CandleService = &CandleService{}
+
+const (
+	defaultCandles = 100  // candles returned when the request gives no from
+	maxCandles     = 1000 // most candles one request may span
+
+	// backfillCooldown stops a coin and range from hitting the price source again
+	// while its gaps can't be filled, e.g. 1m bars older than the source's fine history
+	backfillCooldown = time.Minute
+)
+
+// CandleService builds OHLCV candles from two sources: the quotes the rest of the
+// app already fetches, sampled into the current bars by RecordQuotes, and the price
+// source's history, which backfills closed bars nobody sampled when they're requested.
+type CandleService struct {
+	Repo      repository.CandleRepository
+	AssetRepo repository.AssetRepository
+
+	mu           sync.Mutex
+	lastBackfill map[string]time.Time
+}
+
+func NewCandleService(r repository.CandleRepository, a repository.AssetRepository) *CandleService {
+	return &CandleService{Repo: r, AssetRepo: a, lastBackfill: map[string]time.Time{}}
+}
+
+// GetCandles returns the coin's bars opening between from and to. from is aligned
+// down to the interval; a zero to means now and a zero from means defaultCandles
+// bars before to. Closed bars missing from the store are backfilled first.
+func (s *CandleService) GetCandles(coinID, interval string, from, to time.Time) (*dto.CandlesDTO, error) {
+	if interval == "" {
+		interval = models.CandleInterval1h
+	}
+	length, ok := models.CandleIntervals[interval]
+	if !ok {
+		return nil, service.ErrInvalidInterval
+	}
+
+	now := time.Now().UTC()
+	if to.IsZero() || to.After(now) {
+		to = now
+	}
+	if from.IsZero() {
+		from = to.Add(-defaultCandles * length)
+	}
+	from, to = from.UTC().Truncate(length), to.UTC()
+	if from.After(to) {
+		return nil, service.ErrInvalidRange
+	}
+	if int(to.Sub(from)/length)+1 > maxCandles {
+		return nil, service.ErrRangeTooLarge
+	}
+
+	candles, err := s.Repo.GetRange(coinID, interval, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	if missing := missingBuckets(candles, from, to, length, now); len(missing) > 0 && s.claimBackfill(coinID, interval, from, now) {
+		if err := s.backfill(coinID, interval, length, missing); err != nil {
+			if len(candles) == 0 {
+				return nil, err
+			}
+			fmt.Printf("⚠️ Candle backfill for %s %s failed: %v\n", coinID, interval, err)
+		} else if candles, err = s.Repo.GetRange(coinID, interval, from, to); err != nil {
+			return nil, err
+		}
+	}
+
+	result := &dto.CandlesDTO{
+		CoinID:   coinID,
+		Interval: interval,
+		From:     from,
+		To:       to,
+		Candles:  make([]dto.CandleDTO, 0, len(candles)),
+	}
+	for _, c := range candles {
+		result.Candles = append(result.Candles, dto.CandleDTO{
+			OpenTime: c.OpenTime.UTC(),
+			Open:     c.Open,
+			High:     c.High,
+			Low:      c.Low,
+			Close:    c.Close,
+			Volume:   c.Volume,
+		})
+	}
+	return result, nil
+}
+
+// missingBuckets lists the open times of closed bars between from and to that candles lacks
+func missingBuckets(candles []models.Candle, from, to time.Time, length time.Duration, now time.Time) map[int64]bool {
+	have := make(map[int64]bool, len(candles))
+	for _, c := range candles {
+		have[c.OpenTime.Unix()] = true
+	}
+	missing := map[int64]bool{}
+	for t := from; !t.After(to) && !t.Add(length).After(now); t = t.Add(length) {
+		if !have[t.Unix()] {
+			missing[t.Unix()] = true
+		}
+	}
+	return missing
+}
+
+// claimBackfill reports whether a backfill may run for the coin, interval and range start now
+func (s *CandleService) claimBackfill(coinID, interval string, from, now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	key := fmt.Sprintf("%s:%s:%d", coinID, interval, from.Unix())
+	if last, ok := s.lastBackfill[key]; ok && now.Sub(last) < backfillCooldown {
+		return false
+	}
+	for k, last := range s.lastBackfill {
+		if now.Sub(last) >= backfillCooldown {
+			delete(s.lastBackfill, k)
+		}
+	}
+	s.lastBackfill[key] = now
+	return true
+}
+
+// backfill aggregates the source's price history into the missing bars. Bars the
+// history has no samples for stay missing.
+func (s *CandleService) backfill(coinID, interval string, length time.Duration, missing map[int64]bool) error {
+	var first, last int64
+	for t := range missing {
+		if first == 0 || t < first {
+			first = t
+		}
+		if t > last {
+			last = t
+		}
+	}
+
+	points, err := s.AssetRepo.FetchMarketChart(coinID, "usd", time.Unix(first, 0), time.Unix(last, 0).Add(length))
+	if err != nil {
+		return err
+	}
+
+	bars := map[int64]*models.Candle{}
+	var order []int64
+	for _, p := range points {
+		open := p.Time.UTC().Truncate(length)
+		if !missing[open.Unix()] {
+			continue
+		}
+		bar, ok := bars[open.Unix()]
+		if !ok {
+			bar = &models.Candle{CoinID: coinID, Interval: interval, OpenTime: open, Open: p.Price, High: p.Price, Low: p.Price}
+			bars[open.Unix()] = bar
+			order = append(order, open.Unix())
+		}
+		if p.Price > bar.High {
+			bar.High = p.Price
+		}
+		if p.Price < bar.Low {
+			bar.Low = p.Price
+		}
+		bar.Close = p.Price
+		bar.Volume = p.Volume
+	}
+
+	candles := make([]models.Candle, 0, len(order))
+	for _, open := range order {
+		candles = append(candles, *bars[open])
+	}
+	return s.Repo.Upsert(candles)
+}
+
+// RecordQuotes folds every fresh USD quote in the quote cache into the coin's current
+// 1m, 1h and 1d bars, and returns how many coins it recorded. Only coins someone has
+// quoted recently are sampled; the rest are backfilled on request.
+func (s *CandleService) RecordQuotes() (int, error) {
+	markets := s.AssetRepo.CachedCoinMarkets("usd")
+	for _, m := range markets {
+		at := m.LastUpdated
+		if at.IsZero() {
+			at = time.Now()
+		}
+		for interval, length := range models.CandleIntervals {
+			candle := &models.Candle{
+				CoinID:   m.ID,
+				Interval: interval,
+				OpenTime: at.UTC().Truncate(length),
+				Open:     m.PriceUSD,
+				High:     m.PriceUSD,
+				Low:      m.PriceUSD,
+				Close:    m.PriceUSD,
+			}
+			if err := s.Repo.Merge(candle); err != nil {
+				return 0, err
+			}
+		}
+	}
+	return len(markets), nil
+}
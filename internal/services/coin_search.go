@@ -0,0 +1,141 @@
+package services
+
+import (
+	"ares_api/internal/api/dto"
+	"ares_api/internal/models"
+	"sort"
+	"strings"
+)
+
+// How a coin matched a search, best first
+const (
+	matchSymbol = iota
+	matchName
+	matchSymbolPrefix
+	matchNamePrefix
+	matchWordPrefix
+	matchSubstring
+	matchFuzzy
+)
+
+var matchNames = []string{"symbol", "name", "symbol_prefix", "name_prefix", "word_prefix", "substring", "fuzzy"}
+
+// defaultCoinSearchLimit is how many results a search returns unless asked otherwise
+const defaultCoinSearchLimit = 20
+
+type coinMatch struct {
+	coin     models.Coin
+	kind     int
+	distance int // edit distance of a fuzzy match
+}
+
+// matchCoin reports how query, already lower case, matches coin. A fuzzy match allows
+// one typo for every four characters of the query, against the symbol, the name or
+// the start of the name.
+func matchCoin(query string, coin models.Coin) (coinMatch, bool) {
+	symbol := strings.ToLower(coin.Symbol)
+	name := strings.ToLower(coin.Name)
+	m := coinMatch{coin: coin}
+
+	switch {
+	case symbol == query:
+		m.kind = matchSymbol
+	case name == query:
+		m.kind = matchName
+	case strings.HasPrefix(symbol, query):
+		m.kind = matchSymbolPrefix
+	case strings.HasPrefix(name, query):
+		m.kind = matchNamePrefix
+	case strings.Contains(name, " "+query):
+		m.kind = matchWordPrefix
+	case strings.Contains(symbol, query) || strings.Contains(name, query):
+		m.kind = matchSubstring
+	default:
+		q := []rune(query)
+		maxDistance := len(q) / 4
+		if maxDistance == 0 {
+			return m, false
+		}
+		m.kind = matchFuzzy
+		m.distance = editDistance(q, []rune(symbol))
+		if d := editDistance(q, []rune(name)); d < m.distance {
+			m.distance = d
+		}
+		if n := []rune(name); len(n) > len(q) {
+			if d := editDistance(q, n[:len(q)]); d < m.distance {
+				m.distance = d
+			}
+		}
+		if m.distance > maxDistance {
+			return m, false
+		}
+	}
+	return m, true
+}
+
+// rankCoins orders the coins matching query by how they matched, then by market cap
+// rank, then by the shorter name
+func rankCoins(query string, coins []models.Coin, limit int) []dto.CoinSearchResultDTO {
+	if limit <= 0 {
+		limit = defaultCoinSearchLimit
+	}
+	matches := make([]coinMatch, 0, len(coins))
+	for _, c := range coins {
+		if m, ok := matchCoin(query, c); ok {
+			matches = append(matches, m)
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		if a.kind != b.kind {
+			return a.kind < b.kind
+		}
+		if a.distance != b.distance {
+			return a.distance < b.distance
+		}
+		if ra, rb := a.coin.MarketCapRank, b.coin.MarketCapRank; ra != rb {
+			return rb == 0 || (ra != 0 && ra < rb) // unranked coins last
+		}
+		if len(a.coin.Name) != len(b.coin.Name) {
+			return len(a.coin.Name) < len(b.coin.Name)
+		}
+		return a.coin.ID < b.coin.ID
+	})
+	if len(matches) > limit {
+		matches = matches[:limit]
+	}
+
+	res := make([]dto.CoinSearchResultDTO, 0, len(matches))
+	for _, m := range matches {
+		res = append(res, dto.CoinSearchResultDTO{
+			ID:            m.coin.ID,
+			Symbol:        m.coin.Symbol,
+			Name:          m.coin.Name,
+			MarketCapRank: m.coin.MarketCapRank,
+			Match:         matchNames[m.kind],
+		})
+	}
+	return res
+}
+
+// editDistance is the Levenshtein distance between a and b
+func editDistance(a, b []rune) int {
+	prev := make([]int, len(b)+1)
+	cur := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		cur[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(b)]
+}
@@ -0,0 +1,392 @@
+package services
+
+import (
+	"ares_api/internal/api/dto"
+	service "ares_api/internal/interfaces/service"
+	"ares_api/internal/models"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// ConditionalOrder places a stop_market, stop_limit, take_profit or trailing_stop order.
+// Conditional orders don't reserve cash; a buy the balance can't cover when it triggers is rejected.
+func (s *TradeService) ConditionalOrder(userID, portfolioID uint, req dto.ConditionalOrderRequest) (*dto.TradeResponse, error) {
+	portfolio, err := resolvePortfolio(s.PortfolioRepo, userID, portfolioID, true)
+	if err != nil {
+		return nil, err
+	}
+	req.CoinID, req.Symbol, err = s.resolveCoin(req.CoinID, req.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	order, err := newConditionalOrder(userID, portfolio.ID, req)
+	if err != nil {
+		return nil, err
+	}
+
+	// Fetch current market price
+	coinMarket, err := s.AssetRepo.FetchCoinMarket(req.CoinID, strings.ToLower(order.QuoteCurrency))
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch market price: %w", err)
+	}
+	if order.Type == models.OrderTypeTrailingStop {
+		price := marketPrice(coinMarket)
+		// A sell stop trailing by the whole price or more would sit at or below zero and never fire
+		if order.Side == "sell" && order.TrailingOffset.GreaterThanOrEqual(price) {
+			return nil, fmt.Errorf("%w: trailing_offset %s must be below the current price %s", service.ErrInvalidOrder, order.TrailingOffset, price)
+		}
+		updateTrailingStop(order, price)
+	}
+	warnings, err := s.checkConditionalRisk(order)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.TxManager.Transaction(func(tx *gorm.DB) error {
+		if err := s.checkSellHolding(tx, order, order.Quantity); err != nil {
+			return err
+		}
+		if err := s.Repo.WithTx(tx).Create(order); err != nil {
+			return fmt.Errorf("failed to create %s order: %w", order.Type, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := toTradeResponse(order, nil)
+	res.RiskWarnings = warnings
+	return &res, nil
+}
+
+// OCOOrder places a take-profit leg and a stop leg sharing one OCO group.
+// When either leg fills the other is cancelled.
+func (s *TradeService) OCOOrder(userID, portfolioID uint, req dto.OCOOrderRequest) ([]dto.TradeResponse, error) {
+	portfolio, err := resolvePortfolio(s.PortfolioRepo, userID, portfolioID, true)
+	if err != nil {
+		return nil, err
+	}
+	switch req.Side {
+	case "sell":
+		if req.TakeProfitPrice.LessThanOrEqual(req.StopPrice) {
+			return nil, fmt.Errorf("%w: take_profit_price must be above stop_price for a sell bracket", service.ErrInvalidOrder)
+		}
+	case "buy":
+		if req.TakeProfitPrice.GreaterThanOrEqual(req.StopPrice) {
+			return nil, fmt.Errorf("%w: take_profit_price must be below stop_price for a buy bracket", service.ErrInvalidOrder)
+		}
+	default:
+		return nil, fmt.Errorf("%w: side %q must be buy or sell", service.ErrInvalidOrder, req.Side)
+	}
+	req.CoinID, req.Symbol, err = s.resolveCoin(req.CoinID, req.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	leg := dto.ConditionalOrderRequest{
+		CoinID:      req.CoinID,
+		Symbol:      req.Symbol,
+		Side:        req.Side,
+		Quantity:    req.Quantity,
+		Currency:    req.Currency,
+		TimeInForce: req.TimeInForce,
+		ExpiresAt:   req.ExpiresAt,
+	}
+
+	takeProfitReq := leg
+	takeProfitReq.Type = models.OrderTypeTakeProfit
+	takeProfitReq.StopPrice = req.TakeProfitPrice
+	takeProfit, err := newConditionalOrder(userID, portfolio.ID, takeProfitReq)
+	if err != nil {
+		return nil, err
+	}
+
+	stopReq := leg
+	stopReq.Type = models.OrderTypeStopMarket
+	stopReq.StopPrice = req.StopPrice
+	if req.StopLimitPrice.IsPositive() {
+		stopReq.Type = models.OrderTypeStopLimit
+		stopReq.LimitPrice = req.StopLimitPrice
+	}
+	stop, err := newConditionalOrder(userID, portfolio.ID, stopReq)
+	if err != nil {
+		return nil, err
+	}
+
+	groupID := uuid.NewString()
+	takeProfit.OCOGroupID = groupID
+	stop.OCOGroupID = groupID
+
+	warnings, err := s.checkConditionalRisk(takeProfit, stop)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.TxManager.Transaction(func(tx *gorm.DB) error {
+		// Only one leg can ever fill, so the holding has to cover a single leg
+		if err := s.checkSellHolding(tx, takeProfit, takeProfit.Quantity); err != nil {
+			return err
+		}
+		for _, order := range []*models.Order{takeProfit, stop} {
+			if err := s.Repo.WithTx(tx).Create(order); err != nil {
+				return fmt.Errorf("failed to create OCO order: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := []dto.TradeResponse{toTradeResponse(takeProfit, nil), toTradeResponse(stop, nil)}
+	for i := range res {
+		res[i].RiskWarnings = warnings
+	}
+	return res, nil
+}
+
+// checkConditionalRisk runs the pre-trade risk checks on conditional orders placed
+// together, all of which rest on the book. Legs of a bracket share one check at the
+// price of the costlier leg, since only one of them can fill.
+func (s *TradeService) checkConditionalRisk(orders ...*models.Order) ([]dto.RiskViolationDTO, error) {
+	order := orders[0]
+	price := decimal.Zero
+	for _, o := range orders {
+		price = decimal.Max(price, riskPrice(o))
+	}
+	return s.Risk.CheckOrder(service.OrderIntent{
+		UserID:      order.UserID,
+		PortfolioID: order.PortfolioID,
+		CoinID:      order.CoinID,
+		Side:        order.Side,
+		Currency:    order.QuoteCurrency,
+		Quantity:    order.Quantity,
+		Price:       price,
+		Resting:     true,
+		Orders:      len(orders),
+	})
+}
+
+// evaluateOrder triggers and fills a locked resting order against market. It must run
+// inside tx. Resting limits fill as makers and triggered stops as takers. Trailing stops
+// persist their new water mark even when they don't trigger, and a fill on one OCO leg
+// cancels its siblings.
+func (s *TradeService) evaluateOrder(tx *gorm.DB, order *models.Order, siblings []models.Order, market *dto.CoinMarketDTO) error {
+	repo := s.Repo.WithTx(tx)
+	price := marketPrice(market)
+	liquidity := service.LiquidityTaker
+
+	switch order.Type {
+	case models.OrderTypeLimit:
+		if !limitReached(order.Side, price, order.Price) {
+			return nil
+		}
+		liquidity = service.LiquidityMaker
+	case models.OrderTypeStopLimit:
+		if order.TriggeredAt == nil {
+			if !stopTriggered(order, price) {
+				return nil
+			}
+			now := time.Now()
+			order.TriggeredAt = &now
+			// Once triggered the order rests as a plain limit order
+			if !limitReached(order.Side, price, order.Price) {
+				return repo.UpdateOrder(order)
+			}
+		} else {
+			if !limitReached(order.Side, price, order.Price) {
+				return nil
+			}
+			// It rested on the book after an earlier trigger
+			liquidity = service.LiquidityMaker
+		}
+	case models.OrderTypeTrailingStop:
+		moved := updateTrailingStop(order, price)
+		if !stopTriggered(order, price) {
+			if moved {
+				return repo.UpdateOrder(order)
+			}
+			return nil
+		}
+		order.Price = price
+	case models.OrderTypeStopMarket, models.OrderTypeTakeProfit:
+		if !stopTriggered(order, price) {
+			return nil
+		}
+		order.Price = price
+	default:
+		return nil
+	}
+
+	if order.Type != models.OrderTypeLimit && order.TriggeredAt == nil {
+		now := time.Now()
+		order.TriggeredAt = &now
+	}
+	if err := s.fillOrder(tx, order, market, liquidity); err != nil {
+		return err
+	}
+	if order.Status == models.OrderStatusFilled || order.Status == models.OrderStatusPartiallyFilled {
+		return s.cancelSiblings(tx, siblings)
+	}
+	return nil
+}
+
+// checkSellHolding verifies quantity of a sell order is covered by the part of its
+// portfolio's holding open sell limits haven't reserved. The cash row is locked before
+// the holding, matching settle.
+func (s *TradeService) checkSellHolding(tx *gorm.DB, order *models.Order, quantity decimal.Decimal) error {
+	if order.Side != "sell" {
+		return nil
+	}
+	if _, err := s.lockBalance(tx, order.UserID, order.PortfolioID, order.QuoteCurrency); err != nil {
+		return err
+	}
+	holding, err := s.HoldingRepo.WithTx(tx).GetHoldingForUpdate(order.PortfolioID, order.CoinID)
+	if err != nil || holding.Quantity.Sub(holding.Reserved).LessThan(quantity) {
+		return fmt.Errorf("%w of %s", service.ErrInsufficientHolding, order.CoinID)
+	}
+	return nil
+}
+
+// riskPrice is the price the risk checks value a resting order at: the limit price of
+// limit-style orders, the trigger price of the others
+func riskPrice(order *models.Order) decimal.Decimal {
+	if order.Type == models.OrderTypeLimit || order.Type == models.OrderTypeStopLimit {
+		return order.Price
+	}
+	return order.StopPrice
+}
+
+// reserveHolding moves delta of the portfolio's holding into (or, when negative, out of)
+// the reservation of a sell limit order. The cash row is locked first, matching settle.
+func (s *TradeService) reserveHolding(tx *gorm.DB, order *models.Order, delta decimal.Decimal) error {
+	if _, err := s.lockBalance(tx, order.UserID, order.PortfolioID, order.QuoteCurrency); err != nil {
+		return err
+	}
+	if _, err := s.HoldingRepo.WithTx(tx).Reserve(order.PortfolioID, order.CoinID, delta); err != nil {
+		if errors.Is(err, gorm.ErrInvalidData) {
+			return fmt.Errorf("%w of %s", service.ErrInsufficientHolding, order.CoinID)
+		}
+		return err
+	}
+	order.ReservedQuantity = order.ReservedQuantity.Add(delta)
+	return nil
+}
+
+// newConditionalOrder validates req and builds the open order it describes
+func newConditionalOrder(userID, portfolioID uint, req dto.ConditionalOrderRequest) (*models.Order, error) {
+	currency, err := parseCurrency(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	if req.Side != "buy" && req.Side != "sell" {
+		return nil, fmt.Errorf("%w: side %q must be buy or sell", service.ErrInvalidOrder, req.Side)
+	}
+	quantity := models.RoundQuantity(req.CoinID, req.Quantity)
+	if !quantity.IsPositive() {
+		return nil, fmt.Errorf("%w: quantity must be positive", service.ErrInvalidOrder)
+	}
+	stopPrice := models.RoundPrice(req.StopPrice)
+	limitPrice := models.RoundPrice(req.LimitPrice)
+
+	order := &models.Order{
+		UserID:        userID,
+		PortfolioID:   portfolioID,
+		CoinID:        req.CoinID,
+		Symbol:        req.Symbol,
+		Side:          req.Side,
+		Quantity:      quantity,
+		Type:          req.Type,
+		Status:        models.OrderStatusOpen,
+		QuoteCurrency: currency,
+	}
+
+	switch req.Type {
+	case models.OrderTypeStopMarket, models.OrderTypeTakeProfit:
+		if !stopPrice.IsPositive() {
+			return nil, fmt.Errorf("%w: %s orders need a positive stop_price", service.ErrInvalidOrder, req.Type)
+		}
+		order.StopPrice = stopPrice
+	case models.OrderTypeStopLimit:
+		if !stopPrice.IsPositive() || !limitPrice.IsPositive() {
+			return nil, fmt.Errorf("%w: stop_limit orders need a positive stop_price and limit_price", service.ErrInvalidOrder)
+		}
+		order.StopPrice = stopPrice
+		order.Price = limitPrice
+	case models.OrderTypeTrailingStop:
+		switch {
+		case req.TrailingPercent.IsPositive() && req.TrailingOffset.IsPositive():
+			return nil, fmt.Errorf("%w: set either trailing_percent or trailing_offset, not both", service.ErrInvalidOrder)
+		case req.TrailingPercent.IsPositive():
+			if req.TrailingPercent.GreaterThanOrEqual(decimal.NewFromInt(100)) {
+				return nil, fmt.Errorf("%w: trailing_percent must be below 100", service.ErrInvalidOrder)
+			}
+			order.TrailingPercent = req.TrailingPercent
+		case req.TrailingOffset.IsPositive():
+			order.TrailingOffset = models.RoundPrice(req.TrailingOffset)
+		default:
+			return nil, fmt.Errorf("%w: trailing_stop orders need a positive trailing_percent or trailing_offset", service.ErrInvalidOrder)
+		}
+	default:
+		return nil, fmt.Errorf("%w: %q, must be stop_market, stop_limit, take_profit or trailing_stop", service.ErrInvalidOrderType, req.Type)
+	}
+
+	switch strings.ToUpper(req.TimeInForce) {
+	case "", models.TimeInForceGTC:
+		order.TimeInForce = models.TimeInForceGTC
+	case models.TimeInForceGTD:
+		if req.ExpiresAt == nil || !req.ExpiresAt.After(time.Now()) {
+			return nil, fmt.Errorf("%w: GTD orders need an expires_at in the future", service.ErrInvalidOrder)
+		}
+		order.TimeInForce = models.TimeInForceGTD
+		order.ExpiresAt = req.ExpiresAt
+	default:
+		return nil, fmt.Errorf("%w: time_in_force %q is not supported, conditional orders take GTC or GTD", service.ErrInvalidOrder, req.TimeInForce)
+	}
+
+	return order, nil
+}
+
+// stopTriggered reports whether price has crossed the order's StopPrice.
+// Stops fire when price moves against the position, take-profits when it moves in favour.
+func stopTriggered(order *models.Order, price decimal.Decimal) bool {
+	if order.Type == models.OrderTypeTakeProfit {
+		return (order.Side == "sell" && price.GreaterThanOrEqual(order.StopPrice)) ||
+			(order.Side == "buy" && price.LessThanOrEqual(order.StopPrice))
+	}
+	return (order.Side == "sell" && price.LessThanOrEqual(order.StopPrice)) ||
+		(order.Side == "buy" && price.GreaterThanOrEqual(order.StopPrice))
+}
+
+// updateTrailingStop moves the water mark to price when price improves on it and
+// re-derives StopPrice, which never drops below the smallest positive price. It reports
+// whether anything changed.
+func updateTrailingStop(order *models.Order, price decimal.Decimal) bool {
+	if order.WaterMark.IsPositive() {
+		if order.Side == "sell" && price.LessThanOrEqual(order.WaterMark) {
+			return false
+		}
+		if order.Side == "buy" && price.GreaterThanOrEqual(order.WaterMark) {
+			return false
+		}
+	}
+
+	order.WaterMark = price
+	distance := order.TrailingOffset
+	if order.TrailingPercent.IsPositive() {
+		distance = models.RoundPrice(price.Mul(order.TrailingPercent).Div(decimal.NewFromInt(100)))
+	}
+	if order.Side == "sell" {
+		order.StopPrice = decimal.Max(price.Sub(distance), decimal.New(1, -models.PriceScale))
+	} else {
+		order.StopPrice = price.Add(distance)
+	}
+	return true
+}
@@ -0,0 +1,149 @@
+package services
+
+import (
+	service "ares_api/internal/interfaces/service"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Cost basis methods for matching sells against earlier buys
+const (
+	CostBasisFIFO    = "fifo"    // oldest lot first
+	CostBasisLIFO    = "lifo"    // newest lot first
+	CostBasisHIFO    = "hifo"    // highest unit cost first
+	CostBasisAverage = "average" // average cost of the open quantity
+)
+
+// lot is the still-held part of one buy
+type lot struct {
+	fillID     uint
+	acquiredAt time.Time
+	quantity   decimal.Decimal
+	price      decimal.Decimal // cost per unit
+}
+
+// position tracks the open quantity and cost of one coin under a cost basis method.
+// Amounts are exact, so a position sold down to zero is exactly flat.
+type position struct {
+	method   string
+	lots     []lot // lot methods only, oldest first
+	quantity decimal.Decimal
+	cost     decimal.Decimal // total cost of the open quantity
+}
+
+func newPosition(method string) *position {
+	return &position{method: method}
+}
+
+func (p *position) buy(quantity, price decimal.Decimal) {
+	p.acquire(0, time.Time{}, quantity, price)
+}
+
+// acquire adds a buy of quantity at price, recording the fill and time it came from
+func (p *position) acquire(fillID uint, at time.Time, quantity, price decimal.Decimal) {
+	if !quantity.IsPositive() {
+		return
+	}
+	p.quantity = p.quantity.Add(quantity)
+	p.cost = p.cost.Add(quantity.Mul(price))
+	if p.method != CostBasisAverage {
+		p.lots = append(p.lots, lot{fillID: fillID, acquiredAt: at, quantity: quantity, price: price})
+	}
+}
+
+// sell removes quantity from the position and returns the realized P&L at price
+func (p *position) sell(quantity, price decimal.Decimal) decimal.Decimal {
+	quantity = decimal.Min(quantity, p.quantity) // never realize against coins we don't hold
+	basis, _ := p.dispose(quantity)
+	return quantity.Mul(price).Sub(basis)
+}
+
+// dispose removes quantity from the position and returns its cost and, under a lot
+// method, the parts of the lots it used, each with the quantity taken from it.
+// Quantity beyond what the position holds is ignored.
+func (p *position) dispose(quantity decimal.Decimal) (basis decimal.Decimal, used []lot) {
+	quantity = decimal.Min(quantity, p.quantity)
+	if !quantity.IsPositive() {
+		return decimal.Zero, nil
+	}
+
+	switch p.method {
+	case CostBasisAverage:
+		basis = quantity.Mul(p.cost).Div(p.quantity)
+	default:
+		remaining := quantity
+		for remaining.IsPositive() && len(p.lots) > 0 {
+			i := p.nextLot()
+			l := &p.lots[i]
+			take := decimal.Min(remaining, l.quantity)
+
+			part := *l
+			part.quantity = take
+			used = append(used, part)
+			basis = basis.Add(take.Mul(l.price))
+
+			l.quantity = l.quantity.Sub(take)
+			remaining = remaining.Sub(take)
+			if !l.quantity.IsPositive() {
+				p.lots = append(p.lots[:i], p.lots[i+1:]...)
+			}
+		}
+	}
+
+	p.quantity = p.quantity.Sub(quantity)
+	p.cost = p.cost.Sub(basis)
+	if !p.quantity.IsPositive() {
+		p.quantity, p.cost, p.lots = decimal.Zero, decimal.Zero, nil
+	}
+	return basis, used
+}
+
+// nextLot is the index of the lot the method sells first
+func (p *position) nextLot() int {
+	switch p.method {
+	case CostBasisLIFO:
+		return len(p.lots) - 1
+	case CostBasisHIFO:
+		best := 0
+		for i, l := range p.lots {
+			if l.price.GreaterThan(p.lots[best].price) {
+				best = i
+			}
+		}
+		return best
+	default:
+		return 0
+	}
+}
+
+// averageCost is the cost per unit of the open quantity
+func (p *position) averageCost() decimal.Decimal {
+	if !p.quantity.IsPositive() {
+		return decimal.Zero
+	}
+	return p.cost.Div(p.quantity)
+}
+
+func validateCostBasis(method string) (string, error) {
+	switch method {
+	case "":
+		return CostBasisFIFO, nil
+	case CostBasisFIFO, CostBasisLIFO, CostBasisHIFO, CostBasisAverage:
+		return method, nil
+	default:
+		return "", service.ErrInvalidCostBasis
+	}
+}
+
+// validateLotMethod accepts the cost basis methods that match sells to individual lots
+func validateLotMethod(method string) (string, error) {
+	switch method {
+	case "":
+		return CostBasisFIFO, nil
+	case CostBasisFIFO, CostBasisLIFO, CostBasisHIFO:
+		return method, nil
+	default:
+		return "", service.ErrInvalidLotMethod
+	}
+}
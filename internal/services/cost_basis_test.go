@@ -0,0 +1,72 @@
+package services
+
+import (
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// Three buys, then a sale of 1.5 at 25 under each method
+func TestPositionDispose(t *testing.T) {
+	d := decimal.RequireFromString
+	start := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	buys := []struct {
+		quantity, price string
+	}{
+		{"1", "10"},
+		{"1", "30"},
+		{"1", "20"},
+	}
+
+	tests := []struct {
+		method    string
+		basis     string
+		realized  string
+		fillIDs   []uint // lots the sale used, in order
+		remaining string // cost of the open 1.5
+	}{
+		{CostBasisFIFO, "25", "12.5", []uint{1, 2}, "35"},
+		{CostBasisLIFO, "35", "2.5", []uint{3, 2}, "25"},
+		{CostBasisHIFO, "40", "-2.5", []uint{2, 3}, "20"},
+		{CostBasisAverage, "30", "7.5", nil, "30"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			newBought := func() *position {
+				p := newPosition(tt.method)
+				for i, b := range buys {
+					p.acquire(uint(i+1), start.AddDate(0, i, 0), d(b.quantity), d(b.price))
+				}
+				return p
+			}
+
+			p := newBought()
+			basis, lots := p.dispose(d("1.5"))
+			if !basis.Equal(d(tt.basis)) {
+				t.Errorf("basis = %s, want %s", basis, tt.basis)
+			}
+			if len(lots) != len(tt.fillIDs) {
+				t.Fatalf("used %d lots, want %d", len(lots), len(tt.fillIDs))
+			}
+			for i, l := range lots {
+				if l.fillID != tt.fillIDs[i] {
+					t.Errorf("lot %d came from fill %d, want %d", i, l.fillID, tt.fillIDs[i])
+				}
+			}
+			if !p.quantity.Equal(d("1.5")) || !p.cost.Equal(d(tt.remaining)) {
+				t.Errorf("open %s costing %s, want 1.5 costing %s", p.quantity, p.cost, tt.remaining)
+			}
+
+			if realized := newBought().sell(d("1.5"), d("25")); !realized.Equal(d(tt.realized)) {
+				t.Errorf("realized = %s, want %s", realized, tt.realized)
+			}
+
+			// Selling more than is held closes the position exactly
+			p.sell(d("5"), d("25"))
+			if !p.quantity.IsZero() || !p.cost.IsZero() || len(p.lots) != 0 {
+				t.Errorf("position not flat: %s costing %s in %d lots", p.quantity, p.cost, len(p.lots))
+			}
+		})
+	}
+}
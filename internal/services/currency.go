@@ -0,0 +1,91 @@
+package services
+
+import (
+	repository "ares_api/internal/interfaces/repository"
+	service "ares_api/internal/interfaces/service"
+	"ares_api/internal/models"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+var _ service.ExecutionCostModel = quotedCost{}
+
+// fxReferenceCoin is priced in every quote currency, so the ratio of its prices gives
+// the exchange rate between any two of them
+const fxReferenceCoin = "bitcoin"
+
+// fxRateDigits is the number of significant digits exchange rates are kept to; the
+// float quotes they are derived from carry no more
+const fxRateDigits = 12
+
+// parseCurrency normalises a requested quote currency, rejecting unsupported ones
+func parseCurrency(code string) (string, error) {
+	currency, ok := models.ParseCurrency(code)
+	if !ok {
+		return "", fmt.Errorf("%w: %q, must be one of %s", service.ErrUnsupportedCurrency, code, strings.Join(models.QuoteCurrencies(), ", "))
+	}
+	return currency, nil
+}
+
+// fxRate returns the units of currency one USD buys right now
+func fxRate(assets repository.AssetRepository, currency string) (decimal.Decimal, error) {
+	if currency == models.CurrencyUSD {
+		return decimal.NewFromInt(1), nil
+	}
+	markets := make(map[string]decimal.Decimal, 2)
+	for _, vs := range []string{models.CurrencyUSD, currency} {
+		market, err := assets.FetchCoinMarket(fxReferenceCoin, strings.ToLower(vs))
+		if err != nil {
+			return decimal.Zero, fmt.Errorf("failed to fetch %s exchange rate: %w", currency, err)
+		}
+		markets[vs] = decimal.NewFromFloat(market.PriceUSD)
+	}
+	if !markets[models.CurrencyUSD].IsPositive() || !markets[currency].IsPositive() {
+		return decimal.Zero, fmt.Errorf("no %s exchange rate available", currency)
+	}
+	return roundSignificant(markets[currency].Div(markets[models.CurrencyUSD]), fxRateDigits), nil
+}
+
+// exchangeRate returns the units of to one unit of from buys right now
+func exchangeRate(assets repository.AssetRepository, from, to string) (decimal.Decimal, error) {
+	if from == to {
+		return decimal.NewFromInt(1), nil
+	}
+	fromRate, err := fxRate(assets, from)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	toRate, err := fxRate(assets, to)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	return roundSignificant(toRate.Div(fromRate), fxRateDigits), nil
+}
+
+// roundSignificant rounds a positive d half-to-even to digits significant digits
+func roundSignificant(d decimal.Decimal, digits int32) decimal.Decimal {
+	if !d.IsPositive() {
+		return d
+	}
+	lead := int32(math.Floor(math.Log10(d.InexactFloat64())))
+	return d.RoundBank(digits - 1 - lead)
+}
+
+// quotedCost prices fills in a quote currency other than USD under a model whose fees
+// and depth are set in USD. Notionals are converted to USD at Rate, the units of the
+// quote currency one USD buys, and fees back into the quote currency.
+type quotedCost struct {
+	Model service.ExecutionCostModel
+	Rate  decimal.Decimal
+}
+
+func (q quotedCost) Slippage(notional, marketCap decimal.Decimal, liquidity string) decimal.Decimal {
+	return q.Model.Slippage(notional.Div(q.Rate), marketCap.Div(q.Rate), liquidity)
+}
+
+func (q quotedCost) Fee(notional decimal.Decimal, liquidity string) decimal.Decimal {
+	return q.Model.Fee(notional.Div(q.Rate), liquidity).Mul(q.Rate)
+}
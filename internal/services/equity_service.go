@@ -0,0 +1,190 @@
+package services
+
+import (
+	"ares_api/internal/api/dto"
+	repository "ares_api/internal/interfaces/repository"
+	service "ares_api/internal/interfaces/service"
+	"ares_api/internal/models"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var _ service.EquityService = &EquityService{}
+
+// equityWindows maps the supported window names to their length; "all" has no bound
+var equityWindows = map[string]time.Duration{
+	"1d":  24 * time.Hour,
+	"7d":  7 * 24 * time.Hour,
+	"30d": 30 * 24 * time.Hour,
+	"90d": 90 * 24 * time.Hour,
+	"1y":  365 * 24 * time.Hour,
+	"all": 0,
+}
+
+const year = 365 * 24 * time.Hour
+
+type EquityService struct {
+	Repo           repository.SnapshotRepository
+	PortfolioRepo  repository.PortfolioRepository
+	BalanceService service.BalanceService
+}
+
+func NewEquityService(r repository.SnapshotRepository, p repository.PortfolioRepository, bs service.BalanceService) *EquityService {
+	return &EquityService{Repo: r, PortfolioRepo: p, BalanceService: bs}
+}
+
+// RecordSnapshots stores the current value of every portfolio that isn't archived.
+// Snapshots are always valued in USD so the curve stays comparable when a user
+// changes their display currency. Portfolios that can't be priced right now are
+// skipped until the next run.
+func (s *EquityService) RecordSnapshots() (int, error) {
+	portfolios, err := s.PortfolioRepo.GetActive()
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	recorded := 0
+	for _, p := range portfolios {
+		portfolio, err := s.BalanceService.GetPortfolio(p.UserID, p.ID, models.CurrencyUSD)
+		if err != nil {
+			fmt.Printf("⚠️ Equity snapshot skipped for portfolio %d of user %d: %v\n", p.ID, p.UserID, err)
+			continue
+		}
+		snapshot := &models.PortfolioSnapshot{
+			UserID:        p.UserID,
+			PortfolioID:   p.ID,
+			TakenAt:       now,
+			Cash:          portfolio.Cash,
+			HoldingsValue: portfolio.HoldingsValue,
+			TotalValue:    portfolio.TotalValue,
+		}
+		if err := s.Repo.Create(snapshot); err != nil {
+			return recorded, err
+		}
+		recorded++
+	}
+	return recorded, nil
+}
+
+// GetEquityCurve returns the portfolio's snapshots within window and the risk metrics derived from them
+func (s *EquityService) GetEquityCurve(userID, portfolioID uint, window string) (*dto.EquityCurveDTO, error) {
+	if window == "" {
+		window = "30d"
+	}
+	length, ok := equityWindows[window]
+	if !ok {
+		return nil, service.ErrInvalidWindow
+	}
+	var from time.Time
+	if length > 0 {
+		from = time.Now().Add(-length)
+	}
+
+	portfolio, err := resolvePortfolio(s.PortfolioRepo, userID, portfolioID, false)
+	if err != nil {
+		return nil, err
+	}
+	snapshots, err := s.Repo.GetByPortfolioSince(portfolio.ID, from)
+	if err != nil {
+		return nil, err
+	}
+
+	curve := &dto.EquityCurveDTO{PortfolioID: portfolio.ID, Window: window, Points: []dto.EquityPointDTO{}}
+	values := make([]float64, 0, len(snapshots))
+	for _, snap := range snapshots {
+		curve.Points = append(curve.Points, dto.EquityPointDTO{
+			TakenAt:       snap.TakenAt,
+			Cash:          snap.Cash,
+			HoldingsValue: snap.HoldingsValue,
+			TotalValue:    snap.TotalValue,
+		})
+		// Ratios don't need exact money, so the metrics run on floats
+		values = append(values, snap.TotalValue.InexactFloat64())
+	}
+	if len(values) == 0 {
+		return curve, nil
+	}
+
+	curve.StartValue = snapshots[0].TotalValue
+	curve.EndValue = snapshots[len(snapshots)-1].TotalValue
+	if curve.StartValue.IsPositive() {
+		curve.TotalReturn = curve.EndValue.Div(curve.StartValue).Sub(decimal.NewFromInt(1)).InexactFloat64()
+	}
+	curve.MaxDrawdown = maxDrawdown(values)
+
+	if len(snapshots) > 1 {
+		span := snapshots[len(snapshots)-1].TakenAt.Sub(snapshots[0].TakenAt)
+		interval := span / time.Duration(len(snapshots)-1)
+		if interval > 0 {
+			periodsPerYear := float64(year) / float64(interval)
+			curve.Volatility, curve.Sharpe, curve.Sortino = riskMetrics(periodReturns(values), periodsPerYear)
+		}
+	}
+
+	return curve, nil
+}
+
+// maxDrawdown is the largest peak-to-trough fall as a fraction of the peak
+func maxDrawdown(values []float64) float64 {
+	var peak, worst float64
+	for _, v := range values {
+		if v > peak {
+			peak = v
+		}
+		if peak > 0 {
+			if dd := (peak - v) / peak; dd > worst {
+				worst = dd
+			}
+		}
+	}
+	return worst
+}
+
+// periodReturns converts a value series into simple returns between consecutive points
+func periodReturns(values []float64) []float64 {
+	returns := make([]float64, 0, len(values))
+	for i := 1; i < len(values); i++ {
+		if values[i-1] > 0 {
+			returns = append(returns, values[i]/values[i-1]-1)
+		}
+	}
+	return returns
+}
+
+// riskMetrics returns annualised volatility, Sharpe and Sortino ratios for a return series
+func riskMetrics(returns []float64, periodsPerYear float64) (volatility, sharpe, sortino float64) {
+	n := float64(len(returns))
+	if n < 2 {
+		return 0, 0, 0
+	}
+
+	var sum float64
+	for _, r := range returns {
+		sum += r
+	}
+	mean := sum / n
+
+	var variance, downside float64
+	for _, r := range returns {
+		variance += (r - mean) * (r - mean)
+		if r < 0 {
+			downside += r * r
+		}
+	}
+	std := math.Sqrt(variance / (n - 1))
+	downsideDev := math.Sqrt(downside / n)
+	annualise := math.Sqrt(periodsPerYear)
+
+	volatility = std * annualise
+	if std > 0 {
+		sharpe = mean / std * annualise
+	}
+	if downsideDev > 0 {
+		sortino = mean / downsideDev * annualise
+	}
+	return volatility, sharpe, sortino
+}
@@ -0,0 +1,159 @@
+package services
+
+import (
+	"ares_api/internal/api/dto"
+	service "ares_api/internal/interfaces/service"
+	"ares_api/internal/models"
+	"sort"
+
+	"github.com/shopspring/decimal"
+)
+
+var _ service.ExecutionCostModel = FeeSchedule{}
+
+// DefaultFeeSchedule applies to accounts that haven't picked a preset
+const DefaultFeeSchedule = "standard"
+
+var (
+	// marketCapDepthRatio estimates order book depth as a share of market cap
+	// when a schedule has no configured depth
+	marketCapDepthRatio = decimal.RequireFromString("0.0005")
+
+	// maxSlippage caps the simulated price impact of a single fill
+	maxSlippage = decimal.RequireFromString("0.10")
+)
+
+// FeeSchedule is a percentage-plus-fixed fee model with notional-based slippage.
+// Rates are fractions (0.001 = 0.1%).
+type FeeSchedule struct {
+	Name       string
+	MakerRate  decimal.Decimal
+	TakerRate  decimal.Decimal
+	FixedFee   decimal.Decimal // USD per fill
+	SpreadRate decimal.Decimal // slippage paid on every taker fill
+	ImpactRate decimal.Decimal // extra slippage per unit of notional / depth
+	DepthUSD   decimal.Decimal // configured depth; zero derives it from market cap
+}
+
+// FeeSchedules are the presets an account can choose from
+var FeeSchedules = map[string]FeeSchedule{
+	"zero": {Name: "zero"},
+	"standard": {
+		Name: "standard", MakerRate: rate("0.001"), TakerRate: rate("0.001"),
+		SpreadRate: rate("0.0005"), ImpactRate: rate("0.1"),
+	},
+	"retail": {
+		Name: "retail", MakerRate: rate("0.004"), TakerRate: rate("0.006"), FixedFee: rate("0.99"),
+		SpreadRate: rate("0.001"), ImpactRate: rate("0.2"),
+	},
+	"pro": {
+		Name: "pro", MakerRate: rate("0.0002"), TakerRate: rate("0.0005"),
+		SpreadRate: rate("0.0002"), ImpactRate: rate("0.05"), DepthUSD: rate("5000000"),
+	},
+}
+
+func rate(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+// FeeScheduleNames lists the presets in a stable order
+func FeeScheduleNames() []string {
+	names := make([]string, 0, len(FeeSchedules))
+	for name := range FeeSchedules {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Slippage charges the spread plus price impact proportional to notional / depth on
+// taker fills. Makers fill at their own price, so they pay no slippage.
+func (f FeeSchedule) Slippage(notional, marketCap decimal.Decimal, liquidity string) decimal.Decimal {
+	if liquidity == service.LiquidityMaker {
+		return decimal.Zero
+	}
+
+	slippage := f.SpreadRate
+	depth := f.DepthUSD
+	if !depth.IsPositive() {
+		depth = marketCap.Mul(marketCapDepthRatio)
+	}
+	if depth.IsPositive() {
+		slippage = slippage.Add(f.ImpactRate.Mul(notional).Div(depth))
+	}
+	return decimal.Min(slippage, maxSlippage)
+}
+
+// Fee charges the maker or taker rate on notional plus the fixed fee
+func (f FeeSchedule) Fee(notional decimal.Decimal, liquidity string) decimal.Decimal {
+	if !notional.IsPositive() {
+		return decimal.Zero
+	}
+	rate := f.TakerRate
+	if liquidity == service.LiquidityMaker {
+		rate = f.MakerRate
+	}
+	return notional.Mul(rate).Add(f.FixedFee)
+}
+
+// quoteExecution prices a fill of quantity at the market price under model, with the fee
+// rounded to the scale of the quote currency. Buys pay slippage above the price and
+// sells receive below it. Limit-style orders pass their limit price so slippage never
+// fills them beyond it; pass zero otherwise.
+func quoteExecution(model service.ExecutionCostModel, currency, side string, quantity, price, marketCap decimal.Decimal, liquidity string, limitPrice decimal.Decimal) service.ExecutionQuote {
+	slippage := model.Slippage(quantity.Mul(price), marketCap, liquidity)
+
+	one := decimal.NewFromInt(1)
+	fillPrice := price.Mul(one.Add(slippage))
+	if side == "sell" {
+		fillPrice = price.Mul(one.Sub(slippage))
+	}
+	fillPrice = models.RoundPrice(fillPrice)
+	if limitPrice.IsPositive() {
+		if side == "buy" && fillPrice.GreaterThan(limitPrice) {
+			fillPrice = limitPrice
+		}
+		if side == "sell" && fillPrice.LessThan(limitPrice) {
+			fillPrice = limitPrice
+		}
+	}
+
+	quote := service.ExecutionQuote{
+		Price: fillPrice,
+		Fee:   models.RoundAmount(currency, model.Fee(models.RoundAmount(currency, quantity.Mul(fillPrice)), liquidity)),
+	}
+	if price.IsPositive() {
+		quote.Slippage = fillPrice.Div(price).Sub(one)
+	}
+	return quote
+}
+
+// quantityForBudget is the largest quantity of the coin, at most budget / price, whose
+// taker buy under model costs no more than budget, in currency, with the fee included. A fixed fee
+// makes cost non-linear in quantity, so the estimate is scaled down until the quote
+// fits. It is zero when no quantity fits.
+func quantityForBudget(model service.ExecutionCostModel, currency, coinID string, price, marketCap, budget decimal.Decimal) decimal.Decimal {
+	if !budget.IsPositive() || !price.IsPositive() {
+		return decimal.Zero
+	}
+	quantity := models.RoundQuantity(coinID, budget.Div(price))
+	for i := 0; i < 5 && quantity.IsPositive(); i++ {
+		quote := quoteExecution(model, currency, "buy", quantity, price, marketCap, service.LiquidityTaker, decimal.Zero)
+		cost := fillCashFlow(currency, "buy", quantity, quote.Price, quote.Fee).Neg()
+		if !cost.GreaterThan(budget) {
+			return quantity
+		}
+		quantity = models.RoundQuantity(coinID, quantity.Mul(budget).Div(cost))
+	}
+	return decimal.Zero
+}
+
+// marketPrice is the quoted USD price of a coin as an exact decimal rounded to PriceScale
+func marketPrice(market *dto.CoinMarketDTO) decimal.Decimal {
+	return models.RoundPrice(decimal.NewFromFloat(market.PriceUSD))
+}
+
+// marketCap is the quoted USD market capitalisation of a coin as a decimal
+func marketCap(market *dto.CoinMarketDTO) decimal.Decimal {
+	return decimal.NewFromFloat(market.MarketCap)
+}
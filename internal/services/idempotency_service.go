@@ -0,0 +1,116 @@
+package services
+
+import (
+	repository "ares_api/internal/interfaces/repository"
+	service "ares_api/internal/interfaces/service"
+	"ares_api/internal/models"
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var _ service.IdempotencyService = &IdempotencyService{}
+
+// maxIdempotencyKeyLength matches the size of the key column
+const maxIdempotencyKeyLength = 255
+
+// IdempotencyService stores the first response to each Idempotency-Key for TTL, so
+// clients can safely retry mutations that timed out
+type IdempotencyService struct {
+	Repo repository.IdempotencyRepository
+	TTL  time.Duration
+}
+
+func NewIdempotencyService(r repository.IdempotencyRepository, ttl time.Duration) *IdempotencyService {
+	return &IdempotencyService{Repo: r, TTL: ttl}
+}
+
+func (s *IdempotencyService) Begin(userID uint, key, fingerprint string) (*service.IdempotentResponse, error) {
+	if !validIdempotencyKey(key) {
+		return nil, service.ErrInvalidIdempotencyKey
+	}
+
+	record := &models.IdempotencyKey{
+		UserID:      userID,
+		Key:         key,
+		RequestHash: fingerprint,
+		ExpiresAt:   time.Now().Add(s.TTL),
+	}
+	claimed, err := s.Repo.Claim(record)
+	if err != nil || claimed {
+		return nil, err
+	}
+
+	existing, err := s.Repo.Get(userID, key)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, service.ErrIdempotencyKeyInProgress // released between the claim and the read; the retry will win
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// An expired key that wasn't purged yet is free to reuse
+	if !existing.ExpiresAt.After(time.Now()) {
+		if err := s.Repo.Delete(existing); err != nil {
+			return nil, err
+		}
+		if claimed, err := s.Repo.Claim(record); err != nil || claimed {
+			return nil, err
+		}
+		return nil, service.ErrIdempotencyKeyInProgress
+	}
+
+	if existing.RequestHash != fingerprint {
+		return nil, service.ErrIdempotencyKeyMismatch
+	}
+	if existing.StatusCode == 0 {
+		return nil, service.ErrIdempotencyKeyInProgress
+	}
+	return &service.IdempotentResponse{
+		StatusCode:  existing.StatusCode,
+		ContentType: existing.ContentType,
+		Body:        existing.Body,
+	}, nil
+}
+
+// Complete stores the response to replay for the key
+func (s *IdempotencyService) Complete(userID uint, key string, response service.IdempotentResponse) error {
+	record, err := s.Repo.Get(userID, key)
+	if err != nil {
+		return err
+	}
+	record.StatusCode = response.StatusCode
+	record.ContentType = response.ContentType
+	record.Body = response.Body
+	return s.Repo.Save(record)
+}
+
+// Release gives the key up without storing a response, so a retry runs the request again
+func (s *IdempotencyService) Release(userID uint, key string) error {
+	record, err := s.Repo.Get(userID, key)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return s.Repo.Delete(record)
+}
+
+// PurgeExpired deletes keys past their retention window
+func (s *IdempotencyService) PurgeExpired() (int64, error) {
+	return s.Repo.DeleteExpired(time.Now())
+}
+
+func validIdempotencyKey(key string) bool {
+	if key == "" || len(key) > maxIdempotencyKeyLength {
+		return false
+	}
+	for _, r := range key {
+		if r < 0x21 || r > 0x7e {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,194 @@
+package services
+
+import (
+	"ares_api/internal/api/dto"
+	service "ares_api/internal/interfaces/service"
+	"fmt"
+	"sort"
+)
+
+// Windows price changes are measured over
+const (
+	Window1h  = "1h"
+	Window24h = "24h"
+	Window7d  = "7d"
+)
+
+// Screener sort fields
+const (
+	ScreenSortMarketCap = "market_cap"
+	ScreenSortPrice     = "price"
+	ScreenSortChange    = "change"
+	ScreenSortVolume    = "volume"
+)
+
+const (
+	defaultMoversLimit = 10
+	defaultScreenLimit = 50
+)
+
+// windowChange returns the function reading a market's change over window
+func windowChange(window string) (func(dto.CoinMarketDTO) float64, error) {
+	switch window {
+	case Window1h:
+		return func(m dto.CoinMarketDTO) float64 { return m.Change1h }, nil
+	case "", Window24h:
+		return func(m dto.CoinMarketDTO) float64 { return m.Change24h }, nil
+	case Window7d:
+		return func(m dto.CoinMarketDTO) float64 { return m.Change7d }, nil
+	default:
+		return nil, fmt.Errorf("%w: unknown window %q: must be 1h, 24h or 7d", service.ErrInvalidScreener, window)
+	}
+}
+
+// GetTopMovers ranks the market universe by its change over the window: the gainers
+// rose the most and the losers fell the most. Coins below the volume floor are left out.
+func (s *AssetServiceImpl) GetTopMovers(query dto.TopMoversQuery) (*dto.TopMoversDTO, error) {
+	change, err := windowChange(query.Window)
+	if err != nil {
+		return nil, err
+	}
+	if query.MinVolume < 0 {
+		return nil, fmt.Errorf("%w: min_volume can't be negative", service.ErrInvalidScreener)
+	}
+	window := query.Window
+	if window == "" {
+		window = Window24h
+	}
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultMoversLimit
+	}
+
+	markets, err := s.Repo.FetchMarketUniverse()
+	if err != nil {
+		return nil, err
+	}
+
+	var gainers, losers []dto.CoinMarketDTO
+	for _, m := range markets {
+		if m.Volume24h < query.MinVolume {
+			continue
+		}
+		switch c := change(m); {
+		case c > 0:
+			gainers = append(gainers, m)
+		case c < 0:
+			losers = append(losers, m)
+		}
+	}
+	sort.SliceStable(gainers, func(i, j int) bool { return change(gainers[i]) > change(gainers[j]) })
+	sort.SliceStable(losers, func(i, j int) bool { return change(losers[i]) < change(losers[j]) })
+
+	return &dto.TopMoversDTO{
+		Window:  window,
+		Gainers: toTopMovers(gainers, change, limit),
+		Losers:  toTopMovers(losers, change, limit),
+	}, nil
+}
+
+func toTopMovers(markets []dto.CoinMarketDTO, change func(dto.CoinMarketDTO) float64, limit int) []dto.TopMoverDTO {
+	if len(markets) > limit {
+		markets = markets[:limit]
+	}
+	movers := make([]dto.TopMoverDTO, 0, len(markets))
+	for _, m := range markets {
+		movers = append(movers, dto.TopMoverDTO{
+			ID:          m.ID,
+			Symbol:      m.Symbol,
+			Name:        m.Name,
+			PriceUSD:    m.PriceUSD,
+			MarketCap:   m.MarketCap,
+			Volume24h:   m.Volume24h,
+			Change:      change(m),
+			Change1h:    m.Change1h,
+			Change24h:   m.Change24h,
+			Change7d:    m.Change7d,
+			LastUpdated: m.LastUpdated,
+		})
+	}
+	return movers
+}
+
+// Screen filters the market universe by the filter's ranges and sorts what is left
+func (s *AssetServiceImpl) Screen(filter dto.ScreenerFilter) ([]dto.CoinMarketDTO, error) {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", service.ErrInvalidScreener, fmt.Sprintf(format, args...))
+	}
+
+	change, err := windowChange(filter.ChangeWindow)
+	if err != nil {
+		return nil, err
+	}
+	var key func(dto.CoinMarketDTO) float64
+	switch filter.Sort {
+	case "", ScreenSortMarketCap:
+		key = func(m dto.CoinMarketDTO) float64 { return m.MarketCap }
+	case ScreenSortPrice:
+		key = func(m dto.CoinMarketDTO) float64 { return m.PriceUSD }
+	case ScreenSortChange:
+		key = change
+	case ScreenSortVolume:
+		key = func(m dto.CoinMarketDTO) float64 { return m.Volume24h }
+	default:
+		return nil, invalid("unknown sort %q: must be market_cap, price, change or volume", filter.Sort)
+	}
+	ascending := false
+	switch filter.Order {
+	case "", "desc":
+	case "asc":
+		ascending = true
+	default:
+		return nil, invalid("unknown order %q: must be asc or desc", filter.Order)
+	}
+	ranges := []struct {
+		name     string
+		min, max *float64
+		value    func(dto.CoinMarketDTO) float64
+	}{
+		{"price", filter.MinPrice, filter.MaxPrice, func(m dto.CoinMarketDTO) float64 { return m.PriceUSD }},
+		{"market_cap", filter.MinMarketCap, filter.MaxMarketCap, func(m dto.CoinMarketDTO) float64 { return m.MarketCap }},
+		{"change", filter.MinChange, filter.MaxChange, change},
+		{"volume", filter.MinVolume, filter.MaxVolume, func(m dto.CoinMarketDTO) float64 { return m.Volume24h }},
+	}
+	for _, r := range ranges {
+		if r.min != nil && r.max != nil && *r.min > *r.max {
+			return nil, invalid("min_%s is above max_%s", r.name, r.name)
+		}
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = defaultScreenLimit
+	}
+
+	markets, err := s.Repo.FetchMarketUniverse()
+	if err != nil {
+		return nil, err
+	}
+
+	inRanges := func(m dto.CoinMarketDTO) bool {
+		for _, r := range ranges {
+			v := r.value(m)
+			if (r.min != nil && v < *r.min) || (r.max != nil && v > *r.max) {
+				return false
+			}
+		}
+		return true
+	}
+	res := []dto.CoinMarketDTO{}
+	for _, m := range markets {
+		if inRanges(m) {
+			res = append(res, m)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		if ascending {
+			return key(res[i]) < key(res[j])
+		}
+		return key(res[i]) > key(res[j])
+	})
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"ares_api/internal/api/dto"
+	repository "ares_api/internal/interfaces/repository"
+	service "ares_api/internal/interfaces/service"
+	"ares_api/internal/models"
+	"fmt"
+
+	"github.com/shopspring/decimal"
+)
+
+var _ service.PerformanceService = &PerformanceService{}
+
+type PerformanceService struct {
+	TradeRepo     repository.TradeRepository
+	PortfolioRepo repository.PortfolioRepository
+	AssetRepo     repository.AssetRepository
+}
+
+func NewPerformanceService(t repository.TradeRepository, p repository.PortfolioRepository, a repository.AssetRepository) *PerformanceService {
+	return &PerformanceService{TradeRepo: t, PortfolioRepo: p, AssetRepo: a}
+}
+
+// GetPerformance replays the portfolio's fills in order under the chosen cost basis
+// and marks any open quantity to the live price. Fills settled in other quote
+// currencies are converted to USD at the exchange rate they filled at, so every
+// figure is in USD.
+func (s *PerformanceService) GetPerformance(userID, portfolioID uint, costBasis string) (*dto.PerformanceDTO, error) {
+	method, err := validateCostBasis(costBasis)
+	if err != nil {
+		return nil, err
+	}
+
+	portfolio, err := resolvePortfolio(s.PortfolioRepo, userID, portfolioID, false)
+	if err != nil {
+		return nil, err
+	}
+	fills, err := s.TradeRepo.GetFillsByPortfolio(portfolio.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	type coinState struct {
+		stats   dto.CoinPerformanceDTO
+		pos     *position
+		tripPnL decimal.Decimal // realized minus fees since the position was last flat
+	}
+	coins := map[string]*coinState{}
+	var order []string
+
+	for _, t := range fills {
+		c, ok := coins[t.CoinID]
+		if !ok {
+			c = &coinState{
+				stats: dto.CoinPerformanceDTO{CoinID: t.CoinID, Symbol: t.Symbol},
+				pos:   newPosition(method),
+			}
+			coins[t.CoinID] = c
+			order = append(order, t.CoinID)
+		}
+
+		price, fee := usdAmounts(t)
+
+		c.stats.Trades++
+		c.stats.Fees = c.stats.Fees.Add(fee)
+		c.tripPnL = c.tripPnL.Sub(fee)
+
+		switch t.Side {
+		case "buy":
+			c.pos.buy(t.Quantity, price)
+		case "sell":
+			realized := c.pos.sell(t.Quantity, price)
+			c.stats.RealizedPnL = c.stats.RealizedPnL.Add(realized)
+			c.tripPnL = c.tripPnL.Add(realized)
+
+			// Position back to flat closes the round trip
+			if c.pos.quantity.IsZero() {
+				c.stats.RoundTrips++
+				if c.tripPnL.IsPositive() {
+					c.stats.Wins++
+				} else if c.tripPnL.IsNegative() {
+					c.stats.Losses++
+				}
+				c.tripPnL = decimal.Zero
+			}
+		}
+	}
+
+	perf := &dto.PerformanceDTO{
+		PortfolioID:     portfolio.ID,
+		CostBasisMethod: method,
+		TotalTrades:     len(fills),
+		Coins:           []dto.CoinPerformanceDTO{},
+	}
+	for _, coinID := range order {
+		c := coins[coinID]
+		c.stats.RealizedPnL = models.RoundCash(c.stats.RealizedPnL)
+		c.stats.Quantity = c.pos.quantity
+		c.stats.CostBasis = models.RoundCash(c.pos.cost)
+		c.stats.AverageCost = models.RoundPrice(c.pos.averageCost())
+
+		if c.pos.quantity.IsPositive() {
+			coinMarket, err := s.AssetRepo.FetchCoinMarket(coinID, "usd")
+			if err != nil {
+				return nil, fmt.Errorf("failed to fetch market price for %s: %w", coinID, err)
+			}
+			c.stats.CurrentPrice = marketPrice(coinMarket)
+			c.stats.MarketValue = models.RoundCash(c.pos.quantity.Mul(c.stats.CurrentPrice))
+			c.stats.UnrealizedPnL = c.stats.MarketValue.Sub(c.stats.CostBasis)
+		}
+		c.stats.NetPnL = c.stats.RealizedPnL.Add(c.stats.UnrealizedPnL).Sub(c.stats.Fees)
+
+		perf.RealizedPnL = perf.RealizedPnL.Add(c.stats.RealizedPnL)
+		perf.UnrealizedPnL = perf.UnrealizedPnL.Add(c.stats.UnrealizedPnL)
+		perf.Fees = perf.Fees.Add(c.stats.Fees)
+		perf.RoundTrips += c.stats.RoundTrips
+		perf.Wins += c.stats.Wins
+		perf.Losses += c.stats.Losses
+		perf.Coins = append(perf.Coins, c.stats)
+	}
+	perf.NetPnL = perf.RealizedPnL.Add(perf.UnrealizedPnL).Sub(perf.Fees)
+	if perf.RoundTrips > 0 {
+		perf.WinRate = float64(perf.Wins) / float64(perf.RoundTrips) * 100
+	}
+
+	return perf, nil
+}
@@ -0,0 +1,162 @@
+package services
+
+import (
+	"ares_api/internal/api/dto"
+	repository "ares_api/internal/interfaces/repository"
+	service "ares_api/internal/interfaces/service"
+	"ares_api/internal/models"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var _ service.PortfolioService = &PortfolioService{}
+
+// maxPortfolioName matches the size of the portfolios.name column
+const maxPortfolioName = 100
+
+// PortfolioService manages a user's paper portfolios. Every user has one default
+// portfolio that requests without a portfolio_id act on.
+type PortfolioService struct {
+	Repo        repository.PortfolioRepository
+	BalanceRepo repository.BalanceRepository
+	TradeRepo   repository.TradeRepository
+	TxManager   repository.TxManager
+}
+
+func NewPortfolioService(r repository.PortfolioRepository, b repository.BalanceRepository, t repository.TradeRepository, tx repository.TxManager) *PortfolioService {
+	return &PortfolioService{Repo: r, BalanceRepo: b, TradeRepo: t, TxManager: tx}
+}
+
+func toPortfolioSummaryDTO(p *models.Portfolio) dto.PortfolioSummaryDTO {
+	return dto.PortfolioSummaryDTO{
+		ID:         p.ID,
+		Name:       p.Name,
+		IsDefault:  p.IsDefault,
+		ArchivedAt: p.ArchivedAt,
+		CreatedAt:  p.CreatedAt,
+	}
+}
+
+// Create opens a portfolio funded with its starting USD balance. A user's first
+// portfolio becomes their default.
+func (s *PortfolioService) Create(userID uint, req dto.CreatePortfolioRequest) (*dto.PortfolioSummaryDTO, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > maxPortfolioName {
+		return nil, fmt.Errorf("%w: name must be 1-%d characters", service.ErrInvalidPortfolio, maxPortfolioName)
+	}
+	starting := DefaultBalance
+	if req.StartingBalance != nil {
+		starting = models.RoundCash(*req.StartingBalance)
+		if starting.IsNegative() {
+			return nil, fmt.Errorf("%w: starting_balance can't be negative", service.ErrInvalidPortfolio)
+		}
+	}
+
+	existing, err := s.Repo.GetByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range existing {
+		if strings.EqualFold(p.Name, name) {
+			return nil, fmt.Errorf("%w: a portfolio named %q already exists", service.ErrInvalidPortfolio, p.Name)
+		}
+	}
+
+	portfolio := &models.Portfolio{UserID: userID, Name: name, IsDefault: len(existing) == 0}
+	err = s.TxManager.Transaction(func(tx *gorm.DB) error {
+		if err := s.Repo.WithTx(tx).Create(portfolio); err != nil {
+			return err
+		}
+		_, err := s.BalanceRepo.WithTx(tx).CreateUSDBalance(userID, portfolio.ID, starting)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	res := toPortfolioSummaryDTO(portfolio)
+	return &res, nil
+}
+
+// List returns the user's portfolios, oldest first
+func (s *PortfolioService) List(userID uint, includeArchived bool) ([]dto.PortfolioSummaryDTO, error) {
+	portfolios, err := s.Repo.GetByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	res := []dto.PortfolioSummaryDTO{}
+	for i := range portfolios {
+		if portfolios[i].ArchivedAt != nil && !includeArchived {
+			continue
+		}
+		res = append(res, toPortfolioSummaryDTO(&portfolios[i]))
+	}
+	return res, nil
+}
+
+// Archive freezes a portfolio: its history stays readable but it can no longer trade.
+// The default portfolio can't be archived, nor can one with orders still open.
+func (s *PortfolioService) Archive(userID, portfolioID uint) (*dto.PortfolioSummaryDTO, error) {
+	portfolio, err := resolvePortfolio(s.Repo, userID, portfolioID, true)
+	if err != nil {
+		return nil, err
+	}
+	if portfolio.IsDefault {
+		return nil, fmt.Errorf("%w: the default portfolio can't be archived", service.ErrInvalidPortfolio)
+	}
+	open, err := s.TradeRepo.GetOpenOrdersByPortfolio(portfolio.ID)
+	if err != nil {
+		return nil, err
+	}
+	if len(open) > 0 {
+		return nil, fmt.Errorf("%w: cancel its %d open orders first", service.ErrInvalidPortfolio, len(open))
+	}
+
+	now := time.Now()
+	portfolio.ArchivedAt = &now
+	if err := s.Repo.Update(portfolio); err != nil {
+		return nil, err
+	}
+
+	res := toPortfolioSummaryDTO(portfolio)
+	return &res, nil
+}
+
+// resolvePortfolio loads the user's portfolio portfolioID, or their default portfolio
+// when portfolioID is 0. Archived portfolios stay readable, so only forWrite rejects them.
+func resolvePortfolio(repo repository.PortfolioRepository, userID, portfolioID uint, forWrite bool) (*models.Portfolio, error) {
+	var portfolio *models.Portfolio
+	var err error
+	if portfolioID == 0 {
+		portfolio, err = repo.GetDefault(userID)
+	} else {
+		portfolio, err = repo.GetByID(userID, portfolioID)
+	}
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, service.ErrPortfolioNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if forWrite && portfolio.ArchivedAt != nil {
+		return nil, fmt.Errorf("%w: %s", service.ErrPortfolioArchived, portfolio.Name)
+	}
+	return portfolio, nil
+}
+
+// defaultPortfolio returns the user's default portfolio, creating it on first use
+func defaultPortfolio(repo repository.PortfolioRepository, userID uint) (*models.Portfolio, error) {
+	portfolio, err := repo.GetDefault(userID)
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return portfolio, err
+	}
+	portfolio = &models.Portfolio{UserID: userID, Name: models.DefaultPortfolioName, IsDefault: true}
+	if err := repo.Create(portfolio); err != nil {
+		return nil, err
+	}
+	return portfolio, nil
+}
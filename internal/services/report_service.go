@@ -0,0 +1,299 @@
+package services
+
+import (
+	"ares_api/internal/api/dto"
+	repository "ares_api/internal/interfaces/repository"
+	service "ares_api/internal/interfaces/service"
+	"ares_api/internal/models"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+var _ service.ReportService = &ReportService{}
+
+// ReportService exports a portfolio's trade history and derives tax-lot reports from it
+type ReportService struct {
+	TradeRepo     repository.TradeRepository
+	PortfolioRepo repository.PortfolioRepository
+}
+
+func NewReportService(t repository.TradeRepository, p repository.PortfolioRepository) *ReportService {
+	return &ReportService{TradeRepo: t, PortfolioRepo: p}
+}
+
+// Export kinds: the orders, or the fills that executed them
+const (
+	ExportKindOrders = "orders"
+	ExportKindFills  = "fills"
+)
+
+// ExportTrades returns the portfolio's orders or fills matching filter, oldest first,
+// with USD prices and fees at the exchange rate each fill executed at
+func (s *ReportService) ExportTrades(userID, portfolioID uint, filter dto.TradeExportFilter) ([]dto.TradeExportDTO, error) {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", service.ErrInvalidExportFilter, fmt.Sprintf(format, args...))
+	}
+
+	query := repository.TradeFilter{CoinID: filter.CoinID, Side: filter.Side}
+	kind := filter.Kind
+	switch kind {
+	case "", "trades": // trades named the orders before fills were split out
+		kind = ExportKindOrders
+	case ExportKindOrders, ExportKindFills:
+	default:
+		return nil, invalid("unknown kind %q: must be orders or fills", filter.Kind)
+	}
+	switch filter.Side {
+	case "", "buy", "sell":
+	default:
+		return nil, invalid("unknown side %q: must be buy or sell", filter.Side)
+	}
+	var err error
+	if query.From, err = parseReportTime(filter.From); err != nil {
+		return nil, invalid("from: %v", err)
+	}
+	if query.To, err = parseReportTime(filter.To); err != nil {
+		return nil, invalid("to: %v", err)
+	}
+	if !query.From.IsZero() && !query.To.IsZero() && !query.From.Before(query.To) {
+		return nil, invalid("from must be before to")
+	}
+
+	portfolio, err := resolvePortfolio(s.PortfolioRepo, userID, portfolioID, false)
+	if err != nil {
+		return nil, err
+	}
+	query.PortfolioID = portfolio.ID
+	if kind == ExportKindFills {
+		return s.exportFills(query)
+	}
+	return s.exportOrders(query)
+}
+
+func (s *ReportService) exportOrders(query repository.TradeFilter) ([]dto.TradeExportDTO, error) {
+	orders, err := s.TradeRepo.Search(query)
+	if err != nil {
+		return nil, err
+	}
+	ids := make([]uint, 0, len(orders))
+	for _, o := range orders {
+		ids = append(ids, o.ID)
+	}
+	fills, err := s.TradeRepo.GetFills(ids)
+	if err != nil {
+		return nil, err
+	}
+
+	// USD notional and fee of each order's fills
+	type usdTotals struct{ notional, fee decimal.Decimal }
+	totals := map[uint]*usdTotals{}
+	for _, f := range fills {
+		price, fee := usdAmounts(f)
+		t, ok := totals[f.OrderID]
+		if !ok {
+			t = &usdTotals{}
+			totals[f.OrderID] = t
+		}
+		t.notional = t.notional.Add(f.Quantity.Mul(price))
+		t.fee = t.fee.Add(fee)
+	}
+
+	rows := make([]dto.TradeExportDTO, 0, len(orders))
+	for _, o := range orders {
+		row := dto.TradeExportDTO{
+			ID:             o.ID,
+			OrderID:        o.ID,
+			CreatedAt:      o.CreatedAt,
+			PortfolioID:    o.PortfolioID,
+			CoinID:         o.CoinID,
+			Symbol:         o.Symbol,
+			Side:           o.Side,
+			Type:           o.Type,
+			Status:         o.Status,
+			Quantity:       o.Quantity,
+			FilledQuantity: o.FilledQuantity,
+			Price:          o.Price,
+			AveragePrice:   o.AveragePrice,
+			Fee:            o.Fee,
+			QuoteCurrency:  o.QuoteCurrency,
+		}
+		if t, ok := totals[o.ID]; ok && o.FilledQuantity.IsPositive() {
+			row.PriceUSD = models.RoundPrice(t.notional.Div(o.FilledQuantity))
+			row.FeeUSD = models.RoundCash(t.fee)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (s *ReportService) exportFills(query repository.TradeFilter) ([]dto.TradeExportDTO, error) {
+	fills, err := s.TradeRepo.SearchFills(query)
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]dto.TradeExportDTO, 0, len(fills))
+	for _, f := range fills {
+		price, fee := usdAmounts(f)
+		rows = append(rows, dto.TradeExportDTO{
+			ID:             f.ID,
+			OrderID:        f.OrderID,
+			CreatedAt:      f.CreatedAt,
+			PortfolioID:    f.PortfolioID,
+			CoinID:         f.CoinID,
+			Symbol:         f.Symbol,
+			Side:           f.Side,
+			Liquidity:      f.Liquidity,
+			Quantity:       f.Quantity,
+			FilledQuantity: f.Quantity,
+			Price:          f.Price,
+			AveragePrice:   f.Price,
+			Fee:            f.Fee,
+			QuoteCurrency:  f.QuoteCurrency,
+			FXRate:         f.FXRate,
+			PriceUSD:       price,
+			FeeUSD:         fee,
+		})
+	}
+	return rows, nil
+}
+
+// TaxReport replays every fill of the portfolio through per-coin positions under a lot
+// method and reports each disposal with the acquisitions it sold, summed per UTC
+// calendar year. Lots cost what was paid for them, buy fee included, and disposals are
+// net of the sell fee. The full history is always replayed so lots bought in earlier
+// years keep their cost.
+func (s *ReportService) TaxReport(userID, portfolioID uint, method string, year int) (*dto.TaxReportDTO, error) {
+	method, err := validateLotMethod(method)
+	if err != nil {
+		return nil, err
+	}
+	if year < 0 {
+		return nil, fmt.Errorf("%w: invalid year %d", service.ErrInvalidExportFilter, year)
+	}
+
+	portfolio, err := resolvePortfolio(s.PortfolioRepo, userID, portfolioID, false)
+	if err != nil {
+		return nil, err
+	}
+	fills, err := s.TradeRepo.GetFillsByPortfolio(portfolio.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &dto.TaxReportDTO{
+		PortfolioID: portfolio.ID,
+		Method:      method,
+		Currency:    models.CurrencyUSD,
+		Years:       []dto.TaxYearDTO{},
+		Disposals:   []dto.TaxDisposalDTO{},
+	}
+	years := map[int]*dto.TaxYearDTO{}
+	positions := map[string]*position{}
+
+	for _, t := range fills {
+		pos, ok := positions[t.CoinID]
+		if !ok {
+			pos = newPosition(method)
+			positions[t.CoinID] = pos
+		}
+		if !t.Quantity.IsPositive() {
+			continue
+		}
+		price, fee := usdAmounts(t)
+		gross := t.Quantity.Mul(price)
+
+		switch t.Side {
+		case "buy":
+			pos.acquire(t.ID, t.CreatedAt, t.Quantity, gross.Add(fee).Div(t.Quantity))
+		case "sell":
+			_, lots := pos.dispose(t.Quantity)
+			disposedYear := t.CreatedAt.UTC().Year()
+			if year != 0 && disposedYear != year {
+				continue
+			}
+			unitProceeds := gross.Sub(fee).Div(t.Quantity)
+			for _, l := range lots {
+				d := dto.TaxDisposalDTO{
+					CoinID:             t.CoinID,
+					Symbol:             t.Symbol,
+					Quantity:           l.quantity,
+					AcquiredAt:         l.acquiredAt,
+					DisposedAt:         t.CreatedAt,
+					AcquisitionTradeID: l.fillID,
+					DisposalTradeID:    t.ID,
+					Proceeds:           models.RoundCash(l.quantity.Mul(unitProceeds)),
+					CostBasis:          models.RoundCash(l.quantity.Mul(l.price)),
+					Term:               gainTerm(l.acquiredAt, t.CreatedAt),
+				}
+				d.Gain = d.Proceeds.Sub(d.CostBasis)
+				report.Disposals = append(report.Disposals, d)
+
+				y, ok := years[disposedYear]
+				if !ok {
+					y = &dto.TaxYearDTO{Year: disposedYear}
+					years[disposedYear] = y
+				}
+				y.Disposals++
+				y.Proceeds = y.Proceeds.Add(d.Proceeds)
+				y.CostBasis = y.CostBasis.Add(d.CostBasis)
+				if d.Term == TermLong {
+					y.LongTermGain = y.LongTermGain.Add(d.Gain)
+				} else {
+					y.ShortTermGain = y.ShortTermGain.Add(d.Gain)
+				}
+				y.TotalGain = y.TotalGain.Add(d.Gain)
+			}
+		}
+	}
+
+	for _, y := range years {
+		report.Years = append(report.Years, *y)
+	}
+	sort.Slice(report.Years, func(i, j int) bool { return report.Years[i].Year < report.Years[j].Year })
+	return report, nil
+}
+
+// Terms of a realized gain: long term once a lot was held for more than a year
+const (
+	TermShort = "short"
+	TermLong  = "long"
+)
+
+// gainTerm is short for a lot held a year or less at disposal, long beyond that
+func gainTerm(acquiredAt, disposedAt time.Time) string {
+	if disposedAt.After(acquiredAt.AddDate(1, 0, 0)) {
+		return TermLong
+	}
+	return TermShort
+}
+
+// usdAmounts converts a fill's price and fee from its quote currency to USD at the
+// rate it executed at
+func usdAmounts(t models.Fill) (price, fee decimal.Decimal) {
+	price, fee = t.Price, t.Fee
+	if t.FXRate.IsPositive() && !t.FXRate.Equal(decimal.NewFromInt(1)) {
+		price = models.RoundPrice(price.Div(t.FXRate))
+		fee = models.RoundCash(fee.Div(t.FXRate))
+	}
+	return price, fee
+}
+
+// parseReportTime reads an RFC 3339 instant or a YYYY-MM-DD date at UTC midnight;
+// empty is the zero time, which leaves that end of the range open
+func parseReportTime(raw string) (time.Time, error) {
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, raw); err == nil {
+		return t, nil
+	}
+	t, err := time.Parse("2006-01-02", raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither RFC 3339 nor YYYY-MM-DD", raw)
+	}
+	return t, nil
+}
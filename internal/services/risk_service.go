@@ -0,0 +1,289 @@
+package services
+
+import (
+	"ares_api/internal/api/dto"
+	repository "ares_api/internal/interfaces/repository"
+	service "ares_api/internal/interfaces/service"
+	"ares_api/internal/models"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+var _ service.RiskService = &RiskService{}
+
+// RiskService keeps each portfolio's pre-trade limits and checks orders against them
+// before the trade service commits them. Every rejection or warning is written to the
+// ledger with the limits it broke.
+type RiskService struct {
+	Repo           repository.RiskLimitRepository
+	PortfolioRepo  repository.PortfolioRepository
+	TradeRepo      repository.TradeRepository
+	SnapshotRepo   repository.SnapshotRepository
+	AssetRepo      repository.AssetRepository
+	BalanceService service.BalanceService
+	LedgerService  service.LedgerService
+}
+
+func NewRiskService(r repository.RiskLimitRepository, p repository.PortfolioRepository, t repository.TradeRepository, sn repository.SnapshotRepository, a repository.AssetRepository, b service.BalanceService, l service.LedgerService) *RiskService {
+	return &RiskService{
+		Repo:           r,
+		PortfolioRepo:  p,
+		TradeRepo:      t,
+		SnapshotRepo:   sn,
+		AssetRepo:      a,
+		BalanceService: b,
+		LedgerService:  l,
+	}
+}
+
+func toRiskLimitsDTO(limit *models.RiskLimit) *dto.RiskLimitsDTO {
+	return &dto.RiskLimitsDTO{
+		PortfolioID:      limit.PortfolioID,
+		MaxPositionValue: limit.MaxPositionValue,
+		MaxOrderNotional: limit.MaxOrderNotional,
+		MaxDailyLoss:     limit.MaxDailyLoss,
+		MaxOpenOrders:    limit.MaxOpenOrders,
+		MaxConcentration: limit.MaxConcentration,
+		Mode:             limit.Mode,
+	}
+}
+
+// GetLimits returns the portfolio's limits; a portfolio that never set any has them all off
+func (s *RiskService) GetLimits(userID, portfolioID uint) (*dto.RiskLimitsDTO, error) {
+	portfolio, err := resolvePortfolio(s.PortfolioRepo, userID, portfolioID, false)
+	if err != nil {
+		return nil, err
+	}
+	limit, err := s.Repo.GetByPortfolio(portfolio.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return toRiskLimitsDTO(&models.RiskLimit{PortfolioID: portfolio.ID, Mode: models.RiskModeReject}), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toRiskLimitsDTO(limit), nil
+}
+
+// SetLimits replaces every limit of the portfolio
+func (s *RiskService) SetLimits(userID, portfolioID uint, req dto.RiskLimitsRequest) (*dto.RiskLimitsDTO, error) {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", service.ErrInvalidRiskLimits, fmt.Sprintf(format, args...))
+	}
+
+	mode := req.Mode
+	switch mode {
+	case "":
+		mode = models.RiskModeReject
+	case models.RiskModeReject, models.RiskModeWarn:
+	default:
+		return nil, invalid("unknown mode %q: must be reject or warn", req.Mode)
+	}
+	if req.MaxPositionValue.IsNegative() || req.MaxOrderNotional.IsNegative() || req.MaxDailyLoss.IsNegative() || req.MaxOpenOrders < 0 {
+		return nil, invalid("limits can't be negative")
+	}
+	if req.MaxConcentration.IsNegative() || req.MaxConcentration.GreaterThan(decimal.NewFromInt(100)) {
+		return nil, invalid("max_concentration must be between 0 and 100")
+	}
+
+	portfolio, err := resolvePortfolio(s.PortfolioRepo, userID, portfolioID, true)
+	if err != nil {
+		return nil, err
+	}
+	limit, err := s.Repo.GetByPortfolio(portfolio.ID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		limit = &models.RiskLimit{UserID: userID, PortfolioID: portfolio.ID}
+	} else if err != nil {
+		return nil, err
+	}
+
+	limit.MaxPositionValue = models.RoundCash(req.MaxPositionValue)
+	limit.MaxOrderNotional = models.RoundCash(req.MaxOrderNotional)
+	limit.MaxDailyLoss = models.RoundCash(req.MaxDailyLoss)
+	limit.MaxOpenOrders = req.MaxOpenOrders
+	limit.MaxConcentration = req.MaxConcentration.Round(4)
+	limit.Mode = mode
+	if err := s.Repo.Save(limit); err != nil {
+		return nil, err
+	}
+	return toRiskLimitsDTO(limit), nil
+}
+
+// riskLedgerEntry is the ledger record of an order that broke a limit
+type riskLedgerEntry struct {
+	PortfolioID uint                   `json:"portfolio_id"`
+	CoinID      string                 `json:"coin_id"`
+	Side        string                 `json:"side"`
+	Quantity    decimal.Decimal        `json:"quantity"`
+	Price       decimal.Decimal        `json:"price"`
+	Currency    string                 `json:"currency"`
+	Violations  []dto.RiskViolationDTO `json:"violations"`
+}
+
+// CheckOrder evaluates order against its portfolio's limits. Portfolios without limits
+// pass every order.
+func (s *RiskService) CheckOrder(order service.OrderIntent) ([]dto.RiskViolationDTO, error) {
+	limit, err := s.Repo.GetByPortfolio(order.PortfolioID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	violations, err := s.evaluate(limit, order)
+	if err != nil {
+		return nil, err
+	}
+	if len(violations) == 0 {
+		return nil, nil
+	}
+
+	entry := riskLedgerEntry{
+		PortfolioID: order.PortfolioID,
+		CoinID:      order.CoinID,
+		Side:        order.Side,
+		Quantity:    order.Quantity,
+		Price:       order.Price,
+		Currency:    order.Currency,
+		Violations:  violations,
+	}
+	if limit.Mode == models.RiskModeWarn {
+		if !order.DryRun {
+			_ = s.LedgerService.Append(order.UserID, "RiskWarning", entry)
+		}
+		return violations, nil
+	}
+	if !order.DryRun {
+		_ = s.LedgerService.Append(order.UserID, "RiskRejected", entry)
+	}
+	return nil, &service.RiskRejection{Violations: violations}
+}
+
+// evaluate lists every limit order breaks, all measured in USD. Position, concentration
+// and daily loss limits only hold back buys: a sell can only shrink the exposure.
+func (s *RiskService) evaluate(limit *models.RiskLimit, order service.OrderIntent) ([]dto.RiskViolationDTO, error) {
+	var violations []dto.RiskViolationDTO
+	add := func(rule string, limit, actual decimal.Decimal, format string, args ...interface{}) {
+		violations = append(violations, dto.RiskViolationDTO{
+			Rule:    rule,
+			Limit:   limit,
+			Actual:  actual,
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	rate, err := fxRate(s.AssetRepo, order.Currency)
+	if err != nil {
+		return nil, err
+	}
+	notional := models.RoundCash(order.Quantity.Mul(order.Price).Div(rate))
+
+	if limit.MaxOrderNotional.IsPositive() && notional.GreaterThan(limit.MaxOrderNotional) {
+		add(models.RiskRuleMaxOrderNotional, limit.MaxOrderNotional, notional,
+			"order notional %s USD is above the %s USD limit", notional, limit.MaxOrderNotional)
+	}
+
+	if limit.MaxOpenOrders > 0 && order.Resting {
+		open, err := s.TradeRepo.GetOpenOrdersByPortfolio(order.PortfolioID)
+		if err != nil {
+			return nil, err
+		}
+		count := max(order.Orders, 1)
+		for _, o := range open {
+			if o.ID != order.Amends {
+				count++
+			}
+		}
+		if count > limit.MaxOpenOrders {
+			add(models.RiskRuleMaxOpenOrders, decimal.NewFromInt(int64(limit.MaxOpenOrders)), decimal.NewFromInt(int64(count)),
+				"%d open orders would be above the limit of %d", count, limit.MaxOpenOrders)
+		}
+	}
+
+	if order.Side != "buy" || !(limit.MaxPositionValue.IsPositive() || limit.MaxConcentration.IsPositive() || limit.MaxDailyLoss.IsPositive()) {
+		return violations, nil
+	}
+
+	portfolio, err := s.BalanceService.GetPortfolio(order.UserID, order.PortfolioID, models.CurrencyUSD)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return violations, nil // no cash yet, so nothing to measure against
+	}
+	if err != nil {
+		return nil, err
+	}
+	position := decimal.Zero
+	for _, h := range portfolio.Holdings {
+		if h.CoinID == order.CoinID {
+			position = h.Value
+		}
+	}
+	after := position.Add(notional)
+
+	if limit.MaxPositionValue.IsPositive() && after.GreaterThan(limit.MaxPositionValue) {
+		add(models.RiskRuleMaxPositionValue, limit.MaxPositionValue, after,
+			"%s position of %s USD would be above the %s USD limit", order.CoinID, after, limit.MaxPositionValue)
+	}
+
+	if limit.MaxConcentration.IsPositive() && portfolio.TotalValue.IsPositive() {
+		share := after.Div(portfolio.TotalValue).Mul(decimal.NewFromInt(100)).Round(2)
+		if share.GreaterThan(limit.MaxConcentration) {
+			add(models.RiskRuleMaxConcentration, limit.MaxConcentration, share,
+				"%s would be %s%% of the portfolio, above the %s%% limit", order.CoinID, share, limit.MaxConcentration)
+		}
+	}
+
+	if limit.MaxDailyLoss.IsPositive() {
+		start, err := s.dayStartValue(order, portfolio)
+		if err != nil {
+			return nil, err
+		}
+		if loss := start.Sub(portfolio.TotalValue); loss.GreaterThanOrEqual(limit.MaxDailyLoss) {
+			add(models.RiskRuleMaxDailyLoss, limit.MaxDailyLoss, loss,
+				"portfolio is down %s USD today, at or above the %s USD limit", loss, limit.MaxDailyLoss)
+		}
+	}
+
+	return violations, nil
+}
+
+// dayStartValue is the portfolio's USD value when the current UTC day began: its first
+// equity snapshot of the day, else its last snapshot from before midnight. A portfolio
+// with no snapshot at all is snapshotted now, at current, so the day's losses count
+// from this order on; previews use current without storing it.
+func (s *RiskService) dayStartValue(order service.OrderIntent, current *dto.PortfolioDTO) (decimal.Decimal, error) {
+	midnight := time.Now().UTC().Truncate(24 * time.Hour)
+	snapshots, err := s.SnapshotRepo.GetByPortfolioSince(order.PortfolioID, midnight)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if len(snapshots) > 0 {
+		return snapshots[0].TotalValue, nil
+	}
+
+	before, err := s.SnapshotRepo.GetLatestBefore(order.PortfolioID, midnight)
+	if err == nil {
+		return before.TotalValue, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return decimal.Zero, err
+	}
+
+	if !order.DryRun {
+		snapshot := &models.PortfolioSnapshot{
+			UserID:        order.UserID,
+			PortfolioID:   order.PortfolioID,
+			TakenAt:       time.Now(),
+			Cash:          current.Cash,
+			HoldingsValue: current.HoldingsValue,
+			TotalValue:    current.TotalValue,
+		}
+		if err := s.SnapshotRepo.Create(snapshot); err != nil {
+			return decimal.Zero, err
+		}
+	}
+	return current.TotalValue, nil
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"ares_api/internal/api/dto"
+	service "ares_api/internal/interfaces/service"
+	"ares_api/internal/models"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// testSnapshots keeps snapshots in memory
+type testSnapshots struct {
+	snapshots []models.PortfolioSnapshot
+}
+
+func (r *testSnapshots) Create(snapshot *models.PortfolioSnapshot) error {
+	r.snapshots = append(r.snapshots, *snapshot)
+	return nil
+}
+
+func (r *testSnapshots) GetByPortfolioSince(portfolioID uint, from time.Time) ([]models.PortfolioSnapshot, error) {
+	var since []models.PortfolioSnapshot
+	for _, s := range r.snapshots {
+		if s.PortfolioID == portfolioID && !s.TakenAt.Before(from) {
+			since = append(since, s)
+		}
+	}
+	return since, nil
+}
+
+func (r *testSnapshots) GetLatestBefore(portfolioID uint, before time.Time) (*models.PortfolioSnapshot, error) {
+	var latest *models.PortfolioSnapshot
+	for i, s := range r.snapshots {
+		if s.PortfolioID == portfolioID && s.TakenAt.Before(before) && (latest == nil || s.TakenAt.After(latest.TakenAt)) {
+			latest = &r.snapshots[i]
+		}
+	}
+	if latest == nil {
+		return nil, gorm.ErrRecordNotFound
+	}
+	return latest, nil
+}
+
+func TestDayStartValue(t *testing.T) {
+	midnight := time.Now().UTC().Truncate(24 * time.Hour)
+	snapshot := func(at time.Time, value int64) models.PortfolioSnapshot {
+		return models.PortfolioSnapshot{PortfolioID: 1, TakenAt: at, TotalValue: decimal.NewFromInt(value)}
+	}
+	current := &dto.PortfolioDTO{TotalValue: decimal.NewFromInt(700)}
+
+	tests := []struct {
+		name      string
+		snapshots []models.PortfolioSnapshot
+		dryRun    bool
+		want      int64
+		stored    int // snapshots stored afterwards
+	}{
+		{"first of the day", []models.PortfolioSnapshot{
+			snapshot(midnight.Add(-time.Hour), 900),
+			snapshot(midnight.Add(time.Minute), 1000),
+			snapshot(midnight.Add(time.Hour), 800),
+		}, false, 1000, 3},
+		{"last before midnight", []models.PortfolioSnapshot{
+			snapshot(midnight.Add(-48*time.Hour), 500),
+			snapshot(midnight.Add(-time.Hour), 900),
+		}, false, 900, 2},
+		{"none yet", nil, false, 700, 1},
+		{"none yet in a preview", nil, true, 700, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo := &testSnapshots{snapshots: tt.snapshots}
+			s := &RiskService{SnapshotRepo: repo}
+			got, err := s.dayStartValue(service.OrderIntent{UserID: 1, PortfolioID: 1, DryRun: tt.dryRun}, current)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !got.Equal(decimal.NewFromInt(tt.want)) {
+				t.Errorf("day start = %s, want %d", got, tt.want)
+			}
+			if len(repo.snapshots) != tt.stored {
+				t.Errorf("%d snapshots stored, want %d", len(repo.snapshots), tt.stored)
+			}
+		})
+	}
+}
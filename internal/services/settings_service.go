@@ -1,10 +1,12 @@
 package services
 
 import (
+	"ares_api/internal/api/dto"
 	repo "ares_api/internal/interfaces/repository"
 	service "ares_api/internal/interfaces/service"
 	"ares_api/internal/models"
 	"errors"
+	"fmt"
 )
 
 var _ service.SettingsService = &SettingsService{}
@@ -32,6 +34,70 @@ func (s *SettingsService) SaveAPIKey(userID uint, apiKey string) error {
 	return s.Repo.Save(setting)
 }
 
+// GetFeeSchedule returns the user's execution cost preset and every preset available
+func (s *SettingsService) GetFeeSchedule(userID uint) (*dto.FeeScheduleResponse, error) {
+	current := DefaultFeeSchedule
+	if setting, err := s.Repo.GetByUserID(userID); err == nil && setting.FeeSchedule != "" {
+		current = setting.FeeSchedule
+	}
 
+	res := &dto.FeeScheduleResponse{Current: current}
+	for _, name := range FeeScheduleNames() {
+		f := FeeSchedules[name]
+		res.Available = append(res.Available, dto.FeeScheduleDTO{
+			Name:       f.Name,
+			MakerRate:  f.MakerRate,
+			TakerRate:  f.TakerRate,
+			FixedFee:   f.FixedFee,
+			SpreadRate: f.SpreadRate,
+			ImpactRate: f.ImpactRate,
+			DepthUSD:   f.DepthUSD,
+		})
+	}
+	return res, nil
+}
 
+// SetFeeSchedule picks the execution cost preset used for the user's future fills
+func (s *SettingsService) SetFeeSchedule(userID uint, name string) (*dto.FeeScheduleResponse, error) {
+	if _, ok := FeeSchedules[name]; !ok {
+		return nil, fmt.Errorf("unknown fee schedule %q", name)
+	}
 
+	setting, err := s.Repo.GetByUserID(userID)
+	if err != nil {
+		setting = &models.Setting{UserID: userID}
+	}
+	setting.FeeSchedule = name
+	if err := s.Repo.Save(setting); err != nil {
+		return nil, err
+	}
+	return s.GetFeeSchedule(userID)
+}
+
+// GetDisplayCurrency returns the currency the user's portfolio totals are reported in
+// and every currency available
+func (s *SettingsService) GetDisplayCurrency(userID uint) (*dto.DisplayCurrencyResponse, error) {
+	current := models.CurrencyUSD
+	if setting, err := s.Repo.GetByUserID(userID); err == nil && setting.DisplayCurrency != "" {
+		current = setting.DisplayCurrency
+	}
+	return &dto.DisplayCurrencyResponse{Current: current, Available: models.QuoteCurrencies()}, nil
+}
+
+// SetDisplayCurrency picks the currency the user's portfolio totals are reported in
+func (s *SettingsService) SetDisplayCurrency(userID uint, currency string) (*dto.DisplayCurrencyResponse, error) {
+	currency, err := parseCurrency(currency)
+	if err != nil {
+		return nil, err
+	}
+
+	setting, err := s.Repo.GetByUserID(userID)
+	if err != nil {
+		setting = &models.Setting{UserID: userID}
+	}
+	setting.DisplayCurrency = currency
+	if err := s.Repo.Save(setting); err != nil {
+		return nil, err
+	}
+	return s.GetDisplayCurrency(userID)
+}
@@ -0,0 +1,252 @@
+package services
+
+import (
+	"ares_api/internal/api/dto"
+	repository "ares_api/internal/interfaces/repository"
+	service "ares_api/internal/interfaces/service"
+	"ares_api/internal/models"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+)
+
+// errPreviewRollback ends a preview's transaction so nothing it did is committed
+var errPreviewRollback = errors.New("preview rolled back")
+
+// PreviewOrder places a market or limit order through MarketOrder or LimitOrder, the
+// code that executes it for real, and rolls the transaction back at the end. Risk checks
+// run without logging to the ledger. What the order did is read back before the
+// rollback, so a preview prices and settles exactly like an order placed with the same
+// quote. Order ids used by previews are skipped by the next real order. An order that
+// would be rejected is previewed as rejected rather than failing the preview.
+func (s *TradeService) PreviewOrder(userID, portfolioID uint, req dto.TradePreviewRequest) (*dto.TradePreviewDTO, error) {
+	currency, err := parseCurrency(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	portfolio, err := resolvePortfolio(s.PortfolioRepo, userID, portfolioID, true)
+	if err != nil {
+		return nil, err
+	}
+	coinID, symbol, err := s.resolveCoin(req.CoinID, req.Symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	quotes := &quoteRecorder{AssetRepository: s.AssetRepo, coinID: coinID, vsCurrency: strings.ToLower(currency)}
+	var before, after previewBalances
+	dry := *s
+	dry.AssetRepo = quotes
+	dry.Risk = dryRunRisk{s.Risk}
+	dry.TxManager = rollbackTx{
+		TxManager: s.TxManager,
+		before: func(tx *gorm.DB) (err error) {
+			before, err = s.readPreviewBalances(tx, portfolio.ID, currency, coinID)
+			return err
+		},
+		after: func(tx *gorm.DB) (err error) {
+			after, err = s.readPreviewBalances(tx, portfolio.ID, currency, coinID)
+			return err
+		},
+	}
+
+	var order *dto.TradeResponse
+	switch req.Type {
+	case models.OrderTypeMarket:
+		order, err = dry.MarketOrder(userID, portfolio.ID, dto.MarketOrderRequest{
+			CoinID:   coinID,
+			Symbol:   symbol,
+			Side:     req.Side,
+			Quantity: req.Quantity,
+			Currency: currency,
+		})
+	case models.OrderTypeLimit:
+		order, err = dry.LimitOrder(userID, portfolio.ID, dto.LimitOrderRequest{
+			CoinID:      coinID,
+			Symbol:      symbol,
+			Side:        req.Side,
+			Quantity:    req.Quantity,
+			LimitPrice:  req.LimitPrice,
+			Currency:    currency,
+			TimeInForce: req.TimeInForce,
+			ExpiresAt:   req.ExpiresAt,
+		})
+	default:
+		return nil, fmt.Errorf("%w: %q, previews take market or limit", service.ErrInvalidOrderType, req.Type)
+	}
+	if isOrderRejection(err) {
+		return s.rejectedPreview(portfolio.ID, coinID, symbol, currency, req, quotes.market, err)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res := &dto.TradePreviewDTO{
+		Type:             order.Type,
+		CoinID:           order.CoinID,
+		Symbol:           order.Symbol,
+		Side:             order.Side,
+		Quantity:         order.Quantity,
+		QuoteCurrency:    order.QuoteCurrency,
+		TimeInForce:      order.TimeInForce,
+		Status:           order.Status,
+		Rejected:         order.Status == models.OrderStatusRejected,
+		FilledQuantity:   order.FilledQuantity,
+		ExpectedPrice:    order.AveragePrice,
+		Fee:              order.Fee,
+		ReservedAmount:   after.reserved.Sub(before.reserved),
+		ReservedQuantity: after.holdingReserved.Sub(before.holdingReserved),
+		Cash:             dto.PreviewBalanceDTO{Asset: currency, Before: before.cash, After: after.cash},
+		CashAvailable:    after.cash.Sub(after.reserved),
+		Holding:          dto.PreviewBalanceDTO{Asset: coinID, Before: before.holding, After: after.holding},
+		HoldingAvailable: after.holding.Sub(after.holdingReserved),
+		RiskWarnings:     order.RiskWarnings,
+	}
+	if quotes.market != nil {
+		res.MarketPrice = marketPrice(quotes.market)
+	}
+	if res.Rejected {
+		res.Reason = "the order could not fill"
+	}
+	if res.MarketPrice.IsPositive() && res.FilledQuantity.IsPositive() {
+		res.Slippage = res.ExpectedPrice.Div(res.MarketPrice).Sub(decimal.NewFromInt(1)).Round(8)
+		res.SlippageCost = models.RoundAmount(currency, res.ExpectedPrice.Sub(res.MarketPrice).Abs().Mul(res.FilledQuantity))
+	}
+
+	// A limit left on the book fills at its limit price as a maker
+	if order.Type == models.OrderTypeLimit && models.IsOrderActive(order.Status) {
+		cost, err := s.executionCost(userID, currency)
+		if err != nil {
+			return nil, err
+		}
+		res.RestingQuantity = order.Quantity.Sub(order.FilledQuantity)
+		res.RestingFee = quoteExecution(cost, currency, order.Side, res.RestingQuantity, order.Price, decimal.Zero, service.LiquidityMaker, order.Price).Fee
+	}
+	return res, nil
+}
+
+// isOrderRejection reports whether err turns an order away, as opposed to a request the
+// preview can't answer
+func isOrderRejection(err error) bool {
+	return errors.Is(err, service.ErrInvalidOrder) ||
+		errors.Is(err, service.ErrInsufficientFunds) ||
+		errors.Is(err, service.ErrInsufficientHolding) ||
+		errors.Is(err, service.ErrRiskRejected)
+}
+
+// rejectedPreview reports an order that would be turned away with err, and the
+// portfolio's balances as they stand
+func (s *TradeService) rejectedPreview(portfolioID uint, coinID, symbol, currency string, req dto.TradePreviewRequest, market *dto.CoinMarketDTO, err error) (*dto.TradePreviewDTO, error) {
+	res := &dto.TradePreviewDTO{
+		Type:          req.Type,
+		CoinID:        coinID,
+		Symbol:        symbol,
+		Side:          req.Side,
+		Quantity:      req.Quantity,
+		QuoteCurrency: currency,
+		TimeInForce:   strings.ToUpper(req.TimeInForce),
+		Status:        models.OrderStatusRejected,
+		Rejected:      true,
+		Reason:        err.Error(),
+	}
+	var rejection *service.RiskRejection
+	if errors.As(err, &rejection) {
+		res.Violations = rejection.Violations
+	}
+	if market != nil {
+		res.MarketPrice = marketPrice(market)
+	}
+
+	var b previewBalances
+	err = s.TxManager.Transaction(func(tx *gorm.DB) (err error) {
+		b, err = s.readPreviewBalances(tx, portfolioID, currency, coinID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	res.Cash = dto.PreviewBalanceDTO{Asset: currency, Before: b.cash, After: b.cash}
+	res.CashAvailable = b.cash.Sub(b.reserved)
+	res.Holding = dto.PreviewBalanceDTO{Asset: coinID, Before: b.holding, After: b.holding}
+	res.HoldingAvailable = b.holding.Sub(b.holdingReserved)
+	return res, nil
+}
+
+// previewBalances is the part of a portfolio an order moves
+type previewBalances struct {
+	cash, reserved, holding, holdingReserved decimal.Decimal
+}
+
+// readPreviewBalances reads the portfolio's cash in currency and its coinID holding
+func (s *TradeService) readPreviewBalances(tx *gorm.DB, portfolioID uint, currency, coinID string) (previewBalances, error) {
+	var b previewBalances
+	balance, err := s.BalanceRepo.WithTx(tx).GetBalance(portfolioID, currency)
+	switch {
+	case err == nil:
+		b.cash, b.reserved = balance.Amount, balance.Reserved
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return b, fmt.Errorf("failed to get %s balance: %w", currency, err)
+	}
+	holding, err := s.HoldingRepo.WithTx(tx).GetHolding(portfolioID, coinID)
+	switch {
+	case err == nil:
+		b.holding, b.holdingReserved = holding.Quantity, holding.Reserved
+	case !errors.Is(err, gorm.ErrRecordNotFound):
+		return b, fmt.Errorf("failed to get %s holding: %w", coinID, err)
+	}
+	return b, nil
+}
+
+// rollbackTx runs each transaction to the end and rolls it back, calling before and
+// after around the work so the preview can read what it changed
+type rollbackTx struct {
+	repository.TxManager
+	before, after func(tx *gorm.DB) error
+}
+
+func (m rollbackTx) Transaction(fn func(tx *gorm.DB) error) error {
+	err := m.TxManager.Transaction(func(tx *gorm.DB) error {
+		if err := m.before(tx); err != nil {
+			return err
+		}
+		if err := fn(tx); err != nil {
+			return err
+		}
+		if err := m.after(tx); err != nil {
+			return err
+		}
+		return errPreviewRollback
+	})
+	if errors.Is(err, errPreviewRollback) {
+		return nil
+	}
+	return err
+}
+
+// dryRunRisk runs the pre-trade checks without logging them
+type dryRunRisk struct {
+	service.RiskService
+}
+
+func (r dryRunRisk) CheckOrder(order service.OrderIntent) ([]dto.RiskViolationDTO, error) {
+	order.DryRun = true
+	return r.RiskService.CheckOrder(order)
+}
+
+// quoteRecorder keeps the first quote of the order's coin, the one it is priced against
+type quoteRecorder struct {
+	repository.AssetRepository
+	coinID, vsCurrency string
+	market             *dto.CoinMarketDTO
+}
+
+func (r *quoteRecorder) FetchCoinMarket(coinID, vsCurrency string) (*dto.CoinMarketDTO, error) {
+	market, err := r.AssetRepository.FetchCoinMarket(coinID, vsCurrency)
+	if err == nil && r.market == nil && coinID == r.coinID && vsCurrency == r.vsCurrency {
+		r.market = market
+	}
+	return market, err
+}
@@ -5,250 +5,870 @@ import (
 	repository "ares_api/internal/interfaces/repository"
 	service "ares_api/internal/interfaces/service"
 	"ares_api/internal/models"
+	"errors"
 	"fmt"
+	"strings"
 	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
 )
 
 var _ service.TradeService = &TradeService{}
 
 type TradeService struct {
-	Repo        repository.TradeRepository
-	BalanceRepo repository.BalanceRepository
-	AssetRepo   repository.AssetRepository
+	Repo          repository.TradeRepository
+	BalanceRepo   repository.BalanceRepository
+	HoldingRepo   repository.HoldingRepository
+	PortfolioRepo repository.PortfolioRepository
+	AssetRepo     repository.AssetRepository
+	SettingsRepo  repository.SettingsRepository
+	TxManager     repository.TxManager
+	Risk          service.RiskService  // pre-trade checks run before market and limit orders commit
+	Catalog       service.AssetService // resolves the coin of every new order
 }
 
-func NewTradeService(r repository.TradeRepository, b repository.BalanceRepository, a repository.AssetRepository) *TradeService {
+func NewTradeService(r repository.TradeRepository, b repository.BalanceRepository, h repository.HoldingRepository, p repository.PortfolioRepository, a repository.AssetRepository, st repository.SettingsRepository, tx repository.TxManager, rk service.RiskService, c service.AssetService) *TradeService {
 	return &TradeService{
-		Repo:        r,
-		BalanceRepo: b,
-		AssetRepo:   a,
+		Repo:          r,
+		BalanceRepo:   b,
+		HoldingRepo:   h,
+		PortfolioRepo: p,
+		AssetRepo:     a,
+		SettingsRepo:  st,
+		TxManager:     tx,
+		Risk:          rk,
+		Catalog:       c,
 	}
 }
 
-// MarketOrder executes immediately and updates USD balance
-func (s *TradeService) MarketOrder(userID uint, req dto.MarketOrderRequest) (*dto.TradeResponse, error) {
-	// Always transact in USD
-	const baseCurrency = "usd"
+// resolveCoin checks an order's coin against the catalog and returns the catalog's coin
+// id with the upper case symbol orders record
+func (s *TradeService) resolveCoin(coinID, symbol string) (string, string, error) {
+	coin, err := s.Catalog.ResolveCoin(coinID, symbol)
+	if err != nil {
+		return "", "", err
+	}
+	return coin.ID, strings.ToUpper(coin.Symbol), nil
+}
+
+// executionCost returns the fee and slippage model picked in the user's settings,
+// pricing fills in currency
+func (s *TradeService) executionCost(userID uint, currency string) (service.ExecutionCostModel, error) {
+	schedule := userFeeSchedule(s.SettingsRepo, userID)
+	if currency == models.CurrencyUSD {
+		return schedule, nil
+	}
+	rate, err := fxRate(s.AssetRepo, currency)
+	if err != nil {
+		return nil, err
+	}
+	return quotedCost{Model: schedule, Rate: rate}, nil
+}
+
+// userFeeSchedule returns the fee schedule picked in the user's settings, or the default
+func userFeeSchedule(settings repository.SettingsRepository, userID uint) FeeSchedule {
+	if setting, err := settings.GetByUserID(userID); err == nil {
+		if schedule, ok := FeeSchedules[setting.FeeSchedule]; ok {
+			return schedule
+		}
+	}
+	return FeeSchedules[DefaultFeeSchedule]
+}
+
+// MarketOrder executes immediately and updates the portfolio's balance of the requested
+// quote currency and its coin holding
+func (s *TradeService) MarketOrder(userID, portfolioID uint, req dto.MarketOrderRequest) (*dto.TradeResponse, error) {
+	currency, err := parseCurrency(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	portfolio, err := resolvePortfolio(s.PortfolioRepo, userID, portfolioID, true)
+	if err != nil {
+		return nil, err
+	}
+	if req.Side != "buy" && req.Side != "sell" {
+		return nil, fmt.Errorf("%w: side %q must be buy or sell", service.ErrInvalidOrder, req.Side)
+	}
+	req.CoinID, req.Symbol, err = s.resolveCoin(req.CoinID, req.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	quantity := models.RoundQuantity(req.CoinID, req.Quantity)
+	if !quantity.IsPositive() {
+		return nil, fmt.Errorf("%w: quantity must be positive", service.ErrInvalidOrder)
+	}
 
 	// Fetch current price from CoinGecko
-	coinMarket, err := s.AssetRepo.FetchCoinMarket(req.CoinID, baseCurrency)
+	coinMarket, err := s.AssetRepo.FetchCoinMarket(req.CoinID, strings.ToLower(currency))
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch market price: %w", err)
 	}
-	price := coinMarket.PriceUSD
-	cost := req.Quantity * price
+	cost, err := s.executionCost(userID, currency)
+	if err != nil {
+		return nil, err
+	}
+	quote := quoteExecution(cost, currency, req.Side, quantity, marketPrice(coinMarket), marketCap(coinMarket), service.LiquidityTaker, decimal.Zero)
+
+	warnings, err := s.Risk.CheckOrder(service.OrderIntent{
+		UserID:      userID,
+		PortfolioID: portfolio.ID,
+		CoinID:      req.CoinID,
+		Side:        req.Side,
+		Currency:    currency,
+		Quantity:    quantity,
+		Price:       quote.Price,
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	// Get user USD balance
-	balance, err := s.BalanceRepo.GetUSDBalance(userID)
+	order := &models.Order{
+		UserID:        userID,
+		PortfolioID:   portfolio.ID,
+		CoinID:        req.CoinID,
+		Symbol:        req.Symbol,
+		Side:          req.Side,
+		Quantity:      quantity,
+		Price:         quote.Price,
+		Type:          models.OrderTypeMarket,
+		Status:        models.OrderStatusOpen,
+		TimeInForce:   models.TimeInForceIOC,
+		QuoteCurrency: currency,
+	}
+	var fills []models.Fill
+	err = s.TxManager.Transaction(func(tx *gorm.DB) error {
+		if err := s.Repo.WithTx(tx).Create(order); err != nil {
+			return fmt.Errorf("failed to create market order: %w", err)
+		}
+		if err := s.execute(tx, order, quantity, quote, service.LiquidityTaker); err != nil {
+			return err
+		}
+		if err := s.transition(tx, order, models.OrderStatusFilled); err != nil {
+			return err
+		}
+		fills, err = s.Repo.WithTx(tx).GetFills([]uint{order.ID})
+		return err
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to get USD balance: %w", err)
+		return nil, err
 	}
 
-	// Check balance
-	if req.Side == "buy" && balance.Amount < cost {
-		return nil, fmt.Errorf("insufficient USD balance")
+	res := toTradeResponse(order, fills)
+	res.RiskWarnings = warnings
+	return &res, nil
+}
+
+// execute settles a fill of quantity of order at the quoted price and fee, records it
+// with the USD exchange rate it executed at and adds it to the order's filled quantity,
+// fee and average price. The caller saves the order. It must run inside tx.
+func (s *TradeService) execute(tx *gorm.DB, order *models.Order, quantity decimal.Decimal, quote service.ExecutionQuote, liquidity string) error {
+	rate, err := fxRate(s.AssetRepo, order.QuoteCurrency)
+	if err != nil {
+		return err
 	}
-	if req.Side == "sell" {
-		// For demo: we only check if user has coins hypothetically
-		// In real-world, you’d maintain user holdings per coin
-		// Here, assume user can always sell (or extend with holdings later)
+	if err := s.settle(tx, order.UserID, order.PortfolioID, order.CoinID, order.Symbol, order.Side, order.QuoteCurrency, quantity, quote.Price, quote.Fee); err != nil {
+		return err
 	}
 
-	// Update USD balance
-	switch req.Side {
+	fill := &models.Fill{
+		OrderID:       order.ID,
+		UserID:        order.UserID,
+		PortfolioID:   order.PortfolioID,
+		CoinID:        order.CoinID,
+		Symbol:        order.Symbol,
+		Side:          order.Side,
+		Quantity:      quantity,
+		Price:         quote.Price,
+		Fee:           quote.Fee,
+		Liquidity:     liquidity,
+		QuoteCurrency: order.QuoteCurrency,
+		FXRate:        rate,
+	}
+	if err := s.Repo.WithTx(tx).CreateFill(fill); err != nil {
+		return fmt.Errorf("failed to record fill: %w", err)
+	}
+
+	filled := order.FilledQuantity.Add(quantity)
+	order.AveragePrice = models.RoundPrice(order.AveragePrice.Mul(order.FilledQuantity).Add(quantity.Mul(quote.Price)).Div(filled))
+	order.FilledQuantity = filled
+	order.Fee = order.Fee.Add(quote.Fee)
+	return nil
+}
+
+// fillCashFlow is the amount of currency a fill of quantity at price moves. The notional
+// is rounded to the currency's scale against the trader: buys pay it rounded up plus the
+// fee, sells receive it rounded down less the fee. A buy and a sell at one price cancel
+// exactly when the notional fits the scale and otherwise cost one unit of it.
+func fillCashFlow(currency, side string, quantity, price, fee decimal.Decimal) decimal.Decimal {
+	if side == "buy" {
+		return models.RoundCost(currency, quantity.Mul(price)).Add(fee).Neg()
+	}
+	return models.RoundProceeds(currency, quantity.Mul(price)).Sub(fee)
+}
+
+// settle moves the portfolio's cash in currency and coins for a fill of quantity at
+// price. It must run inside tx. The cash row is always locked first so concurrent orders
+// for one portfolio queue on it and buy/sell paths can't deadlock on the holding row.
+func (s *TradeService) settle(tx *gorm.DB, userID, portfolioID uint, coinID, symbol, side, currency string, quantity, price, fee decimal.Decimal) error {
+	balanceRepo := s.BalanceRepo.WithTx(tx)
+	holdingRepo := s.HoldingRepo.WithTx(tx)
+	cashFlow := fillCashFlow(currency, side, quantity, price, fee)
+
+	balance, err := s.lockBalance(tx, userID, portfolioID, currency)
+	if err != nil {
+		return err
+	}
+
+	switch side {
 	case "buy":
+		// Funds reserved by open limit orders are not spendable
+		if balance.Amount.Sub(balance.Reserved).LessThan(cashFlow.Neg()) {
+			return fmt.Errorf("%w in %s", service.ErrInsufficientFunds, currency)
+		}
 		// Subtract cost
-		if _, err := s.BalanceRepo.UpdateUSDBalance(userID, -cost); err != nil {
-			return nil, err
+		if _, err := balanceRepo.UpdateBalance(userID, portfolioID, currency, cashFlow); err != nil {
+			return err
+		}
+		if _, err := holdingRepo.UpdateHolding(userID, portfolioID, coinID, symbol, quantity); err != nil {
+			return err
 		}
 	case "sell":
+		// Coins reserved by open sell limits are not sellable
+		holding, err := holdingRepo.GetHoldingForUpdate(portfolioID, coinID)
+		if err != nil || holding.Quantity.Sub(holding.Reserved).LessThan(quantity) {
+			return fmt.Errorf("%w of %s", service.ErrInsufficientHolding, coinID)
+		}
 		// Add proceeds
-		if _, err := s.BalanceRepo.UpdateUSDBalance(userID, cost); err != nil {
-			return nil, err
+		if _, err := holdingRepo.UpdateHolding(userID, portfolioID, coinID, symbol, quantity.Neg()); err != nil {
+			return err
 		}
+		if _, err := balanceRepo.UpdateBalance(userID, portfolioID, currency, cashFlow); err != nil {
+			return err
+		}
+	default:
+		return fmt.Errorf("%w: side %q must be buy or sell", service.ErrInvalidOrder, side)
 	}
+	return nil
+}
 
-	// Record the trade
-	trade := &models.Trade{
-		UserID:   userID,
-		CoinID:  req.CoinID,
-		Symbol:   req.Symbol,
-		Side:     req.Side,
-		Quantity: req.Quantity,
-		Price:    price,
-		Type:     "market",
-		Status:   "filled",
+// lockBalance locks the portfolio's cash row in currency. A currency the portfolio
+// doesn't hold yet reads as an empty balance; crediting it creates the row.
+func (s *TradeService) lockBalance(tx *gorm.DB, userID, portfolioID uint, currency string) (*models.Balance, error) {
+	balance, err := s.BalanceRepo.WithTx(tx).GetBalanceForUpdate(portfolioID, currency)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return &models.Balance{UserID: userID, PortfolioID: portfolioID, Asset: currency}, nil
 	}
-	if err := s.Repo.Create(trade); err != nil {
-		return nil, err
+	if err != nil {
+		return nil, fmt.Errorf("failed to get %s balance: %w", currency, err)
 	}
-
-	return &dto.TradeResponse{
-		ID:        trade.ID,
-		UserID:    trade.UserID,
-		CoinID:    trade.CoinID,
-		Symbol:    trade.Symbol,
-		Side:      trade.Side,
-		Quantity:  trade.Quantity,
-		Price:     trade.Price,
-		Type:      trade.Type,
-		Status:    trade.Status,
-		CreatedAt: trade.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: trade.UpdatedAt.Format(time.RFC3339),
-	}, nil
+	return balance, nil
 }
 
-// LimitOrder places a conditional order
-func (s *TradeService) LimitOrder(userID uint, req dto.LimitOrderRequest) (*dto.TradeResponse, error) {
-	const baseCurrency = "usd"
+// LimitOrder places a conditional order. Buy orders reserve quantity * limit price
+// of the quote currency, plus the taker fee on it, and sell orders reserve quantity of
+// the holding, until they fill, are cancelled or expire.
+func (s *TradeService) LimitOrder(userID, portfolioID uint, req dto.LimitOrderRequest) (*dto.TradeResponse, error) {
+	currency, err := parseCurrency(req.Currency)
+	if err != nil {
+		return nil, err
+	}
+	portfolio, err := resolvePortfolio(s.PortfolioRepo, userID, portfolioID, true)
+	if err != nil {
+		return nil, err
+	}
+	if req.Side != "buy" && req.Side != "sell" {
+		return nil, fmt.Errorf("%w: side %q must be buy or sell", service.ErrInvalidOrder, req.Side)
+	}
+	req.CoinID, req.Symbol, err = s.resolveCoin(req.CoinID, req.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	quantity := models.RoundQuantity(req.CoinID, req.Quantity)
+	limitPrice := models.RoundPrice(req.LimitPrice)
+	if !quantity.IsPositive() || !limitPrice.IsPositive() {
+		return nil, fmt.Errorf("%w: quantity and limit_price must be positive", service.ErrInvalidOrder)
+	}
 
-	// Default status
-	status := "open"
+	tif := strings.ToUpper(req.TimeInForce)
+	expiresAt := req.ExpiresAt
+	switch tif {
+	case "":
+		tif = models.TimeInForceGTC
+		expiresAt = nil
+	case models.TimeInForceGTC, models.TimeInForceIOC, models.TimeInForceFOK:
+		expiresAt = nil
+	case models.TimeInForceGTD:
+		if expiresAt == nil || !expiresAt.After(time.Now()) {
+			return nil, fmt.Errorf("%w: GTD orders need an expires_at in the future", service.ErrInvalidOrder)
+		}
+	default:
+		return nil, fmt.Errorf("%w: time_in_force %q must be GTC, IOC, FOK or GTD", service.ErrInvalidOrder, req.TimeInForce)
+	}
 
 	// Fetch current market price
-	coinMarket, err := s.AssetRepo.FetchCoinMarket(req.CoinID, baseCurrency)
+	coinMarket, err := s.AssetRepo.FetchCoinMarket(req.CoinID, strings.ToLower(currency))
 	if err != nil {
 		return nil, fmt.Errorf("failed to fetch market price: %w", err)
 	}
-	currentPrice := coinMarket.PriceUSD
+	currentPrice := marketPrice(coinMarket)
+	cost, err := s.executionCost(userID, currency)
+	if err != nil {
+		return nil, err
+	}
+
+	warnings, err := s.Risk.CheckOrder(service.OrderIntent{
+		UserID:      userID,
+		PortfolioID: portfolio.ID,
+		CoinID:      req.CoinID,
+		Side:        req.Side,
+		Currency:    currency,
+		Quantity:    quantity,
+		Price:       limitPrice,
+		Resting:     tif == models.TimeInForceGTC || tif == models.TimeInForceGTD,
+	})
+	if err != nil {
+		return nil, err
+	}
 
-	// Immediate execution if limit condition met
-	if (req.Side == "buy" && currentPrice <= req.LimitPrice) ||
-		(req.Side == "sell" && currentPrice >= req.LimitPrice) {
+	order := &models.Order{
+		UserID:        userID,
+		PortfolioID:   portfolio.ID,
+		CoinID:        req.CoinID,
+		Symbol:        req.Symbol,
+		Side:          req.Side,
+		Quantity:      quantity,
+		Price:         limitPrice,
+		Type:          models.OrderTypeLimit,
+		Status:        models.OrderStatusOpen,
+		TimeInForce:   tif,
+		ExpiresAt:     expiresAt,
+		QuoteCurrency: currency,
+	}
 
-		status = "filled"
+	var fills []models.Fill
+	err = s.TxManager.Transaction(func(tx *gorm.DB) error {
+		switch req.Side {
+		case "buy":
+			reserve := limitReservation(cost, currency, quantity, limitPrice)
+			if _, err := s.BalanceRepo.WithTx(tx).Reserve(portfolio.ID, currency, reserve); err != nil {
+				if errors.Is(err, gorm.ErrInvalidData) {
+					return fmt.Errorf("%w in %s", service.ErrInsufficientFunds, currency)
+				}
+				return err
+			}
+			order.ReservedAmount = reserve
+		case "sell":
+			if err := s.reserveHolding(tx, order, quantity); err != nil {
+				return err
+			}
+		}
 
-		// Execute as market order
-		_, err := s.MarketOrder(userID, dto.MarketOrderRequest{
-			CoinID:   req.CoinID,
-			Symbol:   req.Symbol,
-			Side:     req.Side,
-			Quantity: req.Quantity,
-			Currency: baseCurrency,
-		})
-		if err != nil {
-			return nil, fmt.Errorf("failed to execute market order: %w", err)
+		// Record limit order in DB
+		if err := s.Repo.WithTx(tx).Create(order); err != nil {
+			return fmt.Errorf("failed to create limit order: %w", err)
 		}
-	}
 
-	// Record limit order in DB
-	trade := &models.Trade{
-		UserID:   userID,
-		CoinID:  req.CoinID,
-		Symbol:   req.Symbol,
-		Side:     req.Side,
-		Quantity: req.Quantity,
-		Price:    req.LimitPrice,
-		Type:     "limit",
-		Status:   status,
+		// Immediate execution if limit condition met; it takes liquidity like a market order
+		if limitReached(order.Side, currentPrice, order.Price) {
+			if err := s.fillOrder(tx, order, coinMarket, service.LiquidityTaker); err != nil {
+				return fmt.Errorf("failed to execute limit order: %w", err)
+			}
+		}
+
+		// IOC and FOK never rest on the book
+		if (tif == models.TimeInForceIOC || tif == models.TimeInForceFOK) && models.IsOrderActive(order.Status) {
+			if err := s.closeOrder(tx, order, models.OrderStatusExpired); err != nil {
+				return err
+			}
+		}
+		fills, err = s.Repo.WithTx(tx).GetFills([]uint{order.ID})
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	if err := s.Repo.Create(trade); err != nil {
-		return nil, fmt.Errorf("failed to create limit order: %w", err)
+	res := toTradeResponse(order, fills)
+	res.RiskWarnings = warnings
+	return &res, nil
+}
+
+// CancelOrder cancels an open or partially filled order and releases its reserved funds
+func (s *TradeService) CancelOrder(userID uint, orderID uint) (*dto.TradeResponse, error) {
+	var order *models.Order
+	var fills []models.Fill
+	err := s.TxManager.Transaction(func(tx *gorm.DB) error {
+		var siblings []models.Order
+		var err error
+		order, siblings, err = s.lockUserOrder(tx, userID, orderID)
+		if err != nil {
+			return err
+		}
+		if err := s.closeOrder(tx, order, models.OrderStatusCancelled); err != nil {
+			return err
+		}
+		// Cancelling one leg of an OCO bracket cancels the whole bracket
+		if err := s.cancelSiblings(tx, siblings); err != nil {
+			return err
+		}
+		fills, err = s.Repo.WithTx(tx).GetFills([]uint{order.ID})
+		return err
+	})
+	if err != nil {
+		return nil, err
 	}
 
-	return &dto.TradeResponse{
-		ID:        trade.ID,
-		UserID:    trade.UserID,
-		CoinID:    trade.CoinID,
-		Symbol:    trade.Symbol,
-		Side:      trade.Side,
-		Quantity:  trade.Quantity,
-		Price:     trade.Price,
-		Type:      trade.Type,
-		Status:    trade.Status,
-		CreatedAt: trade.CreatedAt.Format(time.RFC3339),
-		UpdatedAt: trade.UpdatedAt.Format(time.RFC3339),
-	}, nil
+	res := toTradeResponse(order, fills)
+	return &res, nil
 }
 
+// AmendOrder changes the price, stop, quantity or expiry of an open order.
+// The amended order goes through the pre-trade risk checks again, and for limit buys
+// the reservation is resized to cover the new unfilled notional, for limit sells to
+// cover the new unfilled quantity.
+func (s *TradeService) AmendOrder(userID uint, orderID uint, req dto.AmendOrderRequest) (*dto.TradeResponse, error) {
+	var order *models.Order
+	var fills []models.Fill
+	var warnings []dto.RiskViolationDTO
+	err := s.TxManager.Transaction(func(tx *gorm.DB) error {
+		var err error
+		order, _, err = s.lockUserOrder(tx, userID, orderID)
+		if err != nil {
+			return err
+		}
+
+		price, quantity := order.Price, order.Quantity
+		if req.LimitPrice != nil {
+			if order.Type != models.OrderTypeLimit && order.Type != models.OrderTypeStopLimit {
+				return fmt.Errorf("%w: limit_price only applies to limit and stop_limit orders", service.ErrInvalidOrder)
+			}
+			price = models.RoundPrice(*req.LimitPrice)
+			if !price.IsPositive() {
+				return fmt.Errorf("%w: limit_price must be positive", service.ErrInvalidOrder)
+			}
+		}
+		if req.StopPrice != nil {
+			if order.Type == models.OrderTypeLimit || order.Type == models.OrderTypeTrailingStop || order.TriggeredAt != nil {
+				return fmt.Errorf("%w: stop_price only applies to untriggered stop and take-profit orders", service.ErrInvalidOrder)
+			}
+			stopPrice := models.RoundPrice(*req.StopPrice)
+			if !stopPrice.IsPositive() {
+				return fmt.Errorf("%w: stop_price must be positive", service.ErrInvalidOrder)
+			}
+			order.StopPrice = stopPrice
+		}
+		if req.Quantity != nil {
+			quantity = models.RoundQuantity(order.CoinID, *req.Quantity)
+			if quantity.LessThanOrEqual(order.FilledQuantity) {
+				return fmt.Errorf("%w: quantity must exceed the filled quantity %s", service.ErrInvalidOrder, order.FilledQuantity)
+			}
+		}
+		if req.ExpiresAt != nil {
+			if order.TimeInForce != models.TimeInForceGTD {
+				return fmt.Errorf("%w: expires_at only applies to GTD orders", service.ErrInvalidOrder)
+			}
+			if !req.ExpiresAt.After(time.Now()) {
+				return fmt.Errorf("%w: expires_at must be in the future", service.ErrInvalidOrder)
+			}
+			order.ExpiresAt = req.ExpiresAt
+		}
+
+		remaining := quantity.Sub(order.FilledQuantity)
+		amended := *order
+		amended.Price = price
+		warnings, err = s.Risk.CheckOrder(service.OrderIntent{
+			UserID:      userID,
+			PortfolioID: order.PortfolioID,
+			CoinID:      order.CoinID,
+			Side:        order.Side,
+			Currency:    order.QuoteCurrency,
+			Quantity:    remaining,
+			Price:       riskPrice(&amended),
+			Resting:     true,
+			Amends:      order.ID,
+		})
+		if err != nil {
+			return err
+		}
 
-// GetHistory returns last N trades for a user
-func (s *TradeService) GetHistory(userID uint, limit int) ([]dto.TradeResponse, error) {
-	trades, err := s.Repo.GetByUserID(userID, limit)
+		switch {
+		case order.Side == "buy" && order.Type == models.OrderTypeLimit:
+			cost, err := s.executionCost(userID, order.QuoteCurrency)
+			if err != nil {
+				return err
+			}
+			delta := limitReservation(cost, order.QuoteCurrency, remaining, price).Sub(order.ReservedAmount)
+			if _, err := s.BalanceRepo.WithTx(tx).Reserve(order.PortfolioID, order.QuoteCurrency, delta); err != nil {
+				if errors.Is(err, gorm.ErrInvalidData) {
+					return fmt.Errorf("%w in %s", service.ErrInsufficientFunds, order.QuoteCurrency)
+				}
+				return err
+			}
+			order.ReservedAmount = order.ReservedAmount.Add(delta)
+		case order.Side == "sell" && order.Type == models.OrderTypeLimit:
+			if err := s.reserveHolding(tx, order, remaining.Sub(order.ReservedQuantity)); err != nil {
+				return err
+			}
+		case order.Side == "sell":
+			if err := s.checkSellHolding(tx, order, remaining); err != nil {
+				return err
+			}
+		}
+
+		order.Price = price
+		order.Quantity = quantity
+		if err := s.Repo.WithTx(tx).UpdateOrder(order); err != nil {
+			return err
+		}
+		fills, err = s.Repo.WithTx(tx).GetFills([]uint{order.ID})
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
 
-	var responses []dto.TradeResponse
-	for _, t := range trades {
-		responses = append(responses, dto.TradeResponse{
-			ID:        t.ID,
-			UserID:    t.UserID,
-			CoinID:    t.CoinID,
-			Symbol:    t.Symbol,
-			Side:      t.Side,
-			Quantity:  t.Quantity,
-			Price:     t.Price,
-			Type:      t.Type,
-			Status:    t.Status,
-			CreatedAt: t.CreatedAt.Format(time.RFC3339),
-			UpdatedAt: t.UpdatedAt.Format(time.RFC3339),
-		})
+	res := toTradeResponse(order, fills)
+	res.RiskWarnings = warnings
+	return &res, nil
+}
+
+// lockUserOrder loads and locks an order that belongs to userID and can still change,
+// together with the other legs of its OCO bracket
+func (s *TradeService) lockUserOrder(tx *gorm.DB, userID uint, orderID uint) (*models.Order, []models.Order, error) {
+	order, siblings, err := s.lockOrder(tx, orderID)
+	if errors.Is(err, gorm.ErrRecordNotFound) || (err == nil && order.UserID != userID) {
+		return nil, nil, service.ErrOrderNotFound
 	}
-	return responses, nil
+	if err != nil {
+		return nil, nil, err
+	}
+	if !models.IsOrderActive(order.Status) {
+		return nil, nil, service.ErrOrderNotOpen
+	}
+	return order, siblings, nil
 }
 
+// lockOrder locks an order row. For OCO legs the whole group is locked in id order
+// and the other legs are returned as siblings.
+func (s *TradeService) lockOrder(tx *gorm.DB, orderID uint) (*models.Order, []models.Order, error) {
+	repo := s.Repo.WithTx(tx)
 
-func (s *TradeService) ProcessOpenLimitOrders() {
-	const baseCurrency = "usd"
+	// The group id never changes after placement, so it is safe to read unlocked
+	peek, err := repo.GetOrderByID(orderID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if peek.OCOGroupID == "" {
+		order, err := repo.GetOrderForUpdate(orderID)
+		return order, nil, err
+	}
 
-	// Fetch all open limit orders
-	openOrders, err := s.Repo.GetOpenLimitOrders()
+	legs, err := repo.GetOCOGroupForUpdate(peek.OCOGroupID)
 	if err != nil {
-		fmt.Println("Error fetching open limit orders:", err)
-		return
+		return nil, nil, err
+	}
+	var order *models.Order
+	var siblings []models.Order
+	for i := range legs {
+		if legs[i].ID == orderID {
+			order = &legs[i]
+		} else {
+			siblings = append(siblings, legs[i])
+		}
+	}
+	if order == nil {
+		return nil, nil, gorm.ErrRecordNotFound
 	}
+	return order, siblings, nil
+}
 
-	for _, order := range openOrders {
-		coinMarket, err := s.AssetRepo.FetchCoinMarket(order.CoinID, baseCurrency)
-		if err != nil {
-			continue // skip if coin data not available
+// cancelSiblings cancels the still-active legs of an OCO bracket
+func (s *TradeService) cancelSiblings(tx *gorm.DB, siblings []models.Order) error {
+	for i := range siblings {
+		if !models.IsOrderActive(siblings[i].Status) {
+			continue
+		}
+		if err := s.closeOrder(tx, &siblings[i], models.OrderStatusCancelled); err != nil {
+			return err
 		}
+	}
+	return nil
+}
 
-		currentPrice := coinMarket.PriceUSD
+// fillOrder executes the unfilled part of order against market, quoted in the order's
+// currency, with the given liquidity role and advances its status. Limit-style orders
+// never fill beyond their limit price. Sells are capped at the holding left after other
+// orders' reservations; a sell with nothing left to sell, a FOK sell that can't fill in
+// full, or a buy the cash balance can't cover, counting what the order itself reserved,
+// is rejected. It must run inside tx with order locked.
+func (s *TradeService) fillOrder(tx *gorm.DB, order *models.Order, market *dto.CoinMarketDTO, liquidity string) error {
+	currency := order.QuoteCurrency
+
+	// Lock the cash row before the holding, matching settle
+	balance, err := s.lockBalance(tx, order.UserID, order.PortfolioID, currency)
+	if err != nil {
+		return err
+	}
 
-		// Check if limit condition is met
-		if (order.Side == "buy" && currentPrice <= order.Price) ||
-			(order.Side == "sell" && currentPrice >= order.Price) {
+	limitPrice := decimal.Zero
+	if order.Type == models.OrderTypeLimit || order.Type == models.OrderTypeStopLimit {
+		limitPrice = order.Price
+	}
+	cost, err := s.executionCost(order.UserID, currency)
+	if err != nil {
+		return err
+	}
 
-			// Execute as market order
-			_, err := s.MarketOrder(order.UserID, dto.MarketOrderRequest{
-				CoinID:   order.CoinID,
-				Symbol:   order.Symbol,
-				Side:     order.Side,
-				Quantity: order.Quantity,
-				Currency: baseCurrency,
-			})
-			if err == nil {
-				s.Repo.MarkOrderFilled(order.ID)
+	price, mcap := marketPrice(market), marketCap(market)
+
+	remaining := order.Quantity.Sub(order.FilledQuantity)
+	quantity := remaining
+	if order.Side == "buy" {
+		// The reservation may have fallen short of the cost since it was made, e.g. after
+		// a fee change; settling anyway would fail on every pass and keep the order open
+		quote := quoteExecution(cost, currency, order.Side, quantity, price, mcap, liquidity, limitPrice)
+		needed := fillCashFlow(currency, order.Side, quantity, quote.Price, quote.Fee).Neg()
+		if balance.Amount.Sub(balance.Reserved).Add(order.ReservedAmount).LessThan(needed) {
+			return s.closeOrder(tx, order, models.OrderStatusRejected)
+		}
+	}
+	if order.Side == "sell" {
+		held := decimal.Zero
+		if holding, err := s.HoldingRepo.WithTx(tx).GetHoldingForUpdate(order.PortfolioID, order.CoinID); err == nil {
+			held = holding.Quantity.Sub(holding.Reserved).Add(order.ReservedQuantity)
+		}
+		if held.LessThan(quantity) {
+			if !held.IsPositive() || order.TimeInForce == models.TimeInForceFOK {
+				return s.closeOrder(tx, order, models.OrderStatusRejected)
 			}
+			quantity = held
+		}
+	}
+
+	// Release the reservation backing this fill before paying for it
+	if order.Side == "buy" && order.ReservedAmount.IsPositive() {
+		release := order.ReservedAmount
+		if quantity.LessThan(remaining) {
+			release = models.RoundAmount(currency, order.ReservedAmount.Mul(quantity).Div(remaining))
+		}
+		if _, err := s.BalanceRepo.WithTx(tx).Reserve(order.PortfolioID, currency, release.Neg()); err != nil {
+			return err
+		}
+		order.ReservedAmount = order.ReservedAmount.Sub(release)
+	}
+	if order.Side == "sell" && order.ReservedQuantity.IsPositive() {
+		release := decimal.Min(order.ReservedQuantity, quantity)
+		if _, err := s.HoldingRepo.WithTx(tx).Reserve(order.PortfolioID, order.CoinID, release.Neg()); err != nil {
+			return err
+		}
+		order.ReservedQuantity = order.ReservedQuantity.Sub(release)
+	}
+
+	quote := quoteExecution(cost, currency, order.Side, quantity, price, mcap, liquidity, limitPrice)
+	if err := s.execute(tx, order, quantity, quote, liquidity); err != nil {
+		return err
+	}
+
+	status := models.OrderStatusPartiallyFilled
+	if order.FilledQuantity.GreaterThanOrEqual(order.Quantity) {
+		status = models.OrderStatusFilled
+	}
+	return s.transition(tx, order, status)
+}
+
+// closeOrder moves order to a terminal status and releases any cash or coins it still holds
+func (s *TradeService) closeOrder(tx *gorm.DB, order *models.Order, status string) error {
+	if order.ReservedAmount.IsPositive() {
+		if _, err := s.BalanceRepo.WithTx(tx).Reserve(order.PortfolioID, order.QuoteCurrency, order.ReservedAmount.Neg()); err != nil {
+			return err
+		}
+		order.ReservedAmount = decimal.Zero
+	}
+	if order.ReservedQuantity.IsPositive() {
+		if err := s.reserveHolding(tx, order, order.ReservedQuantity.Neg()); err != nil {
+			return err
 		}
 	}
+	return s.transition(tx, order, status)
 }
 
+func (s *TradeService) transition(tx *gorm.DB, order *models.Order, status string) error {
+	if !models.CanTransitionOrder(order.Status, status) {
+		return fmt.Errorf("%w: cannot move from %s to %s", service.ErrOrderNotOpen, order.Status, status)
+	}
+	order.Status = status
+	return s.Repo.WithTx(tx).UpdateOrder(order)
+}
+
+// limitReservation is the amount of currency a buy limit for quantity at limitPrice
+// holds back: the notional plus the taker fee, the most the fill can cost
+func limitReservation(cost service.ExecutionCostModel, currency string, quantity, limitPrice decimal.Decimal) decimal.Decimal {
+	notional := models.RoundCost(currency, quantity.Mul(limitPrice))
+	return notional.Add(models.RoundAmount(currency, cost.Fee(notional, service.LiquidityTaker)))
+}
 
-func (s *TradeService) GetPendingLimitOrders(userID uint) ([]dto.TradeResponse, error) {
-	trades, err := s.Repo.GetOpenLimitOrdersByUser(userID)
+// limitReached reports whether a limit order on side is marketable at price
+func limitReached(side string, price, limitPrice decimal.Decimal) bool {
+	return (side == "buy" && price.LessThanOrEqual(limitPrice)) || (side == "sell" && price.GreaterThanOrEqual(limitPrice))
+}
+
+// toTradeResponse describes order with those of fills that belong to it
+func toTradeResponse(t *models.Order, fills []models.Fill) dto.TradeResponse {
+	res := dto.TradeResponse{
+		ID:              t.ID,
+		UserID:          t.UserID,
+		PortfolioID:     t.PortfolioID,
+		CoinID:          t.CoinID,
+		Symbol:          t.Symbol,
+		Side:            t.Side,
+		Quantity:        t.Quantity,
+		Price:           t.Price,
+		AveragePrice:    t.AveragePrice,
+		Fee:             t.Fee,
+		Type:            t.Type,
+		Status:          t.Status,
+		TimeInForce:     t.TimeInForce,
+		FilledQuantity:  t.FilledQuantity,
+		StopPrice:       t.StopPrice,
+		TrailingPercent: t.TrailingPercent,
+		TrailingOffset:  t.TrailingOffset,
+		WaterMark:       t.WaterMark,
+		OCOGroupID:      t.OCOGroupID,
+		QuoteCurrency:   t.QuoteCurrency,
+		Fills:           []dto.FillDTO{},
+		CreatedAt:       t.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:       t.UpdatedAt.Format(time.RFC3339),
+	}
+	for _, f := range fills {
+		if f.OrderID != t.ID {
+			continue
+		}
+		res.Fills = append(res.Fills, dto.FillDTO{
+			ID:        f.ID,
+			Quantity:  f.Quantity,
+			Price:     f.Price,
+			Fee:       f.Fee,
+			Liquidity: f.Liquidity,
+			FXRate:    f.FXRate,
+			CreatedAt: f.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	if t.ExpiresAt != nil {
+		res.ExpiresAt = t.ExpiresAt.Format(time.RFC3339)
+	}
+	if t.TriggeredAt != nil {
+		res.TriggeredAt = t.TriggeredAt.Format(time.RFC3339)
+	}
+	return res
+}
+
+// GetHistory returns the last N orders of a portfolio with their fills
+func (s *TradeService) GetHistory(userID, portfolioID uint, limit int) ([]dto.TradeResponse, error) {
+	portfolio, err := resolvePortfolio(s.PortfolioRepo, userID, portfolioID, false)
+	if err != nil {
+		return nil, err
+	}
+	orders, err := s.Repo.GetByPortfolio(portfolio.ID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return s.toTradeResponses(orders)
+}
+
+// toTradeResponses describes orders, loading all their fills in one query
+func (s *TradeService) toTradeResponses(orders []models.Order) ([]dto.TradeResponse, error) {
+	ids := make([]uint, 0, len(orders))
+	for _, o := range orders {
+		ids = append(ids, o.ID)
+	}
+	fills, err := s.Repo.GetFills(ids)
 	if err != nil {
 		return nil, err
 	}
 
 	var responses []dto.TradeResponse
-	for _, t := range trades {
-		responses = append(responses, dto.TradeResponse{
-			ID:        t.ID,
-			UserID:    t.UserID,
-			CoinID:    t.CoinID,
-			Symbol:    t.Symbol,
-			Side:      t.Side,
-			Quantity:  t.Quantity,
-			Price:     t.Price,
-			Type:      t.Type,
-			Status:    t.Status,
-			CreatedAt: t.CreatedAt.Format(time.RFC3339),
-			UpdatedAt: t.UpdatedAt.Format(time.RFC3339),
-		})
+	for i := range orders {
+		responses = append(responses, toTradeResponse(&orders[i], fills))
 	}
 	return responses, nil
 }
+
+// ProcessOpenOrders expires GTD orders past their deadline, then triggers and fills
+// every resting limit and conditional order whose price has been reached. Quotes for
+// all the coins involved are fetched in one batch per quote currency.
+func (s *TradeService) ProcessOpenOrders() {
+	// Fetch all open orders
+	openOrders, err := s.Repo.GetOpenOrders()
+	if err != nil {
+		fmt.Println("Error fetching open orders:", err)
+		return
+	}
+
+	now := time.Now()
+	var live []models.Order
+	coinIDs := map[string][]string{} // by quote currency
+	for _, order := range openOrders {
+		// IOC/FOK remainders are expired at placement; this only catches leftovers
+		expired := order.TimeInForce == models.TimeInForceIOC || order.TimeInForce == models.TimeInForceFOK ||
+			(order.ExpiresAt != nil && now.After(*order.ExpiresAt))
+		if expired {
+			_ = s.withLockedOrder(order.ID, func(tx *gorm.DB, o *models.Order, _ []models.Order) error {
+				return s.closeOrder(tx, o, models.OrderStatusExpired)
+			})
+			continue
+		}
+		live = append(live, order)
+		coinIDs[order.QuoteCurrency] = append(coinIDs[order.QuoteCurrency], order.CoinID)
+	}
+	if len(live) == 0 {
+		return
+	}
+
+	markets := make(map[string]map[string]dto.CoinMarketDTO, len(coinIDs))
+	for currency, ids := range coinIDs {
+		quoted, err := s.AssetRepo.FetchCoinMarkets(ids, strings.ToLower(currency))
+		if err != nil {
+			fmt.Printf("Error fetching %s market prices: %v\n", currency, err)
+			continue
+		}
+		markets[currency] = quoted
+	}
+
+	for _, order := range live {
+		coinMarket, ok := markets[order.QuoteCurrency][order.CoinID]
+		if !ok {
+			continue // skip if coin data not available
+		}
+		_ = s.withLockedOrder(order.ID, func(tx *gorm.DB, o *models.Order, siblings []models.Order) error {
+			return s.evaluateOrder(tx, o, siblings, &coinMarket)
+		})
+	}
+}
+
+// withLockedOrder runs fn in a transaction holding the order's row lock.
+// Orders that were cancelled or filled since they were listed are skipped.
+func (s *TradeService) withLockedOrder(orderID uint, fn func(tx *gorm.DB, order *models.Order, siblings []models.Order) error) error {
+	return s.TxManager.Transaction(func(tx *gorm.DB) error {
+		order, siblings, err := s.lockOrder(tx, orderID)
+		if err != nil {
+			return err
+		}
+		if !models.IsOrderActive(order.Status) {
+			return service.ErrOrderNotOpen
+		}
+		return fn(tx, order, siblings)
+	})
+}
+
+func (s *TradeService) GetPendingLimitOrders(userID, portfolioID uint) ([]dto.TradeResponse, error) {
+	portfolio, err := resolvePortfolio(s.PortfolioRepo, userID, portfolioID, false)
+	if err != nil {
+		return nil, err
+	}
+	orders, err := s.Repo.GetOpenOrdersByPortfolio(portfolio.ID)
+	if err != nil {
+		return nil, err
+	}
+	return s.toTradeResponses(orders)
+}
@@ -0,0 +1,648 @@
+package services
+
+import (
+	"ares_api/internal/api/dto"
+	repository "ares_api/internal/interfaces/repository"
+	service "ares_api/internal/interfaces/service"
+	"ares_api/internal/models"
+	"ares_api/internal/repositories"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+const testUserID = 1
+
+// openTestDB connects to the Postgres database in TEST_DATABASE_URL and migrates the
+// trading tables into a schema of their own, which is dropped when the test ends.
+// Tests that need a database are skipped when the variable is unset.
+func openTestDB(t *testing.T) *gorm.DB {
+	t.Helper()
+	dsn := os.Getenv("TEST_DATABASE_URL")
+	if dsn == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	config := &gorm.Config{Logger: logger.Default.LogMode(logger.Silent)}
+
+	admin, err := gorm.Open(postgres.Open(dsn), config)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	schema := fmt.Sprintf("test_%d_%d", os.Getpid(), time.Now().UnixNano())
+	if err := admin.Exec("CREATE SCHEMA " + schema).Error; err != nil {
+		t.Fatalf("create schema: %v", err)
+	}
+	t.Cleanup(func() {
+		admin.Exec("DROP SCHEMA " + schema + " CASCADE")
+		if sqlDB, err := admin.DB(); err == nil {
+			sqlDB.Close()
+		}
+	})
+
+	sep := " "
+	if strings.Contains(dsn, "://") {
+		sep = "?"
+		if strings.Contains(dsn, "?") {
+			sep = "&"
+		}
+	}
+	db, err := gorm.Open(postgres.Open(dsn+sep+"search_path="+schema), config)
+	if err != nil {
+		t.Fatalf("connect to schema: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	sqlDB.SetMaxOpenConns(20)
+	t.Cleanup(func() { sqlDB.Close() })
+
+	err = db.AutoMigrate(&models.Portfolio{}, &models.Balance{}, &models.Holding{}, &models.Order{}, &models.Fill{}, &models.Setting{})
+	if err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return db
+}
+
+// testMarket quotes every coin at a fixed USD price
+type testMarket struct {
+	repository.AssetRepository
+	price float64
+}
+
+func (m testMarket) FetchCoinMarket(id, vsCurrency string) (*dto.CoinMarketDTO, error) {
+	return &dto.CoinMarketDTO{ID: id, Symbol: "btc", PriceUSD: m.price, MarketCap: 1e12}, nil
+}
+
+// testCatalog knows every coin
+type testCatalog struct {
+	service.AssetService
+}
+
+func (testCatalog) ResolveCoin(coinID, symbol string) (*dto.CoinDTO, error) {
+	return &dto.CoinDTO{ID: coinID, Symbol: "btc"}, nil
+}
+
+// noRiskLimits passes every order
+type noRiskLimits struct {
+	service.RiskService
+}
+
+func (noRiskLimits) CheckOrder(service.OrderIntent) ([]dto.RiskViolationDTO, error) {
+	return nil, nil
+}
+
+// newTestTradeService returns a trade service on db with a portfolio holding cash USD
+func newTestTradeService(t *testing.T, db *gorm.DB, cash decimal.Decimal) (*TradeService, *models.Portfolio) {
+	t.Helper()
+	portfolioRepo := repositories.NewPortfolioRepository(db)
+	balanceRepo := repositories.NewBalanceRepository(db)
+	portfolio := &models.Portfolio{UserID: testUserID, Name: models.DefaultPortfolioName, IsDefault: true}
+	if err := portfolioRepo.Create(portfolio); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := balanceRepo.CreateUSDBalance(testUserID, portfolio.ID, cash); err != nil {
+		t.Fatal(err)
+	}
+	s := NewTradeService(
+		repositories.NewTradeRepository(db),
+		balanceRepo,
+		repositories.NewHoldingRepository(db),
+		portfolioRepo,
+		testMarket{price: 100},
+		repositories.NewSettingsRepository(db),
+		repositories.NewTxManager(db),
+		noRiskLimits{},
+		testCatalog{},
+	)
+	return s, portfolio
+}
+
+func TestFillCashFlow(t *testing.T) {
+	tests := []struct {
+		currency, side, quantity, price, fee, want string
+	}{
+		{models.CurrencyUSD, "buy", "0.5", "100", "0", "-50"},
+		{models.CurrencyUSD, "sell", "0.5", "100", "0", "50"},
+		{models.CurrencyUSD, "buy", "0.5", "100", "0.05", "-50.05"},
+		{models.CurrencyUSD, "sell", "0.5", "100", "0.05", "49.95"},
+		{models.CurrencyUSD, "buy", "0.00000001", "12345.67891234", "0", "-0.01"}, // dust costs a cent
+		{models.CurrencyUSD, "sell", "0.00000001", "12345.67891234", "0", "0"},
+		{models.CurrencyUSD, "buy", "0.333", "0.015", "0", "-0.01"}, // 0.004995 rounds up
+		{models.CurrencyEUR, "buy", "1.5", "0.01", "0", "-0.02"},
+		{models.CurrencyEUR, "sell", "1.5", "0.01", "0", "0.01"}, // 0.015 rounds down
+		{models.CurrencyEUR, "sell", "2.5", "0.01", "0", "0.02"},
+		{models.CurrencyBTC, "buy", "3", "0.012345678", "0", "-0.03703704"},
+		{models.CurrencyBTC, "sell", "3", "0.012345678", "0.00001", "0.03702703"},
+	}
+	for _, tt := range tests {
+		got := fillCashFlow(tt.currency, tt.side, decimal.RequireFromString(tt.quantity),
+			decimal.RequireFromString(tt.price), decimal.RequireFromString(tt.fee))
+		if !got.Equal(decimal.RequireFromString(tt.want)) {
+			t.Errorf("fillCashFlow(%s, %s, %s @ %s, fee %s) = %s, want %s",
+				tt.currency, tt.side, tt.quantity, tt.price, tt.fee, got, tt.want)
+		}
+	}
+}
+
+// Without fees, buying and then selling the same quantity at the same price never gains
+// anything: the balance ends exactly where it started when the notional fits the quote
+// currency's scale and one unit of it lower otherwise
+func TestFillCashFlowRoundTrip(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	coins := []string{"bitcoin", "ethereum", "tether", "ripple"}
+	for _, currency := range models.QuoteCurrencies() {
+		for i := 0; i < 2000; i++ {
+			coinID := coins[rng.Intn(len(coins))]
+			quantity := models.RoundQuantity(coinID, decimal.New(rng.Int63n(1e12)+1, -int32(rng.Intn(10))))
+			price := models.RoundPrice(decimal.New(rng.Int63n(1e12)+1, -int32(rng.Intn(12))))
+			start := models.RoundAmount(currency, decimal.New(rng.Int63n(1e15), -int32(rng.Intn(10))))
+			notional := quantity.Mul(price)
+
+			cost := fillCashFlow(currency, "buy", quantity, price, decimal.Zero).Neg()
+			proceeds := fillCashFlow(currency, "sell", quantity, price, decimal.Zero)
+			if cost.LessThan(notional) || proceeds.GreaterThan(notional) {
+				t.Fatalf("%s: %s %s @ %s costs %s and sells for %s, worth %s", currency, quantity, coinID, price, cost, proceeds, notional)
+			}
+
+			loss := decimal.Zero
+			if !models.RoundAmount(currency, notional).Equal(notional) {
+				loss = decimal.New(1, -models.CurrencyScale(currency))
+			}
+			balance := start.Sub(cost).Add(proceeds)
+			if !start.Sub(balance).Equal(loss) {
+				t.Fatalf("%s: buying and selling %s %s @ %s moved %s to %s", currency, quantity, coinID, price, start, balance)
+			}
+		}
+	}
+}
+
+// Fees are kept to the precision of the quote currency: satoshis for BTC, cents for fiat
+func TestQuoteExecutionFeeScale(t *testing.T) {
+	schedule := FeeSchedules[DefaultFeeSchedule]
+	btc := quotedCost{Model: schedule, Rate: decimal.RequireFromString("0.00001")} // 1 USD = 0.00001 BTC
+	quantity, price := decimal.RequireFromString("0.5"), decimal.RequireFromString("0.0123")
+
+	quote := quoteExecution(btc, models.CurrencyBTC, "buy", quantity, price, decimal.Zero, service.LiquidityMaker, decimal.Zero)
+	if want := decimal.RequireFromString("0.00000615"); !quote.Fee.Equal(want) {
+		t.Errorf("BTC fee = %s, want %s", quote.Fee, want)
+	}
+	quote = quoteExecution(schedule, models.CurrencyUSD, "buy", quantity, decimal.NewFromInt(123), decimal.Zero, service.LiquidityMaker, decimal.Zero)
+	if want := decimal.RequireFromString("0.06"); !quote.Fee.Equal(want) {
+		t.Errorf("USD fee = %s, want %s", quote.Fee, want)
+	}
+	if got := quantityForBudget(btc, models.CurrencyBTC, "ethereum", price, decimal.Zero, decimal.RequireFromString("0.01")); !got.IsPositive() {
+		t.Errorf("a 0.01 BTC budget buys no coins")
+	}
+}
+
+// Hundreds of buys and sells race for one portfolio's cash and holding. Every order
+// either fills or is turned away for lack of funds, and the balances end up exactly
+// where the recorded fills put them, never below zero.
+func TestMarketOrdersConcurrently(t *testing.T) {
+	db := openTestDB(t)
+	start := decimal.NewFromInt(10000)
+	s, portfolio := newTestTradeService(t, db, start)
+
+	const orders = 300
+	errs := make(chan error, orders)
+	var wg sync.WaitGroup
+	for i := 0; i < orders; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			side := "buy"
+			if i%3 == 2 {
+				side = "sell"
+			}
+			_, err := s.MarketOrder(testUserID, portfolio.ID, dto.MarketOrderRequest{
+				CoinID:   "bitcoin",
+				Currency: models.CurrencyUSD,
+				Side:     side,
+				Quantity: decimal.RequireFromString("0.7"),
+			})
+			if err != nil && !errors.Is(err, service.ErrInsufficientFunds) && !errors.Is(err, service.ErrInsufficientHolding) {
+				errs <- err
+			}
+		}(i)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("order failed: %v", err)
+	}
+
+	var fills []models.Fill
+	if err := db.Where("portfolio_id = ?", portfolio.ID).Find(&fills).Error; err != nil {
+		t.Fatal(err)
+	}
+	wantCash, wantHeld := start, decimal.Zero
+	buys := 0
+	for _, f := range fills {
+		wantCash = wantCash.Add(fillCashFlow(f.QuoteCurrency, f.Side, f.Quantity, f.Price, f.Fee))
+		if f.Side == "buy" {
+			wantHeld = wantHeld.Add(f.Quantity)
+			buys++
+		} else {
+			wantHeld = wantHeld.Sub(f.Quantity)
+		}
+	}
+	if buys == 0 || buys == len(fills) {
+		t.Fatalf("want both buys and sells to fill, got %d buys of %d fills", buys, len(fills))
+	}
+
+	var filled int64
+	db.Model(&models.Order{}).Where("portfolio_id = ? AND status = ?", portfolio.ID, models.OrderStatusFilled).Count(&filled)
+	if int(filled) != len(fills) {
+		t.Errorf("%d filled orders but %d fills", filled, len(fills))
+	}
+
+	balance, err := s.BalanceRepo.GetBalance(portfolio.ID, models.CurrencyUSD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !balance.Amount.Equal(wantCash) {
+		t.Errorf("cash = %s, fills add up to %s", balance.Amount, wantCash)
+	}
+	if balance.Amount.IsNegative() {
+		t.Errorf("cash went negative: %s", balance.Amount)
+	}
+	holding, err := s.HoldingRepo.GetHolding(portfolio.ID, "bitcoin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !holding.Quantity.Equal(wantHeld) || holding.Quantity.IsNegative() {
+		t.Errorf("holding = %s, fills add up to %s", holding.Quantity, wantHeld)
+	}
+}
+
+// A resting sell limit holds its coins back from every other sell until it is cancelled
+func TestSellLimitReservesHolding(t *testing.T) {
+	db := openTestDB(t)
+	s, portfolio := newTestTradeService(t, db, decimal.NewFromInt(10000))
+	one := decimal.NewFromInt(1)
+
+	market := func(side string) error {
+		_, err := s.MarketOrder(testUserID, portfolio.ID, dto.MarketOrderRequest{
+			CoinID: "bitcoin", Currency: models.CurrencyUSD, Side: side, Quantity: one,
+		})
+		return err
+	}
+	limit := func() (*dto.TradeResponse, error) {
+		return s.LimitOrder(testUserID, portfolio.ID, dto.LimitOrderRequest{
+			CoinID: "bitcoin", Currency: models.CurrencyUSD, Side: "sell", Quantity: one,
+			LimitPrice: decimal.NewFromInt(200),
+		})
+	}
+
+	if err := market("buy"); err != nil {
+		t.Fatal(err)
+	}
+	order, err := limit()
+	if err != nil {
+		t.Fatal(err)
+	}
+	holding, err := s.HoldingRepo.GetHolding(portfolio.ID, "bitcoin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !holding.Reserved.Equal(one) {
+		t.Fatalf("reserved = %s, want 1", holding.Reserved)
+	}
+
+	if _, err := limit(); err == nil {
+		t.Error("a second sell limit was placed against reserved coins")
+	}
+	if err := market("sell"); err == nil {
+		t.Error("a market sell spent reserved coins")
+	}
+
+	if _, err := s.CancelOrder(testUserID, order.ID); err != nil {
+		t.Fatal(err)
+	}
+	holding, err = s.HoldingRepo.GetHolding(portfolio.ID, "bitcoin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !holding.Reserved.IsZero() {
+		t.Errorf("reserved = %s after cancel, want 0", holding.Reserved)
+	}
+	if err := market("sell"); err != nil {
+		t.Errorf("sell after cancel: %v", err)
+	}
+}
+
+// rejectAll turns every order away, recording what it was asked
+type rejectAll struct {
+	service.RiskService
+	intents []service.OrderIntent
+}
+
+func (r *rejectAll) CheckOrder(order service.OrderIntent) ([]dto.RiskViolationDTO, error) {
+	r.intents = append(r.intents, order)
+	return nil, &service.RiskRejection{}
+}
+
+// Conditional orders and OCO brackets go through the risk checks as resting orders
+func TestConditionalOrdersCheckRisk(t *testing.T) {
+	db := openTestDB(t)
+	s, portfolio := newTestTradeService(t, db, decimal.NewFromInt(10000))
+	risk := &rejectAll{}
+	s.Risk = risk
+
+	_, err := s.ConditionalOrder(testUserID, portfolio.ID, dto.ConditionalOrderRequest{
+		CoinID: "bitcoin", Side: "buy", Type: models.OrderTypeStopMarket,
+		Quantity: decimal.NewFromInt(1), StopPrice: decimal.NewFromInt(120),
+	})
+	if !errors.Is(err, service.ErrRiskRejected) {
+		t.Errorf("conditional order: err = %v, want a risk rejection", err)
+	}
+	_, err = s.OCOOrder(testUserID, portfolio.ID, dto.OCOOrderRequest{
+		CoinID: "bitcoin", Side: "buy", Quantity: decimal.NewFromInt(1),
+		TakeProfitPrice: decimal.NewFromInt(80), StopPrice: decimal.NewFromInt(120),
+	})
+	if !errors.Is(err, service.ErrRiskRejected) {
+		t.Errorf("OCO order: err = %v, want a risk rejection", err)
+	}
+
+	if len(risk.intents) != 2 {
+		t.Fatalf("risk checked %d orders, want 2", len(risk.intents))
+	}
+	for _, intent := range risk.intents {
+		if !intent.Resting || !intent.Price.Equal(decimal.NewFromInt(120)) {
+			t.Errorf("intent = %+v, want a resting order at the 120 stop", intent)
+		}
+	}
+	if oco := risk.intents[1]; oco.Orders != 2 {
+		t.Errorf("OCO counted as %d orders, want 2", oco.Orders)
+	}
+
+	var placed int64
+	db.Model(&models.Order{}).Where("portfolio_id = ?", portfolio.ID).Count(&placed)
+	if placed != 0 {
+		t.Errorf("%d orders placed despite the rejection", placed)
+	}
+}
+
+// Orders that would be turned away are previewed as rejected instead of failing
+func TestPreviewRejectedOrders(t *testing.T) {
+	db := openTestDB(t)
+	start := decimal.NewFromInt(1000)
+	s, portfolio := newTestTradeService(t, db, start)
+
+	tests := []struct {
+		name string
+		req  dto.TradePreviewRequest
+		want error
+	}{
+		{"beyond the cash", dto.TradePreviewRequest{Type: models.OrderTypeMarket, Side: "buy", Quantity: decimal.NewFromInt(50)}, service.ErrInsufficientFunds},
+		{"coins not held", dto.TradePreviewRequest{Type: models.OrderTypeLimit, Side: "sell", Quantity: decimal.NewFromInt(1), LimitPrice: decimal.NewFromInt(200)}, service.ErrInsufficientHolding},
+		{"zero quantity", dto.TradePreviewRequest{Type: models.OrderTypeMarket, Side: "buy"}, service.ErrInvalidOrder},
+		{"unknown side", dto.TradePreviewRequest{Type: models.OrderTypeMarket, Side: "hold", Quantity: decimal.NewFromInt(1)}, service.ErrInvalidOrder},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.req.CoinID = "bitcoin"
+			res, err := s.PreviewOrder(testUserID, portfolio.ID, tt.req)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !res.Rejected || res.Status != models.OrderStatusRejected || !strings.HasPrefix(res.Reason, tt.want.Error()) {
+				t.Errorf("rejected %v, status %q, reason %q; want rejected for %q", res.Rejected, res.Status, res.Reason, tt.want)
+			}
+			if !res.Cash.Before.Equal(start) || !res.Cash.After.Equal(start) {
+				t.Errorf("cash %s -> %s, want it untouched at %s", res.Cash.Before, res.Cash.After, start)
+			}
+		})
+	}
+
+	s.Risk = &rejectAll{}
+	res, err := s.PreviewOrder(testUserID, portfolio.ID, dto.TradePreviewRequest{
+		Type: models.OrderTypeMarket, CoinID: "bitcoin", Side: "buy", Quantity: decimal.NewFromInt(1),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !res.Rejected || !res.MarketPrice.Equal(decimal.NewFromInt(100)) {
+		t.Errorf("risk rejection previewed as rejected %v at %s, want rejected at 100", res.Rejected, res.MarketPrice)
+	}
+}
+
+// notionalLimit rejects orders worth more than max, as a max_order_notional limit in
+// reject mode does, and records what it was asked
+type notionalLimit struct {
+	service.RiskService
+	max     decimal.Decimal
+	intents []service.OrderIntent
+}
+
+func (r *notionalLimit) CheckOrder(order service.OrderIntent) ([]dto.RiskViolationDTO, error) {
+	r.intents = append(r.intents, order)
+	if notional := order.Quantity.Mul(order.Price); notional.GreaterThan(r.max) {
+		return nil, &service.RiskRejection{Violations: []dto.RiskViolationDTO{{
+			Rule: models.RiskRuleMaxOrderNotional, Limit: r.max, Actual: notional,
+		}}}
+	}
+	return nil, nil
+}
+
+// Amending an order up past a risk limit is rejected and leaves the order as it was
+func TestAmendOrderChecksRisk(t *testing.T) {
+	db := openTestDB(t)
+	s, portfolio := newTestTradeService(t, db, decimal.NewFromInt(10000))
+	risk := &notionalLimit{max: decimal.NewFromInt(200)}
+	s.Risk = risk
+
+	order, err := s.LimitOrder(testUserID, portfolio.ID, dto.LimitOrderRequest{
+		CoinID: "bitcoin", Currency: models.CurrencyUSD, Side: "buy",
+		Quantity: decimal.NewFromInt(2), LimitPrice: decimal.NewFromInt(50),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	reserved := func() decimal.Decimal {
+		balance, err := s.BalanceRepo.GetBalance(portfolio.ID, models.CurrencyUSD)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return balance.Reserved
+	}
+	before := reserved()
+
+	quantity := decimal.NewFromInt(10)
+	_, err = s.AmendOrder(testUserID, order.ID, dto.AmendOrderRequest{Quantity: &quantity})
+	if !errors.Is(err, service.ErrRiskRejected) {
+		t.Fatalf("amend to 10 @ 50: err = %v, want a risk rejection", err)
+	}
+	if intent := risk.intents[len(risk.intents)-1]; intent.Amends != order.ID || !intent.Resting || !intent.Quantity.Equal(quantity) {
+		t.Errorf("intent = %+v, want the amended 10 of order %d", intent, order.ID)
+	}
+
+	var stored models.Order
+	if err := db.First(&stored, order.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if !stored.Quantity.Equal(decimal.NewFromInt(2)) || !reserved().Equal(before) {
+		t.Errorf("order now %s with %s reserved, want it untouched at 2 with %s", stored.Quantity, reserved(), before)
+	}
+
+	quantity = decimal.NewFromInt(3)
+	if _, err := s.AmendOrder(testUserID, order.ID, dto.AmendOrderRequest{Quantity: &quantity}); err != nil {
+		t.Errorf("amend within the limit: %v", err)
+	}
+}
+
+// A buy limit whose reservation no longer covers its cost, and that has no other cash
+// to fall back on, is rejected instead of failing to settle on every pass
+func TestReservedBuyShortOfFunds(t *testing.T) {
+	db := openTestDB(t)
+	s, portfolio := newTestTradeService(t, db, decimal.NewFromInt(10000))
+
+	placed, err := s.LimitOrder(testUserID, portfolio.ID, dto.LimitOrderRequest{
+		CoinID: "bitcoin", Currency: models.CurrencyUSD, Side: "buy",
+		Quantity: decimal.NewFromInt(1), LimitPrice: decimal.NewFromInt(50),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Shave 10 off the reservation and leave no cash beside it
+	short := gorm.Expr("reserved_amount - 10")
+	if err := db.Model(&models.Order{}).Where("id = ?", placed.ID).Update("reserved_amount", short).Error; err != nil {
+		t.Fatal(err)
+	}
+	err = db.Model(&models.Balance{}).Where("portfolio_id = ? AND asset = ?", portfolio.ID, models.CurrencyUSD).
+		Updates(map[string]interface{}{"amount": gorm.Expr("reserved - 10"), "reserved": gorm.Expr("reserved - 10")}).Error
+	if err != nil {
+		t.Fatal(err)
+	}
+	before, err := s.BalanceRepo.GetBalance(portfolio.ID, models.CurrencyUSD)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	market := &dto.CoinMarketDTO{ID: "bitcoin", Symbol: "btc", PriceUSD: 50, MarketCap: 1e12}
+	err = s.withLockedOrder(placed.ID, func(tx *gorm.DB, order *models.Order, siblings []models.Order) error {
+		return s.evaluateOrder(tx, order, siblings, market)
+	})
+	if err != nil {
+		t.Fatalf("evaluate: %v", err)
+	}
+
+	var order models.Order
+	if err := db.First(&order, placed.ID).Error; err != nil {
+		t.Fatal(err)
+	}
+	if order.Status != models.OrderStatusRejected || !order.FilledQuantity.IsZero() {
+		t.Errorf("order %s with %s filled, want rejected with nothing filled", order.Status, order.FilledQuantity)
+	}
+	after, err := s.BalanceRepo.GetBalance(portfolio.ID, models.CurrencyUSD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !after.Amount.Equal(before.Amount) || !after.Reserved.IsZero() {
+		t.Errorf("balance %s with %s reserved, want %s with nothing reserved", after.Amount, after.Reserved, before.Amount)
+	}
+}
+
+// Resetting the balances never takes away cash that open buy orders still hold
+func TestResetBalancesKeepsReservedCash(t *testing.T) {
+	db := openTestDB(t)
+	s, portfolio := newTestTradeService(t, db, decimal.NewFromInt(20000))
+
+	placed, err := s.LimitOrder(testUserID, portfolio.ID, dto.LimitOrderRequest{
+		CoinID: "bitcoin", Currency: models.CurrencyUSD, Side: "buy",
+		Quantity: decimal.NewFromInt(200), LimitPrice: decimal.NewFromInt(75),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.BalanceRepo.ResetBalances(portfolio.ID, decimal.NewFromInt(10000)); err != nil {
+		t.Fatal(err)
+	}
+	balance, err := s.BalanceRepo.GetBalance(portfolio.ID, models.CurrencyUSD)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if balance.Reserved.IsZero() || !balance.Amount.Equal(balance.Reserved) {
+		t.Errorf("balance %s with %s reserved, want it kept at the reservation", balance.Amount, balance.Reserved)
+	}
+
+	if _, err := s.CancelOrder(testUserID, placed.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.BalanceRepo.ResetBalances(portfolio.ID, decimal.NewFromInt(10000)); err != nil {
+		t.Fatal(err)
+	}
+	if balance, err = s.BalanceRepo.GetBalance(portfolio.ID, models.CurrencyUSD); err != nil {
+		t.Fatal(err)
+	}
+	if !balance.Amount.Equal(decimal.NewFromInt(10000)) || !balance.Reserved.IsZero() {
+		t.Errorf("balance %s with %s reserved after cancelling, want 10000 with nothing reserved", balance.Amount, balance.Reserved)
+	}
+}
+
+func TestUpdateTrailingStop(t *testing.T) {
+	d := decimal.RequireFromString
+	tests := []struct {
+		side, offset, percent, waterMark, price string
+		moved                                   bool
+		stop                                    string
+	}{
+		{"sell", "10", "0", "0", "100", true, "90"},
+		{"sell", "10", "0", "100", "120", true, "110"},
+		{"sell", "10", "0", "120", "110", false, "0"},
+		{"buy", "10", "0", "100", "80", true, "90"},
+		{"sell", "0", "5", "0", "200", true, "190"},
+		{"sell", "100", "0", "0", "40", true, "0.00000001"}, // clamped above zero
+	}
+	for _, tt := range tests {
+		order := &models.Order{
+			Type: models.OrderTypeTrailingStop, Side: tt.side, TrailingOffset: d(tt.offset),
+			TrailingPercent: d(tt.percent), WaterMark: d(tt.waterMark),
+		}
+		moved := updateTrailingStop(order, d(tt.price))
+		if moved != tt.moved || !order.StopPrice.Equal(d(tt.stop)) {
+			t.Errorf("%s trailing %s/%s%% from %s to %s: moved %v, stop %s; want %v, %s", tt.side, tt.offset, tt.percent,
+				tt.waterMark, tt.price, moved, order.StopPrice, tt.moved, tt.stop)
+		}
+	}
+}
+
+// A sell trailing stop whose offset reaches the current price is turned away up front
+func TestTrailingStopOffsetBelowPrice(t *testing.T) {
+	db := openTestDB(t)
+	s, portfolio := newTestTradeService(t, db, decimal.NewFromInt(10000))
+	risk := &rejectAll{}
+	s.Risk = risk
+
+	_, err := s.ConditionalOrder(testUserID, portfolio.ID, dto.ConditionalOrderRequest{
+		CoinID: "bitcoin", Side: "sell", Type: models.OrderTypeTrailingStop,
+		Quantity: decimal.NewFromInt(1), TrailingOffset: decimal.NewFromInt(100),
+	})
+	if !errors.Is(err, service.ErrInvalidOrder) {
+		t.Errorf("offset 100 at price 100: err = %v, want an invalid order", err)
+	}
+	if len(risk.intents) != 0 {
+		t.Errorf("risk checked %d orders, want none", len(risk.intents))
+	}
+
+	// Buy stops trail above the price, so any offset works
+	_, err = s.ConditionalOrder(testUserID, portfolio.ID, dto.ConditionalOrderRequest{
+		CoinID: "bitcoin", Side: "buy", Type: models.OrderTypeTrailingStop,
+		Quantity: decimal.NewFromInt(1), TrailingOffset: decimal.NewFromInt(100),
+	})
+	if !errors.Is(err, service.ErrRiskRejected) {
+		t.Errorf("buy with offset 100: err = %v, want it to reach the risk checks", err)
+	}
+}
@@ -0,0 +1,358 @@
+package services
+
+import (
+	"ares_api/internal/api/dto"
+	repository "ares_api/internal/interfaces/repository"
+	service "ares_api/internal/interfaces/service"
+	"ares_api/internal/models"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var _ service.WatchlistService = &WatchlistService{}
+
+const (
+	// maxWatchlistName matches the size of the watchlists.name column
+	maxWatchlistName = 100
+	// maxWatchlistCoins keeps a watchlist's quotes within one batched market data call
+	maxWatchlistCoins = 250
+)
+
+// WatchlistService manages users' watchlists. Coins are checked against the coin
+// catalog, and a watchlist's default alert is created through the AlertService for
+// every coin added to it.
+type WatchlistService struct {
+	Repo      repository.WatchlistRepository
+	AssetRepo repository.AssetRepository
+	TxManager repository.TxManager
+	Catalog   service.AssetService
+	Alerts    service.AlertService
+}
+
+func NewWatchlistService(r repository.WatchlistRepository, a repository.AssetRepository, tx repository.TxManager, c service.AssetService, al service.AlertService) *WatchlistService {
+	return &WatchlistService{Repo: r, AssetRepo: a, TxManager: tx, Catalog: c, Alerts: al}
+}
+
+func toWatchlistDTO(w *models.Watchlist) dto.WatchlistDTO {
+	res := dto.WatchlistDTO{
+		ID:        w.ID,
+		Name:      w.Name,
+		Coins:     make([]dto.WatchlistItemDTO, 0, len(w.Items)),
+		CreatedAt: w.CreatedAt,
+	}
+	if w.AlertCondition != "" {
+		res.DefaultAlert = &dto.WatchlistAlertDTO{
+			Condition: w.AlertCondition,
+			Threshold: w.AlertThreshold,
+			Window:    w.AlertWindow,
+			Mode:      w.AlertMode,
+			Cooldown:  w.AlertCooldown,
+		}
+	}
+	for _, item := range w.Items {
+		res.Coins = append(res.Coins, dto.WatchlistItemDTO{
+			CoinID:   item.CoinID,
+			Position: item.Position,
+			AlertID:  item.AlertID,
+			AddedAt:  item.CreatedAt,
+		})
+	}
+	return res
+}
+
+// validateDefaultAlert checks a default alert up front, so a bad one can't make every
+// later add fail
+func validateDefaultAlert(a *dto.WatchlistAlertDTO) error {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: default_alert: %s", service.ErrInvalidWatchlist, fmt.Sprintf(format, args...))
+	}
+
+	switch a.Condition {
+	case models.AlertPercentMove:
+		window, err := time.ParseDuration(a.Window)
+		if err != nil || window < time.Minute || window > maxAlertWindow {
+			return invalid("percent_move needs a window between 1m and 24h")
+		}
+	case models.AlertChange24h:
+		a.Window = ""
+	default:
+		return invalid("unknown condition %q: must be percent_move or change_24h", a.Condition)
+	}
+	if a.Threshold.IsZero() {
+		return invalid("threshold must be a non-zero percentage")
+	}
+
+	switch a.Mode {
+	case "", models.AlertModeOnce:
+		a.Cooldown = ""
+	case models.AlertModeRecurring:
+		if a.Cooldown != "" {
+			if cooldown, err := time.ParseDuration(a.Cooldown); err != nil || cooldown < 0 {
+				return invalid("cooldown must be a duration")
+			}
+		}
+	default:
+		return invalid("unknown mode %q: must be once or recurring", a.Mode)
+	}
+	return nil
+}
+
+// Create makes an empty watchlist, optionally with a default alert for its coins
+func (s *WatchlistService) Create(userID uint, req dto.CreateWatchlistRequest) (*dto.WatchlistDTO, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > maxWatchlistName {
+		return nil, fmt.Errorf("%w: name must be 1-%d characters", service.ErrInvalidWatchlist, maxWatchlistName)
+	}
+
+	existing, err := s.Repo.GetByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, w := range existing {
+		if strings.EqualFold(w.Name, name) {
+			return nil, fmt.Errorf("%w: a watchlist named %q already exists", service.ErrInvalidWatchlist, w.Name)
+		}
+	}
+
+	watchlist := &models.Watchlist{UserID: userID, Name: name}
+	if a := req.DefaultAlert; a != nil {
+		if err := validateDefaultAlert(a); err != nil {
+			return nil, err
+		}
+		watchlist.AlertCondition = a.Condition
+		watchlist.AlertThreshold = a.Threshold
+		watchlist.AlertWindow = a.Window
+		watchlist.AlertMode = a.Mode
+		watchlist.AlertCooldown = a.Cooldown
+	}
+	if err := s.Repo.Create(watchlist); err != nil {
+		return nil, err
+	}
+	res := toWatchlistDTO(watchlist)
+	return &res, nil
+}
+
+func (s *WatchlistService) List(userID uint) ([]dto.WatchlistDTO, error) {
+	watchlists, err := s.Repo.GetByUser(userID)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]dto.WatchlistDTO, 0, len(watchlists))
+	for i := range watchlists {
+		res = append(res, toWatchlistDTO(&watchlists[i]))
+	}
+	return res, nil
+}
+
+func (s *WatchlistService) get(userID, watchlistID uint) (*models.Watchlist, error) {
+	watchlist, err := s.Repo.GetByID(userID, watchlistID)
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, service.ErrWatchlistNotFound
+	}
+	return watchlist, err
+}
+
+func (s *WatchlistService) Get(userID, watchlistID uint) (*dto.WatchlistDTO, error) {
+	watchlist, err := s.get(userID, watchlistID)
+	if err != nil {
+		return nil, err
+	}
+	res := toWatchlistDTO(watchlist)
+	return &res, nil
+}
+
+// Delete removes the watchlist along with the alerts its coins inherited
+func (s *WatchlistService) Delete(userID, watchlistID uint) error {
+	watchlist, err := s.get(userID, watchlistID)
+	if err != nil {
+		return err
+	}
+	if err := s.Repo.Delete(watchlist); err != nil {
+		return err
+	}
+	for _, item := range watchlist.Items {
+		if err := s.deleteInheritedAlert(userID, item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// deleteInheritedAlert removes the default alert item got when it was added. The user
+// may have deleted it already.
+func (s *WatchlistService) deleteInheritedAlert(userID uint, item models.WatchlistItem) error {
+	if item.AlertID == nil {
+		return nil
+	}
+	if err := s.Alerts.Delete(userID, *item.AlertID); err != nil && !errors.Is(err, service.ErrAlertNotFound) {
+		return err
+	}
+	return nil
+}
+
+// AddCoin appends a catalog coin to the watchlist. If the watchlist has a default
+// alert, the coin gets its own copy of it unless the request opts out.
+func (s *WatchlistService) AddCoin(userID, watchlistID uint, req dto.AddWatchlistCoinRequest) (*dto.WatchlistDTO, error) {
+	watchlist, err := s.get(userID, watchlistID)
+	if err != nil {
+		return nil, err
+	}
+	coin, err := s.Catalog.ResolveCoin(req.CoinID, req.Symbol)
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range watchlist.Items {
+		if item.CoinID == coin.ID {
+			return nil, fmt.Errorf("%w: %s", service.ErrCoinOnWatchlist, coin.ID)
+		}
+	}
+	if len(watchlist.Items) >= maxWatchlistCoins {
+		return nil, fmt.Errorf("%w: a watchlist holds at most %d coins", service.ErrInvalidWatchlist, maxWatchlistCoins)
+	}
+
+	item := models.WatchlistItem{WatchlistID: watchlist.ID, CoinID: coin.ID}
+	if n := len(watchlist.Items); n > 0 {
+		item.Position = watchlist.Items[n-1].Position + 1
+	}
+	if watchlist.AlertCondition != "" && (req.Alert == nil || *req.Alert) {
+		alert, err := s.Alerts.Create(userID, dto.CreateAlertRequest{
+			CoinID:    coin.ID,
+			Condition: watchlist.AlertCondition,
+			Threshold: watchlist.AlertThreshold,
+			Window:    watchlist.AlertWindow,
+			Mode:      watchlist.AlertMode,
+			Cooldown:  watchlist.AlertCooldown,
+			Note:      "Watchlist: " + watchlist.Name,
+		})
+		if err != nil {
+			return nil, err
+		}
+		item.AlertID = &alert.ID
+	}
+	if err := s.Repo.AddItem(&item); err != nil {
+		// most likely a concurrent add of the same coin; don't leave its alert behind
+		_ = s.deleteInheritedAlert(userID, item)
+		return nil, err
+	}
+
+	watchlist.Items = append(watchlist.Items, item)
+	res := toWatchlistDTO(watchlist)
+	return &res, nil
+}
+
+// RemoveCoin takes a coin off the watchlist together with the alert it inherited
+func (s *WatchlistService) RemoveCoin(userID, watchlistID uint, coinID string) (*dto.WatchlistDTO, error) {
+	watchlist, err := s.get(userID, watchlistID)
+	if err != nil {
+		return nil, err
+	}
+	coinID = strings.ToLower(strings.TrimSpace(coinID))
+	idx := -1
+	for i, item := range watchlist.Items {
+		if item.CoinID == coinID {
+			idx = i
+			break
+		}
+	}
+	if idx < 0 {
+		return nil, fmt.Errorf("%w: %s", service.ErrCoinNotOnWatchlist, coinID)
+	}
+
+	item := watchlist.Items[idx]
+	if err := s.Repo.DeleteItem(&item); err != nil {
+		return nil, err
+	}
+	if err := s.deleteInheritedAlert(userID, item); err != nil {
+		return nil, err
+	}
+
+	watchlist.Items = append(watchlist.Items[:idx], watchlist.Items[idx+1:]...)
+	res := toWatchlistDTO(watchlist)
+	return &res, nil
+}
+
+// Reorder puts the watchlist's coins in the order given, which must list each of them
+// exactly once
+func (s *WatchlistService) Reorder(userID, watchlistID uint, req dto.ReorderWatchlistRequest) (*dto.WatchlistDTO, error) {
+	invalid := func(format string, args ...interface{}) error {
+		return fmt.Errorf("%w: %s", service.ErrInvalidWatchlist, fmt.Sprintf(format, args...))
+	}
+
+	watchlist, err := s.get(userID, watchlistID)
+	if err != nil {
+		return nil, err
+	}
+	if len(req.CoinIDs) != len(watchlist.Items) {
+		return nil, invalid("coin_ids must list all %d coins of the watchlist", len(watchlist.Items))
+	}
+	byCoin := make(map[string]models.WatchlistItem, len(watchlist.Items))
+	for _, item := range watchlist.Items {
+		byCoin[item.CoinID] = item
+	}
+	items := make([]models.WatchlistItem, 0, len(req.CoinIDs))
+	for i, id := range req.CoinIDs {
+		id = strings.ToLower(strings.TrimSpace(id))
+		item, ok := byCoin[id]
+		if !ok {
+			return nil, invalid("%q is not on the watchlist or is listed twice", id)
+		}
+		delete(byCoin, id)
+		item.Position = i
+		items = append(items, item)
+	}
+
+	err = s.TxManager.Transaction(func(tx *gorm.DB) error {
+		return s.Repo.WithTx(tx).UpdatePositions(items)
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	watchlist.Items = items
+	res := toWatchlistDTO(watchlist)
+	return &res, nil
+}
+
+// Quotes fetches the quotes of all the watchlist's coins in a single batch through the
+// quote cache. vsCurrency defaults to usd.
+func (s *WatchlistService) Quotes(userID, watchlistID uint, vsCurrency string) (*dto.WatchlistQuotesDTO, error) {
+	watchlist, err := s.get(userID, watchlistID)
+	if err != nil {
+		return nil, err
+	}
+	vs := strings.ToLower(strings.TrimSpace(vsCurrency))
+	if vs == "" {
+		vs = "usd"
+	}
+
+	res := &dto.WatchlistQuotesDTO{
+		WatchlistID: watchlist.ID,
+		Name:        watchlist.Name,
+		Currency:    vs,
+		Quotes:      make([]dto.CoinMarketDTO, 0, len(watchlist.Items)),
+	}
+	if len(watchlist.Items) == 0 {
+		return res, nil
+	}
+
+	ids := make([]string, 0, len(watchlist.Items))
+	for _, item := range watchlist.Items {
+		ids = append(ids, item.CoinID)
+	}
+	markets, err := s.AssetRepo.FetchCoinMarkets(ids, vs)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if m, ok := markets[id]; ok {
+			res.Quotes = append(res.Quotes, m)
+		} else {
+			res.Missing = append(res.Missing, id)
+		}
+	}
+	return res, nil
+}